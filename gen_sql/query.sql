@@ -2,7 +2,8 @@
 SELECT n.*
 FROM notes n
 WHERE user_id = $1
-ORDER BY n.created_at, n.id;
+  AND n.trashed_at IS NULL
+ORDER BY n.pinned DESC, n.created_at, n.id;
 
 -- name: GetNoteById :one
 SELECT DISTINCT n.*
@@ -10,8 +11,8 @@ FROM notes n
 WHERE n.id = $1;
 
 -- name: CreateNote :one
-INSERT INTO notes (user_id, name, description, deadline_at)
-VALUES ($1, $2, $3, $4)
+INSERT INTO notes (user_id, name, description, deadline_at, recurrence, series_id, notebook_id, priority, pinned)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING id;
 
 -- name: UpdateNote :one
@@ -19,9 +20,17 @@ UPDATE notes
 SET name         = $1,
     description  = $2,
     is_completed = $3,
-    deadline_at  = $4
-WHERE id = $5
-RETURNING id;
+    deadline_at  = $4,
+    recurrence   = $5,
+    notebook_id  = $6,
+    priority     = $7,
+    pinned       = $8,
+    version      = version + 1,
+    updated_at   = NOW(),
+    completed_at = CASE WHEN $3 THEN COALESCE(completed_at, NOW()) END
+WHERE id = $9
+  AND version = $10
+RETURNING version;
 
 -- name: DeleteNoteById :one
 DELETE
@@ -31,7 +40,10 @@ RETURNING id;
 
 -- name: ChangeNoteStatus :one
 UPDATE notes
-SET is_completed = $1
+SET is_completed = $1,
+    version      = version + 1,
+    updated_at   = NOW(),
+    completed_at = CASE WHEN $1 THEN COALESCE(completed_at, NOW()) END
 WHERE id = $2
 RETURNING id;
 
@@ -51,4 +63,1220 @@ SELECT n.*
 FROM notes n
 WHERE user_id = $1
   AND (name ILIKE '%' || $2 || '%')
-ORDER BY n.created_at, n.id;
\ No newline at end of file
+  AND n.trashed_at IS NULL
+ORDER BY n.pinned DESC, n.created_at, n.id;
+
+-- name: CountOpenSeriesNotes :one
+SELECT COUNT(*)
+FROM notes n
+WHERE COALESCE(n.series_id, n.id) = @series_id::BIGINT
+  AND n.is_completed = FALSE
+  AND n.trashed_at IS NULL
+  AND n.id <> @id;
+
+-- name: UpdateNoteSeries :execrows
+UPDATE notes
+SET name        = @name,
+    description = @description,
+    recurrence  = @recurrence,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE COALESCE(series_id, id) = @series_id::BIGINT
+  AND is_completed = FALSE;
+
+-- name: StopNoteSeries :execrows
+UPDATE notes
+SET recurrence = NULL,
+    version    = version + 1,
+    updated_at = NOW()
+WHERE COALESCE(series_id, id) = @series_id::BIGINT;
+
+-- name: GetDigestSettingsByUserId :one
+SELECT d.*
+FROM digest_settings d
+WHERE d.user_id = $1;
+
+-- name: UpsertDigestSettings :exec
+INSERT INTO digest_settings (user_id, enabled, email, send_time, timezone, days_ahead)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id) DO UPDATE
+    SET enabled    = EXCLUDED.enabled,
+        email      = EXCLUDED.email,
+        send_time  = EXCLUDED.send_time,
+        timezone   = EXCLUDED.timezone,
+        days_ahead = EXCLUDED.days_ahead;
+
+-- name: GetEnabledDigestSettings :many
+SELECT d.*
+FROM digest_settings d
+WHERE d.enabled = TRUE
+ORDER BY d.user_id;
+
+-- name: ClaimDigest :execrows
+UPDATE digest_settings
+SET last_sent_on = @today
+WHERE user_id = @user_id
+  AND (last_sent_on IS NULL OR last_sent_on < @today);
+
+-- name: ReleaseDigest :exec
+UPDATE digest_settings
+SET last_sent_on = @last_sent_on
+WHERE user_id = @user_id
+  AND last_sent_on = @today;
+
+-- name: GetExpiredNotesByUserId :many
+SELECT n.*
+FROM notes n
+WHERE n.user_id = @user_id
+  AND n.is_completed = FALSE
+  AND n.trashed_at IS NULL
+  AND n.deadline_at < @today::DATE
+ORDER BY n.deadline_at, n.id;
+
+-- name: GetUpcomingNotesByUserId :many
+SELECT n.*
+FROM notes n
+WHERE n.user_id = @user_id
+  AND n.is_completed = FALSE
+  AND n.trashed_at IS NULL
+  AND n.deadline_at >= @today::DATE
+  AND n.deadline_at <= @until::DATE
+ORDER BY n.deadline_at, n.id;
+
+-- name: GetNotesByUserIdAndNotebook :many
+SELECT n.*
+FROM notes n
+WHERE n.user_id = $1
+  AND n.notebook_id = $2
+  AND n.trashed_at IS NULL
+ORDER BY n.pinned DESC, n.created_at, n.id;
+
+-- name: SetNoteNotebook :exec
+UPDATE notes
+SET notebook_id = $1,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE id = $2;
+
+-- name: GetTrashedNotesByUserId :many
+SELECT n.*
+FROM notes n
+WHERE n.user_id = $1
+  AND n.trashed_at IS NOT NULL
+ORDER BY n.trashed_at DESC, n.id;
+
+-- name: TrashNote :exec
+UPDATE notes
+SET trashed_at = NOW()
+WHERE id = $1;
+
+-- name: RestoreNote :exec
+UPDATE notes
+SET trashed_at = NULL
+WHERE id = $1;
+
+-- name: CreateNotebook :one
+INSERT INTO notebooks (user_id, parent_id, name)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- name: GetNotebookById :one
+SELECT nb.*
+FROM notebooks nb
+WHERE nb.id = $1;
+
+-- name: GetNotebooksByUserId :many
+SELECT nb.*
+FROM notebooks nb
+WHERE nb.user_id = $1
+ORDER BY nb.name, nb.id;
+
+-- name: RenameNotebook :exec
+UPDATE notebooks
+SET name = $1
+WHERE id = $2;
+
+-- name: MoveNotebook :exec
+UPDATE notebooks
+SET parent_id = $1
+WHERE id = $2;
+
+-- name: DeleteNotebookById :exec
+DELETE
+FROM notebooks
+WHERE id = $1;
+
+-- name: GetNotebookSubtreeIds :many
+WITH RECURSIVE subtree AS (SELECT nb.id
+                           FROM notebooks nb
+                           WHERE nb.id = $1
+                           UNION ALL
+                           SELECT child.id
+                           FROM notebooks child
+                                    JOIN subtree ON child.parent_id = subtree.id)
+SELECT subtree.id::BIGINT AS id
+FROM subtree;
+
+-- name: GetNotebookNoteCounts :many
+SELECT n.notebook_id::BIGINT AS notebook_id, COUNT(*) AS notes_count
+FROM notes n
+WHERE n.user_id = $1
+  AND n.notebook_id IS NOT NULL
+  AND n.trashed_at IS NULL
+GROUP BY n.notebook_id;
+
+-- name: MoveNotesBetweenNotebooks :execrows
+UPDATE notes
+SET notebook_id = sqlc.narg(target_id)::BIGINT,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE notebook_id = ANY (@notebook_ids::BIGINT[]);
+
+-- name: TrashNotesInNotebooks :execrows
+UPDATE notes
+SET trashed_at  = NOW(),
+    notebook_id = NULL
+WHERE notebook_id = ANY (@notebook_ids::BIGINT[]);
+
+-- name: SetNotePinned :exec
+UPDATE notes
+SET pinned     = $1,
+    version    = version + 1,
+    updated_at = NOW()
+WHERE id = $2;
+-- name: GetUserByLogin :one
+SELECT DISTINCT u.*
+FROM users u
+WHERE u.login = $1;
+
+-- name: ShareNote :exec
+INSERT INTO shares (owner_id, user_id, note_id, permission)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, note_id) WHERE note_id IS NOT NULL DO UPDATE SET permission = EXCLUDED.permission;
+
+-- name: ShareNotebook :exec
+INSERT INTO shares (owner_id, user_id, notebook_id, permission)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, notebook_id) WHERE notebook_id IS NOT NULL DO UPDATE SET permission = EXCLUDED.permission;
+
+-- name: GetShareById :one
+SELECT s.*
+FROM shares s
+WHERE s.id = $1;
+
+-- name: DeleteShare :exec
+DELETE
+FROM shares
+WHERE id = $1
+  AND owner_id = $2;
+
+-- name: GetSharesByNoteId :many
+SELECT s.*, u.login
+FROM shares s
+         JOIN users u ON u.id = s.user_id
+WHERE s.note_id = $1
+ORDER BY u.login, s.id;
+
+-- name: GetSharesByNotebookId :many
+SELECT s.*, u.login
+FROM shares s
+         JOIN users u ON u.id = s.user_id
+WHERE s.notebook_id = $1
+ORDER BY u.login, s.id;
+
+-- name: GetNoteSharePermission :one
+WITH RECURSIVE ancestors AS (SELECT nb.id, nb.parent_id
+                             FROM notebooks nb
+                                      JOIN notes n ON n.notebook_id = nb.id
+                             WHERE n.id = @note_id
+                             UNION ALL
+                             SELECT parent.id, parent.parent_id
+                             FROM notebooks parent
+                                      JOIN ancestors ON parent.id = ancestors.parent_id)
+SELECT COUNT(*)                                                  AS shares_count,
+       COALESCE(BOOL_OR(s.permission = 'edit'), FALSE)::BOOLEAN AS can_edit
+FROM shares s
+WHERE s.user_id = @user_id
+  AND (s.note_id = @note_id OR s.notebook_id IN (SELECT ancestors.id FROM ancestors));
+
+-- name: GetNotesSharedWithUser :many
+WITH RECURSIVE shared_notebooks AS (SELECT s.notebook_id AS id, s.permission
+                                    FROM shares s
+                                    WHERE s.user_id = @user_id
+                                      AND s.notebook_id IS NOT NULL
+                                    UNION ALL
+                                    SELECT child.id, shared_notebooks.permission
+                                    FROM notebooks child
+                                             JOIN shared_notebooks ON child.parent_id = shared_notebooks.id),
+               shared_notes AS (SELECT s.note_id AS id, s.permission
+                                FROM shares s
+                                WHERE s.user_id = @user_id
+                                  AND s.note_id IS NOT NULL
+                                UNION ALL
+                                SELECT nn.id, shared_notebooks.permission
+                                FROM notes nn
+                                         JOIN shared_notebooks ON nn.notebook_id = shared_notebooks.id)
+SELECT n.*,
+       u.login                                          AS owner_login,
+       BOOL_OR(shared_notes.permission = 'edit')::BOOLEAN AS can_edit
+FROM notes n
+         JOIN shared_notes ON shared_notes.id = n.id
+         JOIN users u ON u.id = n.user_id
+WHERE n.user_id <> @user_id
+  AND n.trashed_at IS NULL
+GROUP BY n.id, u.login
+ORDER BY n.pinned DESC, n.created_at, n.id;
+
+-- name: CreatePublicLink :one
+INSERT INTO public_links (note_id, user_id, token, password, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: GetPublicLinkByToken :one
+SELECT pl.*
+FROM public_links pl
+WHERE pl.token = $1;
+
+-- name: GetActivePublicLinksByUserId :many
+SELECT pl.*, n.name AS note_name
+FROM public_links pl
+         JOIN notes n ON n.id = pl.note_id
+WHERE pl.user_id = $1
+  AND pl.revoked_at IS NULL
+  AND (pl.expires_at IS NULL OR pl.expires_at > NOW())
+ORDER BY pl.created_at DESC, pl.id DESC;
+
+-- name: RevokePublicLink :execrows
+UPDATE public_links
+SET revoked_at = NOW()
+WHERE id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL;
+
+-- name: CreateAttachment :one
+INSERT INTO attachments (note_id, user_id, blob_key, thumb_key, file_name, content_type, size)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id;
+
+-- name: GetAttachmentById :one
+SELECT a.*
+FROM attachments a
+WHERE a.id = $1;
+
+-- name: GetAttachmentsByNoteId :many
+SELECT a.*
+FROM attachments a
+WHERE a.note_id = $1
+ORDER BY a.created_at, a.id;
+
+-- name: GetUserAttachmentsSize :one
+SELECT COALESCE(SUM(a.size), 0)::BIGINT AS total_size
+FROM attachments a
+WHERE a.user_id = $1;
+
+-- name: LockUserForUpdate :exec
+SELECT u.id
+FROM users u
+WHERE u.id = $1
+FOR UPDATE;
+
+-- name: DeleteAttachmentById :exec
+DELETE
+FROM attachments
+WHERE id = $1;
+
+-- name: ImportNote :one
+INSERT INTO notes (user_id, name, description, is_completed, deadline_at, priority, pinned, created_at, updated_at,
+                   completed_at, trashed_at)
+VALUES (@user_id, @name, @description, @is_completed, @deadline_at, @priority, @pinned,
+        COALESCE(sqlc.narg(created_at)::TIMESTAMPTZ, NOW()), NOW(),
+        CASE WHEN @is_completed THEN NOW() END, sqlc.narg(trashed_at)::TIMESTAMPTZ)
+RETURNING id;
+
+-- name: UpsertTag :one
+INSERT INTO tags (user_id, name)
+VALUES ($1, $2)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id;
+
+-- name: GetTagByName :one
+SELECT t.*
+FROM tags t
+WHERE t.user_id = $1
+  AND t.name = $2;
+
+-- name: AddNoteTag :exec
+INSERT INTO note_tags (note_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: GetTagsByNoteIds :many
+SELECT nt.note_id, t.name
+FROM note_tags nt
+         JOIN tags t ON t.id = nt.tag_id
+WHERE nt.note_id = ANY (@note_ids::BIGINT[])
+ORDER BY t.name;
+
+-- name: GetUserById :one
+SELECT u.*
+FROM users u
+WHERE u.id = $1;
+
+-- name: GetAllNotesByUserId :many
+SELECT n.*
+FROM notes n
+WHERE n.user_id = $1
+ORDER BY n.created_at, n.id;
+
+-- name: GetAttachmentsByUserId :many
+SELECT a.*
+FROM attachments a
+         JOIN notes n ON n.id = a.note_id
+WHERE n.user_id = $1
+ORDER BY a.note_id, a.id;
+
+-- name: CreateExport :one
+INSERT INTO exports (user_id)
+VALUES ($1)
+RETURNING id;
+
+-- name: CountActiveExportsByUserId :one
+SELECT COUNT(*)
+FROM exports e
+WHERE e.user_id = $1
+  AND e.status IN ('pending', 'running');
+
+-- name: GetExportsByUserId :many
+SELECT e.*
+FROM exports e
+WHERE e.user_id = $1
+ORDER BY e.created_at DESC, e.id DESC
+LIMIT 10;
+
+-- name: GetExportById :one
+SELECT e.*
+FROM exports e
+WHERE e.id = $1;
+
+-- name: ClaimExport :one
+UPDATE exports
+SET status     = 'running',
+    claimed_at = NOW()
+WHERE id = (SELECT e.id
+            FROM exports e
+            WHERE e.status = 'pending'
+            ORDER BY e.id
+            LIMIT 1 FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: RenewExportLease :exec
+UPDATE exports
+SET claimed_at = NOW()
+WHERE id = $1
+  AND status = 'running';
+
+-- name: ReclaimExports :execrows
+UPDATE exports
+SET status = 'pending'
+WHERE status = 'running'
+  AND (claimed_at IS NULL OR claimed_at < NOW() - MAKE_INTERVAL(secs => @lease_seconds::FLOAT8));
+
+-- name: FinishExport :exec
+UPDATE exports
+SET status      = 'ready',
+    blob_key    = $2,
+    size        = $3,
+    finished_at = NOW()
+WHERE id = $1;
+
+-- name: FailExport :exec
+UPDATE exports
+SET status      = 'failed',
+    error       = $2,
+    finished_at = NOW()
+WHERE id = $1;
+
+-- name: GetExpiredExports :many
+SELECT e.*
+FROM exports e
+WHERE e.finished_at < @before::TIMESTAMPTZ;
+
+-- name: DeleteExportById :exec
+DELETE
+FROM exports
+WHERE id = $1;
+
+-- name: DeleteBulkActionsByUserId :exec
+DELETE
+FROM bulk_actions
+WHERE user_id = $1;
+
+-- name: CreateBulkAction :one
+INSERT INTO bulk_actions (user_id, action, tag_id)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- name: SnapshotBulkActionNotes :execrows
+INSERT INTO bulk_action_notes (bulk_action_id, note_id, is_completed, completed_at, deadline_at, notebook_id, trashed_at,
+                               had_tag)
+SELECT @bulk_action_id::BIGINT,
+       n.id,
+       n.is_completed,
+       n.completed_at,
+       n.deadline_at,
+       n.notebook_id,
+       n.trashed_at,
+       EXISTS (SELECT 1 FROM note_tags nt WHERE nt.note_id = n.id AND nt.tag_id = sqlc.narg(tag_id)::BIGINT)
+FROM notes n
+WHERE n.user_id = @user_id::BIGINT
+  AND n.trashed_at IS NULL
+  AND n.id = ANY (@note_ids::BIGINT[]);
+
+-- name: GetBulkActionNotes :many
+SELECT n.*
+FROM notes n
+         JOIN bulk_action_notes b ON b.note_id = n.id
+WHERE b.bulk_action_id = $1
+  AND b.created = FALSE
+ORDER BY n.id;
+
+-- name: AddBulkActionCreatedNote :exec
+INSERT INTO bulk_action_notes (bulk_action_id, note_id, is_completed, created)
+VALUES ($1, $2, FALSE, TRUE);
+
+-- name: BulkSetCompleted :exec
+UPDATE notes
+SET is_completed = @is_completed,
+    version      = version + 1,
+    updated_at   = NOW(),
+    completed_at = CASE WHEN @is_completed THEN COALESCE(completed_at, NOW()) END
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = @bulk_action_id::BIGINT);
+
+-- name: BulkTrash :exec
+UPDATE notes
+SET trashed_at = NOW()
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = @bulk_action_id::BIGINT);
+
+-- name: BulkSetNotebook :exec
+UPDATE notes
+SET notebook_id = sqlc.narg(notebook_id)::BIGINT,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = @bulk_action_id::BIGINT);
+
+-- name: BulkSetDeadline :exec
+UPDATE notes
+SET deadline_at = sqlc.narg(deadline_at)::DATE,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = @bulk_action_id::BIGINT);
+
+-- name: BulkAddTag :exec
+INSERT INTO note_tags (note_id, tag_id)
+SELECT b.note_id, @tag_id::BIGINT
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = @bulk_action_id::BIGINT
+ON CONFLICT DO NOTHING;
+
+-- name: BulkRemoveTag :exec
+DELETE
+FROM note_tags
+WHERE tag_id = @tag_id::BIGINT
+  AND note_id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = @bulk_action_id::BIGINT);
+
+-- name: GetLastBulkAction :one
+SELECT ba.*
+FROM bulk_actions ba
+WHERE ba.user_id = $1
+ORDER BY ba.id DESC
+LIMIT 1;
+
+-- name: CountBulkActionNotes :one
+SELECT COUNT(*)
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = $1
+  AND b.created = FALSE;
+
+-- name: UndoBulkCompleted :exec
+UPDATE notes n
+SET is_completed = b.is_completed,
+    completed_at = b.completed_at,
+    version      = n.version + 1,
+    updated_at   = NOW()
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = @bulk_action_id::BIGINT
+  AND b.created = FALSE
+  AND n.id = b.note_id;
+
+-- name: DeleteBulkActionCreatedNotes :exec
+DELETE
+FROM notes
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = @bulk_action_id::BIGINT AND b.created);
+
+-- name: UndoBulkTrash :exec
+UPDATE notes n
+SET trashed_at = b.trashed_at
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = @bulk_action_id::BIGINT
+  AND n.id = b.note_id;
+
+-- name: UndoBulkNotebook :exec
+UPDATE notes n
+SET notebook_id = (SELECT nb.id FROM notebooks nb WHERE nb.id = b.notebook_id),
+    version     = n.version + 1,
+    updated_at  = NOW()
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = @bulk_action_id::BIGINT
+  AND n.id = b.note_id;
+
+-- name: UndoBulkDeadline :exec
+UPDATE notes n
+SET deadline_at = b.deadline_at,
+    version     = n.version + 1,
+    updated_at  = NOW()
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = @bulk_action_id::BIGINT
+  AND n.id = b.note_id;
+
+-- name: UndoBulkAddTag :exec
+DELETE
+FROM note_tags nt
+    USING bulk_action_notes b
+WHERE b.bulk_action_id = @bulk_action_id::BIGINT
+  AND b.had_tag = FALSE
+  AND nt.note_id = b.note_id
+  AND nt.tag_id = @tag_id::BIGINT;
+
+-- name: UndoBulkRemoveTag :exec
+INSERT INTO note_tags (note_id, tag_id)
+SELECT b.note_id, @tag_id::BIGINT
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = @bulk_action_id::BIGINT
+  AND b.had_tag
+ON CONFLICT DO NOTHING;
+
+-- name: MarkBulkActionUndone :execrows
+UPDATE bulk_actions
+SET undone_at = NOW()
+WHERE id = $1
+  AND undone_at IS NULL;
+
+-- name: GetNoteTemplatesByUserId :many
+SELECT t.*
+FROM note_templates t
+WHERE t.user_id = $1
+ORDER BY t.name;
+
+-- name: GetNoteTemplateById :one
+SELECT t.*
+FROM note_templates t
+WHERE t.id = $1
+  AND t.user_id = $2;
+
+-- name: CreateNoteTemplate :one
+INSERT INTO note_templates (user_id, name, title, description, tags, deadline_offset)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id;
+
+-- name: UpdateNoteTemplate :execrows
+UPDATE note_templates
+SET name            = @name,
+    title           = @title,
+    description     = @description,
+    tags            = @tags,
+    deadline_offset = @deadline_offset
+WHERE id = @id
+  AND user_id = @user_id;
+
+-- name: DeleteNoteTemplate :execrows
+DELETE
+FROM note_templates
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: DeleteNoteReferences :exec
+DELETE
+FROM note_references
+WHERE note_id = $1;
+
+-- name: AddNoteReference :exec
+INSERT INTO note_references (note_id, target_id, target_name)
+VALUES (@note_id, sqlc.narg(target_id)::BIGINT, sqlc.narg(target_name)::TEXT);
+
+-- name: CopySeriesReferences :exec
+INSERT INTO note_references (note_id, target_id, target_name)
+SELECT n.id, r.target_id, r.target_name
+FROM notes n
+         JOIN note_references r ON r.note_id = @note_id::BIGINT
+WHERE COALESCE(n.series_id, n.id) = @series_id::BIGINT
+  AND n.is_completed = FALSE
+  AND n.id <> @note_id::BIGINT;
+
+-- name: DeleteSeriesReferences :exec
+DELETE
+FROM note_references r
+    USING notes n
+WHERE r.note_id = n.id
+  AND COALESCE(n.series_id, n.id) = @series_id::BIGINT
+  AND n.is_completed = FALSE
+  AND n.id <> @note_id::BIGINT;
+
+-- name: GetLinkTargets :many
+SELECT n.*
+FROM notes n
+WHERE n.trashed_at IS NULL
+  AND (n.id = ANY (@ids::BIGINT[])
+    OR (n.user_id = @user_id AND LOWER(n.name) = ANY (@names::TEXT[])))
+ORDER BY n.id;
+
+-- name: GetBacklinks :many
+SELECT n.*
+FROM notes n
+WHERE n.trashed_at IS NULL
+  AND n.id <> @target_id::BIGINT
+  AND n.id IN (SELECT r.note_id
+               FROM note_references r
+               WHERE r.target_id = @target_id::BIGINT
+                  OR (r.target_id IS NULL AND LOWER(r.target_name) = LOWER(@name::TEXT)))
+  AND (n.user_id = @user_id OR EXISTS (SELECT 1
+                                       FROM note_references r
+                                       WHERE r.note_id = n.id
+                                         AND r.target_id = @target_id::BIGINT))
+ORDER BY n.name;
+
+-- name: GetNotesReferencingName :many
+SELECT n.*
+FROM notes n
+WHERE n.user_id = @user_id
+  AND n.id IN (SELECT r.note_id
+               FROM note_references r
+               WHERE r.target_id IS NULL
+                 AND LOWER(r.target_name) = LOWER(@name::TEXT));
+
+-- name: CountNotesByName :one
+SELECT COUNT(*)
+FROM notes n
+WHERE n.user_id = @user_id
+  AND n.trashed_at IS NULL
+  AND n.id <> @exclude_id::BIGINT
+  AND LOWER(n.name) = LOWER(@name::TEXT);
+
+-- name: UpdateNoteDescription :one
+UPDATE notes
+SET description = @description,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE id = @id
+  AND version = @version
+RETURNING version;
+
+-- name: GetBrokenReferences :many
+SELECT r.note_id, n.name AS note_name, r.target_id, r.target_name
+FROM note_references r
+         JOIN notes n ON n.id = r.note_id
+WHERE n.user_id = @user_id
+  AND n.trashed_at IS NULL
+  AND NOT EXISTS (SELECT 1
+                  FROM notes t
+                  WHERE t.trashed_at IS NULL
+                    AND ((r.target_id IS NOT NULL AND t.id = r.target_id)
+                      OR (r.target_id IS NULL AND t.user_id = n.user_id AND LOWER(t.name) = LOWER(r.target_name))))
+ORDER BY n.name, r.target_name, r.target_id;
+
+-- name: CreateComment :one
+INSERT INTO note_comments (note_id, user_id, body)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- name: GetCommentById :one
+SELECT c.*
+FROM note_comments c
+WHERE c.id = $1;
+
+-- name: UpdateComment :execrows
+UPDATE note_comments
+SET body       = @body,
+    updated_at = NOW()
+WHERE id = @id
+  AND user_id = @user_id;
+
+-- name: DeleteComment :execrows
+DELETE
+FROM note_comments
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: GetCommentsByNoteId :many
+SELECT c.*, u.login
+FROM note_comments c
+         JOIN users u ON u.id = c.user_id
+WHERE c.note_id = $1
+ORDER BY c.created_at, c.id;
+
+-- name: CreateNoteActivity :exec
+INSERT INTO note_activity (note_id, user_id, kind)
+VALUES ($1, $2, $3);
+
+-- name: GetNoteActivityByNoteId :many
+SELECT a.*, u.login
+FROM note_activity a
+         JOIN users u ON u.id = a.user_id
+WHERE a.note_id = $1
+ORDER BY a.created_at, a.id;
+
+-- name: GetUsersByLogins :many
+SELECT u.*
+FROM users u
+WHERE u.login = ANY (@logins::TEXT[]);
+
+-- name: CreateNotification :exec
+INSERT INTO notifications (user_id, actor_id, note_id, comment_id, kind)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetNotificationsByUserId :many
+SELECT nf.*, u.login AS actor_login, n.name AS note_name
+FROM notifications nf
+         JOIN users u ON u.id = nf.actor_id
+         JOIN notes n ON n.id = nf.note_id
+WHERE nf.user_id = $1
+ORDER BY nf.created_at DESC, nf.id DESC
+LIMIT 100;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications nf
+WHERE nf.user_id = $1
+  AND nf.read_at IS NULL;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+  AND read_at IS NULL;
+
+-- name: GetNoteAudience :many
+WITH RECURSIVE ancestors AS (SELECT nb.id, nb.parent_id
+                             FROM notebooks nb
+                                      JOIN notes n ON n.notebook_id = nb.id
+                             WHERE n.id = @note_id
+                             UNION ALL
+                             SELECT parent.id, parent.parent_id
+                             FROM notebooks parent
+                                      JOIN ancestors ON parent.id = ancestors.parent_id)
+SELECT DISTINCT s.user_id
+FROM shares s
+WHERE s.note_id = @note_id
+   OR s.notebook_id IN (SELECT ancestors.id FROM ancestors);
+
+-- name: GetWebhooksByUserId :many
+SELECT w.*
+FROM webhooks w
+WHERE w.user_id = $1
+ORDER BY w.id;
+
+-- name: GetWebhookById :one
+SELECT w.*
+FROM webhooks w
+WHERE w.id = $1;
+
+-- name: CreateWebhook :one
+INSERT INTO webhooks (user_id, url, events, secret)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: SetWebhookActive :execrows
+UPDATE webhooks
+SET active = $1
+WHERE id = $2
+  AND user_id = $3;
+
+-- name: DeleteWebhook :execrows
+DELETE
+FROM webhooks
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: GetNoteWebhooks :many
+SELECT w.*
+FROM webhooks w
+         JOIN notes n ON n.user_id = w.user_id
+WHERE n.id = @note_id
+  AND w.active
+  AND @event::TEXT = ANY (w.events)
+ORDER BY w.id;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event, payload)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ClaimWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status     = 'running',
+    claimed_at = NOW()
+WHERE id = (SELECT d.id
+            FROM webhook_deliveries d
+            WHERE d.status = 'pending'
+              AND d.next_attempt_at <= NOW()
+            ORDER BY d.next_attempt_at, d.id
+            LIMIT 1 FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: ReclaimWebhookDeliveries :execrows
+UPDATE webhook_deliveries
+SET status = 'pending'
+WHERE status = 'running'
+  AND (claimed_at IS NULL OR claimed_at < NOW() - MAKE_INTERVAL(secs => @lease_seconds::FLOAT8));
+
+-- name: FinishWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status          = 'delivered',
+    attempts        = attempts + 1,
+    response_status = $2,
+    error           = NULL,
+    delivered_at    = NOW()
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status          = 'pending',
+    attempts        = attempts + 1,
+    response_status = @response_status,
+    error           = @error,
+    next_attempt_at = @next_attempt_at
+WHERE id = @id;
+
+-- name: FailWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status          = 'failed',
+    attempts        = attempts + 1,
+    response_status = @response_status,
+    error           = @error
+WHERE id = @id;
+
+-- name: RequeueWebhookDelivery :execrows
+UPDATE webhook_deliveries d
+SET status          = 'pending',
+    attempts        = 0,
+    next_attempt_at = NOW()
+FROM webhooks w
+WHERE d.id = @id
+  AND d.status = 'failed'
+  AND w.id = d.webhook_id
+  AND w.user_id = @user_id;
+
+-- name: GetWebhookDeliveriesByUserId :many
+SELECT d.*, w.url
+FROM webhook_deliveries d
+         JOIN webhooks w ON w.id = d.webhook_id
+WHERE w.user_id = $1
+ORDER BY d.id DESC
+LIMIT 50;
+
+-- name: GetOverdueNotesForWebhooks :many
+SELECT n.*
+FROM notes n
+         LEFT JOIN webhook_overdue_notes o ON o.note_id = n.id AND o.deadline_at = n.deadline_at
+WHERE n.is_completed = FALSE
+  AND n.trashed_at IS NULL
+  AND n.deadline_at < CURRENT_DATE
+  AND o.note_id IS NULL
+  AND EXISTS (SELECT 1
+              FROM webhooks w
+              WHERE w.user_id = n.user_id
+                AND w.active
+                AND 'note.overdue' = ANY (w.events))
+ORDER BY n.id
+LIMIT 100;
+
+-- name: MarkNoteOverdueSent :execrows
+INSERT INTO webhook_overdue_notes (note_id, deadline_at)
+VALUES ($1, $2)
+ON CONFLICT (note_id) DO UPDATE SET deadline_at = EXCLUDED.deadline_at
+WHERE webhook_overdue_notes.deadline_at <> EXCLUDED.deadline_at;
+
+-- name: GetWeeklyNoteStats :many
+WITH weeks AS (SELECT GENERATE_SERIES(DATE_TRUNC('week', @since::TIMESTAMPTZ), DATE_TRUNC('week', NOW()),
+                                      INTERVAL '1 week') AS week_start)
+SELECT w.week_start::TIMESTAMPTZ AS week_start,
+       (SELECT COUNT(*)
+        FROM notes n
+        WHERE n.user_id = @user_id::BIGINT
+          AND n.trashed_at IS NULL
+          AND n.created_at >= w.week_start
+          AND n.created_at < w.week_start + INTERVAL '1 week')::BIGINT AS created_count,
+       (SELECT COUNT(*)
+        FROM notes n
+        WHERE n.user_id = @user_id::BIGINT
+          AND n.trashed_at IS NULL
+          AND n.completed_at >= w.week_start
+          AND n.completed_at < w.week_start + INTERVAL '1 week')::BIGINT AS completed_count
+FROM weeks w
+ORDER BY w.week_start;
+
+-- name: GetNoteCompletionStats :one
+SELECT COUNT(*) AS notes_count,
+       COUNT(*) FILTER (WHERE n.is_completed) AS completed_count,
+       COUNT(*) FILTER (WHERE n.is_completed AND n.deadline_at IS NOT NULL) AS completed_with_deadline_count,
+       COUNT(*) FILTER (WHERE n.is_completed AND n.completed_at < (n.deadline_at + 1)::TIMESTAMPTZ) AS completed_on_time_count,
+       COUNT(*) FILTER (WHERE NOT n.is_completed AND n.deadline_at < CURRENT_DATE) AS overdue_count,
+       COALESCE(AVG(EXTRACT(EPOCH FROM n.completed_at - n.created_at)) FILTER (WHERE n.is_completed),
+                0)::FLOAT8 AS avg_completion_seconds
+FROM notes n
+WHERE n.user_id = @user_id
+  AND n.trashed_at IS NULL;
+
+-- name: GetTagNoteStats :many
+SELECT t.name, COUNT(*) AS notes_count, COUNT(*) FILTER (WHERE n.is_completed) AS completed_count
+FROM note_tags nt
+         JOIN tags t ON t.id = nt.tag_id
+         JOIN notes n ON n.id = nt.note_id
+WHERE t.user_id = @user_id
+  AND n.trashed_at IS NULL
+GROUP BY t.id, t.name
+ORDER BY notes_count DESC, t.name;
+
+-- name: GetNotebookNoteStats :many
+SELECT nb.name, COUNT(*) AS notes_count, COUNT(*) FILTER (WHERE n.is_completed) AS completed_count
+FROM notes n
+         LEFT JOIN notebooks nb ON nb.id = n.notebook_id
+WHERE n.user_id = @user_id
+  AND n.trashed_at IS NULL
+GROUP BY nb.id, nb.name
+ORDER BY notes_count DESC, nb.name NULLS LAST;
+
+-- name: SetUserLastLogin :exec
+UPDATE users
+SET last_login_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password                = $2,
+    password_reset_required = FALSE
+WHERE id = $1;
+
+-- name: GetUsersWithNoteCounts :many
+SELECT u.id,
+       u.login,
+       u.role,
+       u.disabled_at,
+       u.password_reset_required,
+       u.last_login_at,
+       u.totp_enabled_at,
+       COUNT(n.id) FILTER (WHERE n.trashed_at IS NULL) AS notes_count,
+       (SELECT COUNT(*)
+        FROM sessions s
+        WHERE s.user_id = u.id
+          AND s.expires_at > NOW())::BIGINT             AS sessions_count
+FROM users u
+         LEFT JOIN notes n ON n.user_id = u.id
+GROUP BY u.id
+ORDER BY u.login;
+
+-- name: SetUserDisabled :execrows
+UPDATE users
+SET disabled_at = CASE WHEN @disabled::BOOLEAN THEN COALESCE(disabled_at, NOW()) END
+WHERE id = @id;
+
+-- name: RequireUserPasswordReset :execrows
+UPDATE users
+SET password_reset_required = TRUE
+WHERE id = $1;
+
+-- name: GetSystemStats :one
+SELECT (SELECT COUNT(*) FROM users)::BIGINT                                  AS users_count,
+       (SELECT COUNT(*) FROM users WHERE role = 'admin')::BIGINT             AS admins_count,
+       (SELECT COUNT(*) FROM users WHERE disabled_at IS NOT NULL)::BIGINT    AS disabled_users_count,
+       (SELECT COUNT(*) FROM notes WHERE trashed_at IS NULL)::BIGINT         AS notes_count,
+       (SELECT COUNT(*) FROM notes WHERE trashed_at IS NOT NULL)::BIGINT     AS trashed_notes_count,
+       (SELECT COUNT(*) FROM attachments)::BIGINT                            AS attachments_count,
+       (SELECT COALESCE(SUM(size), 0) FROM attachments)::BIGINT              AS attachments_size,
+       (SELECT COUNT(*) FROM webhook_deliveries WHERE status = 'pending')::BIGINT AS pending_deliveries_count,
+       (SELECT COUNT(*) FROM sessions WHERE expires_at > NOW())::BIGINT      AS sessions_count;
+
+-- name: GetLoginAttemptForUpdate :one
+INSERT INTO login_attempts (key, failures, last_failure_at)
+VALUES (@key, 0, NOW())
+ON CONFLICT (key) DO UPDATE
+    SET key = EXCLUDED.key
+RETURNING locked_until, NOW()::TIMESTAMPTZ AS now;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_attempts (key, failures, last_failure_at)
+VALUES (@key, 1, NOW())
+ON CONFLICT (key) DO UPDATE
+    SET failures        = CASE
+                              WHEN login_attempts.last_failure_at < NOW() - MAKE_INTERVAL(secs => @reset_seconds::FLOAT8)
+                                  THEN 1
+                              ELSE login_attempts.failures + 1 END,
+        last_failure_at = NOW()
+RETURNING failures;
+
+-- name: LockLoginAttempts :exec
+UPDATE login_attempts
+SET locked_until = GREATEST(locked_until, NOW() + MAKE_INTERVAL(secs => @seconds::FLOAT8))
+WHERE key = @key;
+
+-- name: ReleaseLoginAttempt :exec
+UPDATE login_attempts
+SET failures     = GREATEST(failures - 1, 0),
+    locked_until = CASE WHEN failures - 1 < @free_attempts::INTEGER THEN NULL ELSE locked_until END
+WHERE key = @key;
+
+-- name: ClearLoginAttempts :exec
+DELETE
+FROM login_attempts
+WHERE key = $1;
+
+-- name: CreateLoginAudit :exec
+INSERT INTO login_audit (login, ip, reason)
+VALUES ($1, $2, $3);
+
+-- name: GetRecentLoginAudit :many
+SELECT a.*
+FROM login_audit a
+ORDER BY a.created_at DESC, a.id DESC
+LIMIT $1;
+
+-- name: SetUserTotpSecret :execrows
+UPDATE users
+SET totp_secret       = $2,
+    totp_last_counter = 0
+WHERE id = $1
+  AND totp_enabled_at IS NULL;
+
+-- name: EnableUserTotp :execrows
+UPDATE users
+SET totp_enabled_at   = NOW(),
+    totp_last_counter = $2
+WHERE id = $1
+  AND totp_secret IS NOT NULL
+  AND totp_enabled_at IS NULL;
+
+-- name: UseTotpCounter :execrows
+UPDATE users
+SET totp_last_counter = $2
+WHERE id = $1
+  AND totp_last_counter < $2;
+
+-- name: ResetUserTotp :execrows
+UPDATE users
+SET totp_secret       = NULL,
+    totp_enabled_at   = NULL,
+    totp_last_counter = 0
+WHERE id = $1;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE
+FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+VALUES ($1, $2);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*)
+FROM recovery_codes
+WHERE user_id = $1
+  AND used_at IS NULL;
+
+-- name: CreateWebauthnCredential :one
+INSERT INTO webauthn_credentials (user_id, name, credential_id, public_key, sign_count)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: GetWebauthnCredentialsByUserId :many
+SELECT c.*
+FROM webauthn_credentials c
+WHERE c.user_id = $1
+ORDER BY c.created_at, c.id;
+
+-- name: GetWebauthnCredentialByCredentialId :one
+SELECT c.*
+FROM webauthn_credentials c
+WHERE c.credential_id = $1;
+
+-- name: UpdateWebauthnCredentialUsage :execrows
+UPDATE webauthn_credentials
+SET sign_count   = @sign_count,
+    last_used_at = NOW()
+WHERE id = @id
+  AND sign_count = @previous_sign_count;
+
+-- name: DeleteWebauthnCredential :execrows
+DELETE
+FROM webauthn_credentials
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: CreateSession :exec
+INSERT INTO sessions (token_hash, user_id, expires_at)
+VALUES (@token_hash, @user_id, NOW() + MAKE_INTERVAL(secs => @ttl_seconds::FLOAT8));
+
+-- name: GetSessionUser :one
+SELECT u.*
+FROM sessions s
+         JOIN users u ON u.id = s.user_id
+WHERE s.token_hash = $1
+  AND s.expires_at > NOW();
+
+-- name: DeleteSession :exec
+DELETE
+FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteUserSessions :execrows
+DELETE
+FROM sessions
+WHERE user_id = $1;
+
+-- name: DeleteExpiredSessions :exec
+DELETE
+FROM sessions
+WHERE expires_at <= NOW();
+
+-- name: CreatePendingLogin :exec
+INSERT INTO pending_logins (token_hash, user_id, expires_at)
+VALUES (@token_hash, @user_id, NOW() + MAKE_INTERVAL(secs => @ttl_seconds::FLOAT8));
+
+-- name: GetPendingLoginUserId :one
+SELECT user_id
+FROM pending_logins
+WHERE token_hash = $1
+  AND expires_at > NOW();
+
+-- name: DeletePendingLogin :exec
+DELETE
+FROM pending_logins
+WHERE token_hash = $1;
+
+-- name: DeleteExpiredPendingLogins :exec
+DELETE
+FROM pending_logins
+WHERE expires_at <= NOW();
+
+-- name: CreatePasskeyCeremony :exec
+INSERT INTO passkey_ceremonies (token_hash, user_id, challenge, expires_at)
+VALUES (@token_hash, @user_id, @challenge, NOW() + MAKE_INTERVAL(secs => @ttl_seconds::FLOAT8));
+
+-- name: TakePasskeyCeremony :one
+DELETE
+FROM passkey_ceremonies
+WHERE token_hash = @token_hash
+  AND user_id = @user_id
+  AND expires_at > NOW()
+RETURNING challenge;
+
+-- name: DeleteExpiredPasskeyCeremonies :exec
+DELETE
+FROM passkey_ceremonies
+WHERE expires_at <= NOW();
@@ -1,8 +1,30 @@
 CREATE TABLE IF NOT EXISTS users
 (
-    id       BIGSERIAL   NOT NULL PRIMARY KEY,
-    login    VARCHAR(20) NOT NULL,
-    password VARCHAR(128) NOT NULL
+    id                      BIGSERIAL    NOT NULL PRIMARY KEY,
+    login                   VARCHAR(20)  NOT NULL,
+    password                VARCHAR(128) NOT NULL,
+    role                    VARCHAR(10)  NOT NULL DEFAULT 'user',
+    disabled_at             TIMESTAMPTZ,
+    password_reset_required BOOLEAN      NOT NULL DEFAULT FALSE,
+    last_login_at           TIMESTAMPTZ,
+    totp_secret             VARCHAR(64),
+    totp_enabled_at         TIMESTAMPTZ,
+    totp_last_counter       BIGINT       NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS notebooks
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    parent_id  BIGINT,
+    name       VARCHAR(50) NOT NULL,
+    created_at DATE        NOT NULL DEFAULT NOW()::DATE,
+    CONSTRAINT notebooks_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT notebooks_to_notebooks_id_fk FOREIGN KEY (parent_id)
+        REFERENCES notebooks (id)
+        ON DELETE CASCADE
 );
 
 CREATE TABLE IF NOT EXISTS notes
@@ -10,11 +32,421 @@ CREATE TABLE IF NOT EXISTS notes
     id           BIGSERIAL   NOT NULL PRIMARY KEY,
     user_id      BIGINT      NOT NULL,
     name         VARCHAR(50) NOT NULL,
-    description  VARCHAR(255),
+    description  TEXT,
     is_completed BOOLEAN     NOT NULL DEFAULT 'FALSE',
-    created_at   DATE        NOT NULL DEFAULT NOW()::DATE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     deadline_at  DATE,
+    recurrence   VARCHAR(100),
+    series_id    BIGINT,
+    notebook_id  BIGINT,
+    trashed_at   TIMESTAMPTZ,
+    priority     SMALLINT    NOT NULL DEFAULT 1,
+    pinned       BOOLEAN     NOT NULL DEFAULT 'FALSE',
+    version      INTEGER     NOT NULL DEFAULT 1,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
     CONSTRAINT notes_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT notes_to_notebooks_id_fk FOREIGN KEY (notebook_id)
+        REFERENCES notebooks (id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS notes_series_id_idx ON notes (series_id);
+CREATE INDEX IF NOT EXISTS notes_notebook_id_idx ON notes (notebook_id);
+CREATE INDEX IF NOT EXISTS notes_user_id_updated_at_idx ON notes (user_id, updated_at);
+
+CREATE TABLE IF NOT EXISTS digest_settings
+(
+    user_id      BIGINT       NOT NULL PRIMARY KEY,
+    enabled      BOOLEAN      NOT NULL DEFAULT 'FALSE',
+    email        VARCHAR(255) NOT NULL,
+    send_time    TIME         NOT NULL DEFAULT '08:00',
+    timezone     VARCHAR(64)  NOT NULL DEFAULT 'UTC',
+    days_ahead   INTEGER      NOT NULL DEFAULT 3,
+    last_sent_on DATE,
+    CONSTRAINT digest_settings_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shares
+(
+    id          BIGSERIAL   NOT NULL PRIMARY KEY,
+    owner_id    BIGINT      NOT NULL,
+    user_id     BIGINT      NOT NULL,
+    note_id     BIGINT,
+    notebook_id BIGINT,
+    permission  VARCHAR(10) NOT NULL DEFAULT 'view',
+    created_at  DATE        NOT NULL DEFAULT NOW()::DATE,
+    CONSTRAINT shares_to_owner_users_id_fk FOREIGN KEY (owner_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT shares_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT shares_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT shares_to_notebooks_id_fk FOREIGN KEY (notebook_id)
+        REFERENCES notebooks (id)
+        ON DELETE CASCADE,
+    CONSTRAINT shares_target_check CHECK ((note_id IS NULL) <> (notebook_id IS NULL)),
+    CONSTRAINT shares_permission_check CHECK (permission IN ('view', 'edit'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS shares_user_id_note_id_uidx ON shares (user_id, note_id) WHERE note_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS shares_user_id_notebook_id_uidx ON shares (user_id, notebook_id) WHERE notebook_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS public_links
+(
+    id         BIGSERIAL    NOT NULL PRIMARY KEY,
+    note_id    BIGINT       NOT NULL,
+    user_id    BIGINT       NOT NULL,
+    token      VARCHAR(64)  NOT NULL UNIQUE,
+    password   VARCHAR(128),
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CONSTRAINT public_links_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT public_links_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS public_links_user_id_idx ON public_links (user_id);
+
+CREATE TABLE IF NOT EXISTS attachments
+(
+    id           BIGSERIAL    NOT NULL PRIMARY KEY,
+    note_id      BIGINT       NOT NULL,
+    user_id      BIGINT       NOT NULL,
+    blob_key     VARCHAR(255) NOT NULL,
+    thumb_key    VARCHAR(255),
+    file_name    VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size         BIGINT       NOT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CONSTRAINT attachments_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT attachments_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS attachments_note_id_idx ON attachments (note_id);
+CREATE INDEX IF NOT EXISTS attachments_user_id_idx ON attachments (user_id);
+
+CREATE TABLE IF NOT EXISTS tags
+(
+    id      BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id BIGINT      NOT NULL,
+    name    VARCHAR(50) NOT NULL,
+    CONSTRAINT tags_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT tags_user_id_name_key UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS note_tags
+(
+    note_id BIGINT NOT NULL,
+    tag_id  BIGINT NOT NULL,
+    CONSTRAINT note_tags_pk PRIMARY KEY (note_id, tag_id),
+    CONSTRAINT note_tags_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT note_tags_to_tags_id_fk FOREIGN KEY (tag_id)
+        REFERENCES tags (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS note_tags_tag_id_idx ON note_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS exports
+(
+    id          BIGSERIAL    NOT NULL PRIMARY KEY,
+    user_id     BIGINT       NOT NULL,
+    status      VARCHAR(10)  NOT NULL DEFAULT 'pending',
+    blob_key    VARCHAR(255),
+    size        BIGINT       NOT NULL DEFAULT 0,
+    error       VARCHAR(255),
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    claimed_at  TIMESTAMPTZ,
+    CONSTRAINT exports_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT exports_status_check CHECK (status IN ('pending', 'running', 'ready', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS exports_user_id_idx ON exports (user_id);
+
+CREATE TABLE IF NOT EXISTS bulk_actions
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    action     VARCHAR(20) NOT NULL,
+    tag_id     BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    undone_at  TIMESTAMPTZ,
+    CONSTRAINT bulk_actions_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT bulk_actions_to_tags_id_fk FOREIGN KEY (tag_id)
+        REFERENCES tags (id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS bulk_actions_user_id_idx ON bulk_actions (user_id);
+
+CREATE TABLE IF NOT EXISTS bulk_action_notes
+(
+    bulk_action_id BIGINT  NOT NULL,
+    note_id        BIGINT  NOT NULL,
+    is_completed   BOOLEAN NOT NULL,
+    deadline_at    DATE,
+    notebook_id    BIGINT,
+    trashed_at     TIMESTAMPTZ,
+    had_tag        BOOLEAN NOT NULL DEFAULT 'FALSE',
+    created        BOOLEAN NOT NULL DEFAULT 'FALSE',
+    completed_at   TIMESTAMPTZ,
+    CONSTRAINT bulk_action_notes_pk PRIMARY KEY (bulk_action_id, note_id),
+    CONSTRAINT bulk_action_notes_to_bulk_actions_id_fk FOREIGN KEY (bulk_action_id)
+        REFERENCES bulk_actions (id)
+        ON DELETE CASCADE,
+    CONSTRAINT bulk_action_notes_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS note_templates
+(
+    id              BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id         BIGINT      NOT NULL,
+    name            VARCHAR(50) NOT NULL,
+    title           VARCHAR(50) NOT NULL DEFAULT '',
+    description     TEXT        NOT NULL DEFAULT '',
+    tags            TEXT[]      NOT NULL DEFAULT '{}',
+    deadline_offset VARCHAR(20),
+    CONSTRAINT note_templates_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT note_templates_user_id_name_key UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS note_references
+(
+    note_id     BIGINT NOT NULL,
+    target_id   BIGINT,
+    target_name TEXT,
+    CONSTRAINT note_references_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS note_references_note_id_idx ON note_references (note_id);
+CREATE INDEX IF NOT EXISTS note_references_target_id_idx ON note_references (target_id);
+CREATE INDEX IF NOT EXISTS note_references_target_name_idx ON note_references (LOWER(target_name));
+
+CREATE TABLE IF NOT EXISTS note_comments
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    note_id    BIGINT      NOT NULL,
+    user_id    BIGINT      NOT NULL,
+    body       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT note_comments_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT note_comments_to_users_id_fk FOREIGN KEY (user_id)
         REFERENCES users (id)
         ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+CREATE INDEX IF NOT EXISTS note_comments_note_id_idx ON note_comments (note_id);
+
+CREATE TABLE IF NOT EXISTS note_activity
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    note_id    BIGINT      NOT NULL,
+    user_id    BIGINT      NOT NULL,
+    kind       VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT note_activity_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT note_activity_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS note_activity_note_id_idx ON note_activity (note_id);
+
+CREATE TABLE IF NOT EXISTS notifications
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    actor_id   BIGINT      NOT NULL,
+    note_id    BIGINT      NOT NULL,
+    comment_id BIGINT,
+    kind       VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    read_at    TIMESTAMPTZ,
+    CONSTRAINT notifications_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT notifications_actor_to_users_id_fk FOREIGN KEY (actor_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT notifications_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT notifications_to_note_comments_id_fk FOREIGN KEY (comment_id)
+        REFERENCES note_comments (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, read_at);
+
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id         BIGSERIAL     NOT NULL PRIMARY KEY,
+    user_id    BIGINT        NOT NULL,
+    url        VARCHAR(2048) NOT NULL,
+    events     TEXT[]        NOT NULL,
+    secret     VARCHAR(100)  NOT NULL,
+    active     BOOLEAN       NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+    CONSTRAINT webhooks_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              BIGSERIAL    NOT NULL PRIMARY KEY,
+    webhook_id      BIGINT       NOT NULL,
+    event           VARCHAR(30)  NOT NULL,
+    payload         TEXT         NOT NULL,
+    status          VARCHAR(10)  NOT NULL DEFAULT 'pending',
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    response_status INTEGER,
+    error           VARCHAR(255),
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ,
+    claimed_at      TIMESTAMPTZ,
+    CONSTRAINT webhook_deliveries_to_webhooks_id_fk FOREIGN KEY (webhook_id)
+        REFERENCES webhooks (id)
+        ON DELETE CASCADE,
+    CONSTRAINT webhook_deliveries_status_check CHECK (status IN ('pending', 'running', 'delivered', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_queue_idx ON webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_overdue_notes
+(
+    note_id     BIGINT NOT NULL PRIMARY KEY,
+    deadline_at DATE   NOT NULL,
+    CONSTRAINT webhook_overdue_notes_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+    key             VARCHAR(100) NOT NULL PRIMARY KEY,
+    failures        INTEGER      NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    locked_until    TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS login_audit
+(
+    id         BIGSERIAL    NOT NULL PRIMARY KEY,
+    login      VARCHAR(64)  NOT NULL,
+    ip         VARCHAR(45)  NOT NULL,
+    reason     VARCHAR(10)  NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CONSTRAINT login_audit_reason_check CHECK (reason IN ('password', 'code', 'locked', 'disabled'))
+);
+
+CREATE INDEX IF NOT EXISTS login_audit_created_at_idx ON login_audit (created_at);
+
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    code_hash  VARCHAR(64) NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT recovery_codes_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_credentials
+(
+    id            BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id       BIGINT      NOT NULL,
+    name          VARCHAR(50) NOT NULL,
+    credential_id BYTEA       NOT NULL,
+    public_key    BYTEA       NOT NULL,
+    sign_count    BIGINT      NOT NULL DEFAULT 0,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at  TIMESTAMPTZ,
+    CONSTRAINT webauthn_credentials_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT webauthn_credentials_credential_id_key UNIQUE (credential_id)
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+CREATE TABLE IF NOT EXISTS sessions
+(
+    token_hash VARCHAR(64) NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT sessions_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+CREATE INDEX IF NOT EXISTS sessions_expires_at_idx ON sessions (expires_at);
+
+CREATE TABLE IF NOT EXISTS pending_logins
+(
+    token_hash VARCHAR(64) NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT pending_logins_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS pending_logins_expires_at_idx ON pending_logins (expires_at);
+
+CREATE TABLE IF NOT EXISTS passkey_ceremonies
+(
+    token_hash VARCHAR(64) NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    challenge  BYTEA       NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS passkey_ceremonies_expires_at_idx ON passkey_ceremonies (expires_at);
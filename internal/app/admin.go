@@ -0,0 +1,222 @@
+package app
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	AdminDisable        = "disable"
+	AdminEnable         = "enable"
+	AdminResetPassword  = "resetPassword"
+	AdminRevokeSessions = "revokeSessions"
+	AdminResetTwoFactor = "resetTwoFactor"
+)
+
+var startedAt = time.Now()
+
+// RequireRole lets the request through only if AuthNeeded put the required role
+// into the params.
+func RequireRole(role string, next httprouter.Handle) httprouter.Handle {
+	return func(rw http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("role") != role {
+			http.Error(rw, "Доступ запрещён", http.StatusForbidden)
+			return
+		}
+		next(rw, r, ps)
+	}
+}
+
+func (a App) AdminNeeded(next httprouter.Handle) httprouter.Handle {
+	return a.AuthNeeded(RequireRole(RoleAdmin, next))
+}
+
+type AdminUserDTO struct {
+	ID                    int64
+	Login                 string
+	IsAdmin               bool
+	Disabled              bool
+	DisabledAt            string
+	PasswordResetRequired bool
+	TwoFactor             bool
+	LastLoginAt           string
+	Notes                 int64
+	Sessions              int64
+}
+
+func MapAdminUser(user *repository.GetUsersWithNoteCountsRow) *AdminUserDTO {
+	return &AdminUserDTO{
+		ID:                    user.ID,
+		Login:                 user.Login,
+		IsAdmin:               user.Role == RoleAdmin,
+		Disabled:              user.DisabledAt.Valid,
+		DisabledAt:            formatNoteTime(user.DisabledAt),
+		PasswordResetRequired: user.PasswordResetRequired,
+		TwoFactor:             user.TotpEnabledAt.Valid,
+		LastLoginAt:           formatNoteTime(user.LastLoginAt),
+		Notes:                 user.NotesCount,
+		Sessions:              user.SessionsCount,
+	}
+}
+
+type LoginAuditDTO struct {
+	Login     string
+	IP        string
+	Reason    string
+	CreatedAt string
+}
+
+func MapLoginAudit(audit *repository.LoginAudit) *LoginAuditDTO {
+	reason := "неверный пароль"
+	switch audit.Reason {
+	case AuditWrongCode:
+		reason = "неверный код 2FA"
+	case AuditLocked:
+		reason = "вход временно заблокирован"
+	case AuditDisabled:
+		reason = "учётная запись заблокирована"
+	}
+	return &LoginAuditDTO{audit.Login, audit.Ip, reason, formatNoteTime(audit.CreatedAt)}
+}
+
+type SystemStatsDTO struct {
+	Users             int64
+	Admins            int64
+	DisabledUsers     int64
+	Notes             int64
+	TrashedNotes      int64
+	Attachments       int64
+	AttachmentsSize   string
+	PendingDeliveries int64
+	Sessions          int64
+	Goroutines        int
+	Memory            string
+	Uptime            string
+}
+
+func MapSystemStats(stats *repository.GetSystemStatsRow, now time.Time) *SystemStatsDTO {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+	return &SystemStatsDTO{
+		Users:             stats.UsersCount,
+		Admins:            stats.AdminsCount,
+		DisabledUsers:     stats.DisabledUsersCount,
+		Notes:             stats.NotesCount,
+		TrashedNotes:      stats.TrashedNotesCount,
+		Attachments:       stats.AttachmentsCount,
+		AttachmentsSize:   FormatSize(stats.AttachmentsSize),
+		PendingDeliveries: stats.PendingDeliveriesCount,
+		Sessions:          stats.SessionsCount,
+		Goroutines:        runtime.NumGoroutine(),
+		Memory:            FormatSize(int64(memory.Alloc)),
+		Uptime:            FormatDuration(now.Sub(startedAt)),
+	}
+}
+
+func (a App) ShowAdminPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := a.db.GetUsersWithNoteCounts(a.ctx)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stats, err := a.db.GetSystemStats(a.ctx)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	audit, err := a.db.GetRecentLoginAudit(a.ctx, loginAuditLimit)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	failures := make([]*LoginAuditDTO, len(audit))
+	for i, item := range audit {
+		failures[i] = MapLoginAudit(item)
+	}
+	users := make([]*AdminUserDTO, len(rows))
+	for i, row := range rows {
+		users[i] = MapAdminUser(row)
+	}
+
+	tmpl := ParseTemplateFiles(rw, "admin.html")
+	type AdminPageData struct {
+		Message string
+		UserID  int64
+		Users   []*AdminUserDTO
+		Stats   *SystemStatsDTO
+		Audit   []*LoginAuditDTO
+	}
+	data := AdminPageData{p.ByName("message"), userID, users, MapSystemStats(stats, time.Now()), failures}
+
+	err = tmpl.ExecuteTemplate(rw, "admin", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) AdminUserAction(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	adminID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+	action := p.ByName("action")
+	if userID == adminID && action != AdminEnable {
+		p = append(p, httprouter.Param{Key: "message", Value: "Нельзя применить это действие к своей учётной записи!"})
+		a.ShowAdminPage(rw, r, p)
+		return
+	}
+
+	var updated int64
+	switch action {
+	case AdminDisable, AdminEnable:
+		updated, err = a.db.SetUserDisabled(a.ctx, repository.SetUserDisabledParams{
+			Disabled: action == AdminDisable,
+			ID:       userID,
+		})
+	case AdminResetPassword:
+		updated, err = a.db.RequireUserPasswordReset(a.ctx, userID)
+	case AdminRevokeSessions:
+		updated = 1
+	case AdminResetTwoFactor:
+		updated, err = a.resetTwoFactor(userID)
+	default:
+		http.Error(rw, "неизвестное действие", http.StatusBadRequest)
+		return
+	}
+	if err != nil || updated == 0 {
+		p = append(p, httprouter.Param{Key: "message", Value: "Пользователь не найден!"})
+		a.ShowAdminPage(rw, r, p)
+		return
+	}
+	// a disabled account or one with a password to reset has to log in again
+	if action != AdminEnable && action != AdminResetTwoFactor {
+		if _, err = a.db.DeleteUserSessions(a.ctx, userID); err != nil {
+			p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при завершении сессий!"})
+			a.ShowAdminPage(rw, r, p)
+			return
+		}
+	}
+	http.Redirect(rw, r, "/admin", http.StatusSeeOther)
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/events"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/pkg/errors"
+)
+
+type APIError struct {
+	Error string `json:"error"`
+}
+
+// APIConflict is returned with 409 and 412 responses along with the saved note.
+type APIConflict struct {
+	Error string   `json:"error"`
+	Note  *NoteDTO `json:"note"`
+}
+
+// APINoteUpdate is the body of PUT /api/notes/:id. The version the client loaded
+// is sent either in the If-Match header or in the version field; an empty priority,
+// notebooks, pinning and recurrence are kept as is.
+type APINoteUpdate struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Deadline    string       `json:"deadline"`
+	IsCompleted bool         `json:"isCompleted"`
+	Priority    NotePriority `json:"priority"`
+	Version     *int32       `json:"version"`
+}
+
+func WriteJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(v)
+}
+
+func WriteJSONError(rw http.ResponseWriter, status int, message string) {
+	WriteJSON(rw, status, APIError{message})
+}
+
+func (a App) APIGetNotes(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("shared") == "true" {
+		shared, err := a.sharedNotes(userID)
+		if err != nil {
+			WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err = a.fillTags(shared); err != nil {
+			WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+			return
+		}
+		WriteJSON(rw, http.StatusOK, shared)
+		return
+	}
+
+	notebook, err := a.userNotebook(userID, query.Get("notebook"))
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, "параметр 'notebook' невалидный")
+		return
+	}
+	priority := NotePriority(query.Get("priority"))
+	if _, ok := ParsePriority(string(priority)); priority != "" && !ok {
+		WriteJSONError(rw, http.StatusBadRequest, "параметр 'priority' невалидный")
+		return
+	}
+
+	notes, err := a.listNotes(userID, query.Get("search"), notebook, query.Get("sort"), priority)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	dtos := make([]*NoteDTO, len(notes))
+	for i := range notes {
+		dtos[i] = MapNote(notes[i])
+	}
+	if err = a.fillTags(dtos); err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	WriteJSON(rw, http.StatusOK, dtos)
+}
+
+func (a App) APIGetNote(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	noteID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, "параметр 'id' невалидный")
+		return
+	}
+
+	note, access, err := a.authorizeNote(userID, noteID, AccessView)
+	if err != nil {
+		WriteJSONError(rw, http.StatusNotFound, "заметка не найдена")
+		return
+	}
+
+	dto := MapNote(note)
+	if err = a.fillTags([]*NoteDTO{dto}); err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if access != AccessOwner {
+		dto.Permission = PermissionView
+		if access == AccessEdit {
+			dto.Permission = PermissionEdit
+		}
+	}
+	rw.Header().Set("ETag", NoteETag(note.Version))
+	WriteJSON(rw, http.StatusOK, dto)
+}
+
+func (a App) APIUpdateNote(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	noteID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, "параметр 'id' невалидный")
+		return
+	}
+
+	var body APINoteUpdate
+	if err = json.NewDecoder(http.MaxBytesReader(rw, r.Body, 1<<20)).Decode(&body); err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, "некорректное тело запроса")
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	body.Description = strings.TrimSpace(body.Description)
+	body.Deadline = strings.TrimSpace(body.Deadline)
+	if message := ValidateNote(body.Name, body.Description, body.Deadline != "", body.Deadline); message != "" {
+		WriteJSONError(rw, http.StatusBadRequest, message)
+		return
+	}
+	priority, ok := ParsePriority(string(body.Priority))
+	if body.Priority != "" && !ok {
+		WriteJSONError(rw, http.StatusBadRequest, "параметр 'priority' невалидный")
+		return
+	}
+
+	note, access, err := a.authorizeNote(userID, noteID, AccessEdit)
+	if errors.Is(err, errNoteAccessDenied) && access >= AccessView {
+		WriteJSONError(rw, http.StatusForbidden, "недостаточно прав для изменения заметки")
+		return
+	}
+	if err != nil {
+		WriteJSONError(rw, http.StatusNotFound, "заметка не найдена")
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	var version int32
+	switch {
+	case ifMatch != "":
+		if !ETagMatches(ifMatch, NoteETag(note.Version)) {
+			a.writeNoteConflict(rw, http.StatusPreconditionFailed, noteID)
+			return
+		}
+		version = note.Version
+	case body.Version != nil:
+		version = *body.Version
+	default:
+		WriteJSONError(rw, http.StatusPreconditionRequired, "укажите версию заметки в заголовке If-Match или в поле version")
+		return
+	}
+
+	if body.Priority == "" {
+		priority = note.Priority
+	}
+	params := repository.UpdateNoteParams{
+		Name:        body.Name,
+		Description: &body.Description,
+		IsCompleted: body.IsCompleted,
+		Recurrence:  note.Recurrence,
+		NotebookID:  note.NotebookID,
+		Priority:    priority,
+		Pinned:      note.Pinned,
+		ID:          noteID,
+		Version:     version,
+	}
+	if body.Deadline != "" {
+		deadline, _ := time.Parse(layoutISO, body.Deadline)
+		params.DeadlineAt = pgtype.Date{Time: deadline, Valid: true}
+	}
+
+	version, err = a.saveNote(note, params)
+	if errors.Is(err, errNoteVersionConflict) {
+		status := http.StatusConflict
+		if ifMatch != "" {
+			status = http.StatusPreconditionFailed
+		}
+		a.writeNoteConflict(rw, status, noteID)
+		return
+	}
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	a.publishNoteByID(events.NoteUpdated, noteID, userID)
+
+	updated, err := a.db.GetNoteById(a.ctx, noteID)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	dto := MapNote(updated)
+	if err = a.fillTags([]*NoteDTO{dto}); err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rw.Header().Set("ETag", NoteETag(version))
+	WriteJSON(rw, http.StatusOK, dto)
+}
+
+// writeNoteConflict answers with the note as it is saved now, so that the client
+// can merge its changes and retry with the returned version.
+func (a App) writeNoteConflict(rw http.ResponseWriter, status int, noteID int64) {
+	note, err := a.db.GetNoteById(a.ctx, noteID)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	dto := MapNote(note)
+	if err = a.fillTags([]*NoteDTO{dto}); err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rw.Header().Set("ETag", NoteETag(note.Version))
+	WriteJSON(rw, status, APIConflict{"заметка была изменена после загрузки", dto})
+}
@@ -10,19 +10,36 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/blobstore"
+	"github.com/notjoji/web-notes/internal/events"
+	"github.com/notjoji/web-notes/internal/importer"
+	"github.com/notjoji/web-notes/internal/notetemplate"
+	"github.com/notjoji/web-notes/internal/recurrence"
 	"github.com/notjoji/web-notes/internal/repository"
 	"github.com/notjoji/web-notes/internal/utils"
+	"github.com/notjoji/web-notes/internal/webhook"
+	"github.com/pkg/errors"
 )
 
 var Token = "token"
 
 type App struct {
-	ctx   context.Context
-	db    *repository.Queries
-	cache map[string]*repository.User
+	ctx              context.Context
+	pool             *pgxpool.Pool
+	db               *repository.Queries
+	blobs            blobstore.BlobStore
+	attachmentLimits AttachmentLimits
+	importPreviews   *importPreviews
+	bus              *events.Bus
+	publisher        events.Publisher
+	webhooks         *webhook.Sender
+	collab           *collabHub
 }
 
 type PageData struct {
@@ -53,29 +70,53 @@ const (
 )
 
 type NoteDTO struct {
-	ID             int64          `json:"id"`
-	UserID         int64          `json:"userId"`
-	Name           string         `json:"name"`
-	Description    string         `json:"description"`
-	CreatedAt      string         `json:"createdAt"`
-	Type           NoteType       `json:"type"`
-	TypeClass      NoteTypeClass  `json:"typeClass"`
-	StatusChangeTo StatusChangeTo `json:"statusChangeTo"`
+	ID             int64           `json:"id"`
+	UserID         int64           `json:"userId"`
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	CreatedAt      string          `json:"createdAt"`
+	UpdatedAt      string          `json:"updatedAt"`
+	CompletedAt    string          `json:"completedAt,omitempty"`
+	Type           NoteType        `json:"type"`
+	TypeClass      NoteTypeClass   `json:"typeClass"`
+	StatusChangeTo StatusChangeTo  `json:"statusChangeTo"`
+	Recurrence     string          `json:"recurrence"`
+	Priority       NotePriority    `json:"priority"`
+	Pinned         bool            `json:"pinned"`
+	Owner          string          `json:"owner,omitempty"`
+	Permission     SharePermission `json:"permission,omitempty"`
+	Deadline       string          `json:"deadline,omitempty"`
+	Tags           []string        `json:"tags,omitempty"`
+	Version        int32           `json:"version"`
+	Body           template.HTML   `json:"-"`
 }
 
 type NoteUpdateDTO struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	HasDeadline bool   `json:"hasDeadline"`
-	Deadline    string `json:"deadline"`
-	IsCompleted bool   `json:"isCompleted"`
+	ID          int64          `json:"id"`
+	Version     int32          `json:"version"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	HasDeadline bool           `json:"hasDeadline"`
+	Deadline    string         `json:"deadline"`
+	IsCompleted bool           `json:"isCompleted"`
+	Recurrence  RecurrenceForm `json:"recurrence"`
+	NotebookID  int64          `json:"notebookId"`
+	Priority    NotePriority   `json:"priority"`
+	Pinned      bool           `json:"pinned"`
+	CreatedAt   string         `json:"createdAt"`
+	UpdatedAt   string         `json:"updatedAt"`
+	CompletedAt string         `json:"completedAt,omitempty"`
 }
 
 type NoteCreateDTO struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Deadline    string `json:"deadline"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Deadline    string         `json:"deadline"`
+	Recurrence  RecurrenceForm `json:"recurrence"`
+	NotebookID  int64          `json:"notebookId"`
+	Priority    NotePriority   `json:"priority"`
+	Pinned      bool           `json:"pinned"`
+	Tags        string         `json:"tags"`
 }
 
 func MapNoteUpdate(note *repository.Note) *NoteUpdateDTO {
@@ -83,13 +124,25 @@ func MapNoteUpdate(note *repository.Note) *NoteUpdateDTO {
 	if note.DeadlineAt.Valid {
 		deadline = note.DeadlineAt.Time.Format(layoutISO)
 	}
+	var notebookID int64
+	if note.NotebookID != nil {
+		notebookID = *note.NotebookID
+	}
 	return &NoteUpdateDTO{
 		ID:          note.ID,
+		Version:     note.Version,
 		Name:        note.Name,
 		Description: *note.Description,
 		HasDeadline: note.DeadlineAt.Valid,
 		Deadline:    deadline,
 		IsCompleted: note.IsCompleted,
+		Recurrence:  MapRecurrenceForm(parseNoteRecurrence(note)),
+		NotebookID:  notebookID,
+		Priority:    MapPriority(note.Priority),
+		Pinned:      note.Pinned,
+		CreatedAt:   formatNoteTime(note.CreatedAt),
+		UpdatedAt:   formatNoteTime(note.UpdatedAt),
+		CompletedAt: formatNoteTime(note.CompletedAt),
 	}
 }
 
@@ -97,6 +150,15 @@ const (
 	layoutISO = "2006-01-02"
 )
 
+// formatNoteTime formats a note timestamp; it is empty when the time is not set,
+// e.g. completed_at of an active note.
+func formatNoteTime(t pgtype.Timestamptz) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format(layoutDateTime)
+}
+
 func MapNote(note *repository.Note) *NoteDTO {
 	var noteType NoteType
 	var noteTypeClass NoteTypeClass
@@ -118,18 +180,59 @@ func MapNote(note *repository.Note) *NoteDTO {
 			statusChangeTo = ToCompleted
 		}
 	}
+	recurrenceDesc := ""
+	if rule := parseNoteRecurrence(note); rule != nil {
+		recurrenceDesc = rule.Describe()
+	}
+	deadline := ""
+	if note.DeadlineAt.Valid {
+		deadline = note.DeadlineAt.Time.Format(layoutISO)
+	}
 	return &NoteDTO{
 		ID:             note.ID,
 		UserID:         note.UserID,
 		Name:           note.Name,
 		Description:    *note.Description,
-		CreatedAt:      note.CreatedAt.Time.Format(layoutISO),
+		CreatedAt:      formatNoteTime(note.CreatedAt),
+		UpdatedAt:      formatNoteTime(note.UpdatedAt),
+		CompletedAt:    formatNoteTime(note.CompletedAt),
 		Type:           noteType,
 		TypeClass:      noteTypeClass,
 		StatusChangeTo: statusChangeTo,
+		Recurrence:     recurrenceDesc,
+		Priority:       MapPriority(note.Priority),
+		Pinned:         note.Pinned,
+		Version:        note.Version,
+		Deadline:       deadline,
 	}
 }
 
+const (
+	maxNoteNameLength        = 50
+	maxNoteDescriptionLength = importer.MaxDescriptionLength
+)
+
+func ValidateNote(name, description string, hasDeadline bool, deadline string) string {
+	if name == "" || description == "" {
+		return "Название и описание заметки не должны быть пустыми!"
+	}
+	if utf8.RuneCountInString(name) > maxNoteNameLength {
+		return fmt.Sprintf("Название заметки не должно быть длиннее %d символов!", maxNoteNameLength)
+	}
+	if utf8.RuneCountInString(description) > maxNoteDescriptionLength {
+		return fmt.Sprintf("Описание заметки не должно быть длиннее %d символов!", maxNoteDescriptionLength)
+	}
+	if hasDeadline && deadline == "" {
+		return "Укажите дату дедлайна!"
+	}
+	if hasDeadline {
+		if _, err := time.Parse(layoutISO, deadline); err != nil {
+			return "Некорректная дата дедлайна!"
+		}
+	}
+	return ""
+}
+
 func (a App) Routes(r *httprouter.Router) {
 	r.ServeFiles("/public/*filepath", http.Dir("public"))
 	r.GET("/", a.AuthNeeded(a.ShowMainPage))
@@ -138,6 +241,12 @@ func (a App) Routes(r *httprouter.Router) {
 		a.ShowLoginPage(rw, "")
 	})
 	r.POST("/login", a.Login)
+	r.GET("/login/2fa", func(rw http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		a.ShowSecondFactorPage(rw, "")
+	})
+	r.POST("/login/2fa", a.LoginSecondFactor)
+	r.POST("/api/passkeys/login", a.APIBeginPasskeyLogin)
+	r.POST("/api/passkeys/login/finish", a.APIFinishPasskeyLogin)
 	r.GET("/logout", a.Logout)
 	r.GET("/register", func(rw http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 		a.ShowRegisterPage(rw, "")
@@ -146,9 +255,82 @@ func (a App) Routes(r *httprouter.Router) {
 	r.GET("/notes", a.AuthNeeded(a.ShowCreateNotePage))
 	r.POST("/notes", a.AuthNeeded(a.CreateNewNote))
 	r.GET("/notes/:page", a.AuthNeeded(a.ShowUpdateNotePage))
+	r.GET("/notes/:page/share", a.AuthNeeded(a.ShowNoteSharesPage))
+	r.POST("/notes/:page/share", a.AuthNeeded(a.ShareNote))
+	r.POST("/notes/:page/publicLinks", a.AuthNeeded(a.CreatePublicLink))
+	r.POST("/notes/:page/attachments", a.AuthNeeded(a.UploadAttachment))
+	r.POST("/notes/:page/comments", a.AuthNeeded(a.CreateComment))
+	r.GET("/notes/:page/live", a.AuthNeeded(a.EditNoteLive))
+	r.POST("/comments/:id", a.AuthNeeded(a.UpdateComment))
+	r.POST("/comments/:id/delete", a.AuthNeeded(a.DeleteComment))
+	r.GET("/notifications", a.AuthNeeded(a.ShowNotificationsPage))
+	r.POST("/notifications/read", a.AuthNeeded(a.MarkNotificationsRead))
+	r.GET("/events", a.AuthNeeded(a.Events))
+	r.GET("/stats", a.AuthNeeded(a.ShowStatsPage))
+	r.GET("/api/stats", a.AuthNeeded(a.APIGetStats))
+	r.GET("/password", a.AuthNeeded(a.ShowPasswordPage))
+	r.POST("/password", a.AuthNeeded(a.ChangePassword))
+	r.GET("/settings/2fa", a.AuthNeeded(a.ShowTwoFactorPage))
+	r.POST("/settings/2fa/enroll", a.AuthNeeded(a.EnrollTwoFactor))
+	r.POST("/settings/2fa/confirm", a.AuthNeeded(a.ConfirmTwoFactor))
+	r.POST("/settings/2fa/disable", a.AuthNeeded(a.DisableTwoFactor))
+	r.POST("/settings/2fa/recovery", a.AuthNeeded(a.RegenerateRecoveryCodes))
+	r.GET("/settings/passkeys", a.AuthNeeded(a.ShowPasskeysPage))
+	r.POST("/settings/passkeys/:id/delete", a.AuthNeeded(a.DeletePasskey))
+	r.POST("/api/passkeys/register", a.AuthNeeded(a.APIBeginPasskeyRegistration))
+	r.POST("/api/passkeys/register/finish", a.AuthNeeded(a.APIFinishPasskeyRegistration))
+	r.GET("/admin", a.AdminNeeded(a.ShowAdminPage))
+	r.POST("/admin/users/:id/:action", a.AdminNeeded(a.AdminUserAction))
+	r.GET("/webhooks", a.AuthNeeded(a.ShowWebhooksPage))
+	r.POST("/webhooks", a.AuthNeeded(a.CreateWebhook))
+	r.POST("/webhooks/:id/toggle", a.AuthNeeded(a.ToggleWebhook))
+	r.POST("/webhooks/:id/test", a.AuthNeeded(a.TestWebhook))
+	r.POST("/webhooks/:id/delete", a.AuthNeeded(a.DeleteWebhook))
+	r.POST("/webhookDeliveries/:id/retry", a.AuthNeeded(a.RetryWebhookDelivery))
+	r.GET("/attachments/:id", a.AuthNeeded(a.DownloadAttachment))
+	r.GET("/attachments/:id/thumbnail", a.AuthNeeded(a.ShowAttachmentThumbnail))
+	r.POST("/attachments/:id/delete", a.AuthNeeded(a.DeleteAttachment))
+	r.GET("/publicLinks", a.AuthNeeded(a.ShowPublicLinksPage))
+	r.POST("/publicLinks/:id/revoke", a.AuthNeeded(a.RevokePublicLink))
+	r.GET("/s/:token", a.ShowPublicNote)
+	r.POST("/s/:token", a.ShowPublicNote)
 	r.POST("/update", a.AuthNeeded(a.UpdateNote))
 	r.POST("/delete/:id", a.AuthNeeded(a.DeleteNote))
 	r.POST("/changeStatus", a.AuthNeeded(a.ChangeStatusNote))
+	r.POST("/series/stop", a.AuthNeeded(a.StopSeries))
+	r.GET("/settings/digest", a.AuthNeeded(a.ShowDigestSettingsPage))
+	r.POST("/settings/digest", a.AuthNeeded(a.SaveDigestSettings))
+	r.GET("/notebooks", a.AuthNeeded(a.ShowNotebooksPage))
+	r.POST("/notebooks", a.AuthNeeded(a.CreateNotebook))
+	r.GET("/brokenLinks", a.AuthNeeded(a.ShowBrokenLinksPage))
+	r.GET("/templates", a.AuthNeeded(a.ShowTemplatesPage))
+	r.POST("/templates", a.AuthNeeded(a.CreateTemplate))
+	r.POST("/templates/:id", a.AuthNeeded(a.UpdateTemplate))
+	r.POST("/templates/:id/delete", a.AuthNeeded(a.DeleteTemplate))
+	r.POST("/notebooks/:id/rename", a.AuthNeeded(a.RenameNotebook))
+	r.POST("/notebooks/:id/move", a.AuthNeeded(a.MoveNotebook))
+	r.POST("/notebooks/:id/delete", a.AuthNeeded(a.DeleteNotebook))
+	r.GET("/notebooks/:id/share", a.AuthNeeded(a.ShowNotebookSharesPage))
+	r.POST("/notebooks/:id/share", a.AuthNeeded(a.ShareNotebook))
+	r.POST("/shares/:id/delete", a.AuthNeeded(a.DeleteShare))
+	r.GET("/trash", a.AuthNeeded(a.ShowTrashPage))
+	r.POST("/trash/:id", a.AuthNeeded(a.TrashNote))
+	r.POST("/trash/:id/restore", a.AuthNeeded(a.RestoreNote))
+	r.POST("/pin/:id", a.AuthNeeded(a.TogglePinNote))
+	r.GET("/api/notes", a.AuthNeeded(a.APIGetNotes))
+	r.GET("/api/notes/:id", a.AuthNeeded(a.APIGetNote))
+	r.PUT("/api/notes/:id", a.AuthNeeded(a.APIUpdateNote))
+	r.POST("/api/notes/bulk", a.AuthNeeded(a.APIBulkNotes))
+	r.POST("/api/notes/bulk/undo", a.AuthNeeded(a.APIUndoBulkNotes))
+	r.POST("/bulk", a.AuthNeeded(a.BulkNotes))
+	r.POST("/bulk/undo", a.AuthNeeded(a.UndoBulkNotes))
+	r.GET("/exports", a.AuthNeeded(a.ShowExportsPage))
+	r.POST("/exports", a.AuthNeeded(a.CreateExport))
+	r.GET("/exports/:id", a.AuthNeeded(a.DownloadExport))
+	r.GET("/import", a.AuthNeeded(a.ShowImportPage))
+	r.POST("/import", a.AuthNeeded(a.ImportNotes))
+	r.POST("/import/confirm", a.AuthNeeded(a.ConfirmImport))
+	r.POST("/api/import", a.AuthNeeded(a.APIImportNotes))
 }
 
 func ParseTemplateFiles(rw http.ResponseWriter, html string) *template.Template {
@@ -180,22 +362,82 @@ func (a App) Login(rw http.ResponseWriter, r *http.Request, _ httprouter.Params)
 		a.ShowLoginPage(rw, "Необходимо указать логин и пароль!")
 		return
 	}
+	ip := ClientIP(r)
+	wait, err := a.reserveLogin(login, ip)
+	if err != nil {
+		a.ShowLoginPage(rw, fmt.Sprintf("Ошибка авторизации: %v", err))
+		return
+	}
+	if wait > 0 {
+		_ = a.auditLogin(login, ip, AuditLocked)
+		a.ShowLoginPage(rw, LockoutMessage(wait))
+		return
+	}
 	user, err := a.db.GetUserByLoginAndPassword(a.ctx, repository.GetUserByLoginAndPasswordParams{
 		Login:    login,
 		Password: utils.GetHashedString(password),
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// the failure is already counted by the reservation
+		if err = a.auditLogin(login, ip, AuditWrongPassword); err != nil {
+			a.ShowLoginPage(rw, fmt.Sprintf("Ошибка авторизации: %v", err))
+			return
+		}
+		a.ShowLoginPage(rw, wrongLoginMessage)
+		return
+	}
+	if err != nil {
+		a.ShowLoginPage(rw, fmt.Sprintf("Ошибка авторизации: %v", err))
+		return
+	}
+	if err = a.releaseLogin(login, ip); err != nil {
+		a.ShowLoginPage(rw, fmt.Sprintf("Ошибка авторизации: %v", err))
+		return
+	}
+	if user.DisabledAt.Valid {
+		_ = a.auditLogin(login, ip, AuditDisabled)
+		a.ShowLoginPage(rw, "Учётная запись заблокирована администратором!")
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		a.askSecondFactor(rw, r, user)
+		return
+	}
+	a.startSession(rw, r, user)
+}
+
+// startSession logs the user in once all the required factors are checked.
+func (a App) startSession(rw http.ResponseWriter, r *http.Request, user *repository.User) {
+	target, err := a.openSession(rw, r, user)
 	if err != nil {
-		a.ShowLoginPage(rw, "Вы ввели неверный логин или пароль!")
+		a.ShowLoginPage(rw, fmt.Sprintf("Ошибка авторизации: %v", err))
 		return
 	}
+	http.Redirect(rw, r, target, http.StatusSeeOther)
+}
 
-	token := utils.GetAuthToken(user)
-	a.cache[token] = user
-	ttl := 60 * time.Minute
-	expiration := time.Now().Add(ttl)
-	cookie := http.Cookie{Name: Token, Value: url.QueryEscape(token), Expires: expiration}
+// openSession sets the auth cookie and returns the page to go to.
+func (a App) openSession(rw http.ResponseWriter, r *http.Request, user *repository.User) (string, error) {
+	err := a.db.ClearLoginAttempts(a.ctx, LoginKeys(user.Login, ClientIP(r))[0])
+	if err != nil {
+		return "", err
+	}
+	if err = a.db.SetUserLastLogin(a.ctx, user.ID); err != nil {
+		return "", err
+	}
+
+	token, err := a.createSession(user.ID)
+	if err != nil {
+		return "", err
+	}
+	cookie := http.Cookie{
+		Name: Token, Value: url.QueryEscape(token), Expires: time.Now().Add(sessionTTL), SameSite: http.SameSiteLaxMode,
+	}
 	http.SetCookie(rw, &cookie)
-	http.Redirect(rw, r, "/", http.StatusSeeOther)
+	if user.PasswordResetRequired {
+		return passwordPath, nil
+	}
+	return "/", nil
 }
 
 func (a App) Logout(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -203,6 +445,9 @@ func (a App) Logout(rw http.ResponseWriter, r *http.Request, _ httprouter.Params
 	if err != nil {
 		return
 	}
+	if token, err := url.QueryUnescape(cookie.Value); err == nil {
+		_ = a.deleteSession(token)
+	}
 	cookie.MaxAge = -1
 	http.SetCookie(rw, cookie)
 	http.Redirect(rw, r, "/login", http.StatusSeeOther)
@@ -231,7 +476,7 @@ func (a App) FilterNotes(rw http.ResponseWriter, r *http.Request, p httprouter.P
 	a.ShowMainPage(rw, r, p)
 }
 
-func (a App) ShowMainPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+func (a App) ShowMainPage(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	var userID int64
 	var err error
 
@@ -246,33 +491,80 @@ func (a App) ShowMainPage(rw http.ResponseWriter, _ *http.Request, p httprouter.
 		return
 	}
 
-	var notes []*repository.Note
+	notebook, err := a.userNotebook(userID, r.URL.Query().Get("notebook"))
+	if err != nil {
+		http.Error(rw, "параметр 'notebook' невалидный", http.StatusBadRequest)
+		return
+	}
+
 	search := p.ByName("search")
-	if search != "" {
-		notes, err = a.db.GetNotesByUserIdAndSearch(a.ctx, repository.GetNotesByUserIdAndSearchParams{
-			UserID:  userID,
-			Column2: &search,
-		})
-	} else {
-		notes, err = a.db.GetNotesByUserId(a.ctx, userID)
+	sortBy := r.URL.Query().Get("sort")
+	priority := NotePriority(r.URL.Query().Get("priority"))
+	notes, err := a.listNotes(userID, search, notebook, sortBy, priority)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
 	}
 
+	var activeNotebookID int64
+	if notebook != nil {
+		activeNotebookID = notebook.ID
+	}
+	notebooks, err := a.notebookTree(userID, activeNotebookID)
 	if err != nil {
 		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	var shared []*NoteDTO
+	if notebook == nil && search == "" {
+		shared, err = a.sharedNotes(userID)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	tmpl := ParseTemplateFiles(rw, "main.html")
 	type NotesPageData struct {
-		Message string
-		Notes   []*NoteDTO
+		Message    string
+		Notes      []*NoteDTO
+		Notebooks  []*NotebookDTO
+		Notebook   *repository.Notebook
+		Sort       string
+		Priority   NotePriority
+		Priorities []PriorityOption
+		Shared     []*NoteDTO
+		Folders    []*NotebookDTO
+		Actions    []BulkAction
+		CanUndo    bool
+		Unread     int64
+		IsAdmin    bool
 	}
 	dtos := make([]*NoteDTO, len(notes))
 	for i := range notes {
 		dtos[i] = MapNote(notes[i])
 	}
+	listed := append(append([]*NoteDTO{}, dtos...), shared...)
+	if err = a.fillTags(listed); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = a.renderLinks(userID, listed); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	unread, err := a.db.CountUnreadNotifications(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
 	message := p.ByName("message")
-	data := NotesPageData{message, dtos}
+	data := NotesPageData{
+		message, dtos, notebooks, notebook, sortBy, priority, PriorityOptions(), shared,
+		FlattenNotebookTree(notebooks), BulkActions(), a.canUndoBulk(userID), unread,
+		p.ByName("role") == RoleAdmin,
+	}
 
 	err = tmpl.ExecuteTemplate(rw, "main", data)
 	if err != nil {
@@ -281,6 +573,34 @@ func (a App) ShowMainPage(rw http.ResponseWriter, _ *http.Request, p httprouter.
 	}
 }
 
+func (a App) listNotes(
+	userID int64, search string, notebook *repository.Notebook, sortBy string, priority NotePriority,
+) ([]*repository.Note, error) {
+	var notes []*repository.Note
+	var err error
+	switch {
+	case search != "":
+		notes, err = a.db.GetNotesByUserIdAndSearch(a.ctx, repository.GetNotesByUserIdAndSearchParams{
+			UserID:  userID,
+			Column2: &search,
+		})
+	case notebook != nil:
+		notes, err = a.db.GetNotesByUserIdAndNotebook(a.ctx, repository.GetNotesByUserIdAndNotebookParams{
+			UserID:     userID,
+			NotebookID: &notebook.ID,
+		})
+	default:
+		notes, err = a.db.GetNotesByUserId(a.ctx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	notes = FilterNotesByPriority(notes, priority)
+	SortNotes(notes, sortBy)
+	return notes, nil
+}
+
 func (a App) ShowUpdateNotePage(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	var noteID int64
 	var err error
@@ -294,15 +614,78 @@ func (a App) ShowUpdateNotePage(rw http.ResponseWriter, r *http.Request, p httpr
 		}
 	}
 
-	note, _ := a.db.GetNoteById(a.ctx, noteID)
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	note, access, err := a.authorizeNote(userID, noteID, AccessView)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Заметка не найдена!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+	notebooks, err := a.notebookTree(userID, 0)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	attachments, err := a.noteAttachments(note.ID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	targets, err := a.linkTargets(userID, note.UserID, []string{*note.Description})
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	backlinks, err := a.backlinks(userID, note)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	activity, err := a.noteActivity(userID, note.ID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var conflict *NoteUpdateDTO
+	if p.ByName("conflict") != "" {
+		conflict = NoteUpdateFromForm(r)
+	}
 
 	tmpl := ParseTemplateFiles(rw, "updateNote.html")
 	message := p.ByName("message")
 	type UpdateNotePageData struct {
-		Message string
-		Note    *NoteUpdateDTO
+		Message     string
+		Note        *NoteUpdateDTO
+		Conflict    *NoteUpdateDTO
+		Notebooks   []*NotebookDTO
+		Priorities  []PriorityOption
+		ReadOnly    bool
+		IsOwner     bool
+		Attachments []*AttachmentDTO
+		MaxFileSize string
+		Body        template.HTML
+		Backlinks   []*NoteDTO
+		Activity    []*ActivityItemDTO
+	}
+	data := UpdateNotePageData{
+		Message:     message,
+		Note:        MapNoteUpdate(note),
+		Conflict:    conflict,
+		Notebooks:   FlattenNotebookTree(notebooks),
+		Priorities:  PriorityOptions(),
+		ReadOnly:    access < AccessEdit,
+		IsOwner:     access == AccessOwner,
+		Attachments: attachments,
+		MaxFileSize: FormatSize(a.attachmentLimits.MaxFileSize),
+		Body:        RenderNoteLinks(*note.Description, targets),
+		Backlinks:   backlinks,
+		Activity:    activity,
 	}
-	data := UpdateNotePageData{message, MapNoteUpdate(note)}
 
 	err = tmpl.ExecuteTemplate(rw, "updateNote", data)
 	if err != nil {
@@ -318,34 +701,71 @@ func (a App) UpdateNote(rw http.ResponseWriter, r *http.Request, p httprouter.Pa
 	hasDeadline := r.FormValue("deadlineDateCheckbox") == "on"
 	deadline := strings.TrimSpace(r.FormValue("deadlineDatePicker"))
 	isCompleted := r.FormValue("completedCheckbox") == "on"
+	applyToSeries := r.FormValue("applyToSeriesCheckbox") == "on"
 
-	if noteName == "" || noteDesc == "" {
-		p = append(p, httprouter.Param{Key: "message", Value: "Название и описание заметки не должны быть пустыми!"})
+	if message := ValidateNote(noteName, noteDesc, hasDeadline, deadline); message != "" {
+		p = append(p, httprouter.Param{Key: "message", Value: message})
 		r.URL.Path = "/notes/" + noteIDParam
 		a.ShowUpdateNotePage(rw, r, p)
 		return
 	}
 
-	if hasDeadline && deadline == "" {
-		p = append(p, httprouter.Param{Key: "message", Value: "Укажите дату дедлайна!"})
+	rule, err := recurrenceFromForm(r)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Некорректное правило повторения заметки!"})
 		r.URL.Path = "/notes/" + noteIDParam
 		a.ShowUpdateNotePage(rw, r, p)
 		return
 	}
 
 	var noteID int64
-	var err error
 	noteID, err = strconv.ParseInt(noteIDParam, 10, 64)
 	if err != nil {
 		http.Error(rw, "параметр 'noteID' невалидный", http.StatusBadRequest)
 		return
 	}
+	version, err := strconv.ParseInt(r.FormValue("noteVersion"), 10, 32)
+	if err != nil {
+		http.Error(rw, "параметр 'noteVersion' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	note, access, err := a.authorizeNote(userID, noteID, AccessEdit)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Заметка не найдена!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+	notebook, err := a.userNotebook(userID, r.FormValue("notebookID"))
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Блокнот не найден!"})
+		r.URL.Path = "/notes/" + noteIDParam
+		a.ShowUpdateNotePage(rw, r, p)
+		return
+	}
 
 	params := repository.UpdateNoteParams{
 		Name:        noteName,
 		Description: &noteDesc,
 		IsCompleted: isCompleted,
+		Recurrence:  ruleString(rule),
+		NotebookID:  notebookIDPtr(notebook),
+		Priority:    priorityFromForm(r),
+		Pinned:      r.FormValue("pinnedCheckbox") == "on",
 		ID:          noteID,
+		Version:     int32(version),
+	}
+	if access != AccessOwner {
+		// notebooks, pinning and series belong to the owner and are kept as is
+		params.Recurrence = note.Recurrence
+		params.NotebookID = note.NotebookID
+		params.Pinned = note.Pinned
+		applyToSeries = false
 	}
 
 	if hasDeadline {
@@ -357,13 +777,54 @@ func (a App) UpdateNote(rw http.ResponseWriter, r *http.Request, p httprouter.Pa
 		}
 	}
 
-	_, err = a.db.UpdateNote(a.ctx, params)
+	_, err = a.saveNote(note, params)
+	if errors.Is(err, errNoteVersionConflict) {
+		p = append(p,
+			httprouter.Param{Key: "message", Value: "Заметка была изменена, пока вы её редактировали. Сравните версии и сохраните снова."},
+			httprouter.Param{Key: "conflict", Value: "true"},
+		)
+		r.URL.Path = "/notes/" + noteIDParam
+		a.ShowUpdateNotePage(rw, r, p)
+		return
+	}
 	if err != nil {
 		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при обновлении заметки!"})
 		a.ShowCreateNotePage(rw, r, p)
 		return
 	}
 
+	if applyToSeries {
+		err = a.inTx(func(q *repository.Queries) error {
+			_, err := q.UpdateNoteSeries(a.ctx, repository.UpdateNoteSeriesParams{
+				Name:        noteName,
+				Description: &noteDesc,
+				Recurrence:  ruleString(rule),
+				SeriesID:    seriesID(note),
+			})
+			if err != nil {
+				return err
+			}
+			err = q.DeleteSeriesReferences(a.ctx, repository.DeleteSeriesReferencesParams{
+				SeriesID: seriesID(note),
+				NoteID:   noteID,
+			})
+			if err != nil {
+				return err
+			}
+			return q.CopySeriesReferences(a.ctx, repository.CopySeriesReferencesParams{
+				NoteID:   noteID,
+				SeriesID: seriesID(note),
+			})
+		})
+		if err != nil {
+			p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при обновлении серии заметок!"})
+			r.URL.Path = "/notes/" + noteIDParam
+			a.ShowUpdateNotePage(rw, r, p)
+			return
+		}
+	}
+	a.publishNoteByID(events.NoteUpdated, noteID, userID)
+
 	http.Redirect(rw, r, "/", http.StatusSeeOther)
 }
 
@@ -380,14 +841,33 @@ func (a App) DeleteNote(rw http.ResponseWriter, r *http.Request, p httprouter.Pa
 		}
 	}
 
-	_, err = a.db.DeleteNoteById(a.ctx, noteID)
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var attachments []*repository.Attachment
+	var audience []int64
+	_, _, err = a.authorizeNote(userID, noteID, AccessOwner)
+	if err == nil {
+		attachments, err = a.db.GetAttachmentsByNoteId(a.ctx, noteID)
+	}
+	if err == nil {
+		// the shares are removed together with the note
+		audience, err = a.noteAudience(&repository.Note{ID: noteID, UserID: userID})
+	}
+	if err == nil {
+		_, err = a.db.DeleteNoteById(a.ctx, noteID)
+	}
 	if err != nil {
 		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при удалении заметки!"})
-		a.ShowMainPage(rw, r, p)
+		a.ShowTrashPage(rw, r, p)
 		return
 	}
+	a.deleteAttachmentBlobs(r.Context(), attachments)
+	a.publisher.Publish(events.Event{Kind: events.NoteDeleted, NoteID: noteID, ActorID: userID, Users: audience})
 
-	http.Redirect(rw, r, "/", http.StatusSeeOther)
+	http.Redirect(rw, r, "/trash", http.StatusSeeOther)
 }
 
 func (a App) ChangeStatusNote(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
@@ -407,33 +887,125 @@ func (a App) ChangeStatusNote(rw http.ResponseWriter, r *http.Request, p httprou
 		isCompleted = true
 	}
 
-	_, err = a.db.ChangeNoteStatus(a.ctx, repository.ChangeNoteStatusParams{
-		IsCompleted: isCompleted,
-		ID:          noteID,
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, _, err = a.authorizeNote(userID, noteID, AccessEdit)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Заметка не найдена!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	var createdID int64
+	err = a.inTx(func(q *repository.Queries) error {
+		_, err := q.ChangeNoteStatus(a.ctx, repository.ChangeNoteStatusParams{
+			IsCompleted: isCompleted,
+			ID:          noteID,
+		})
+		if err != nil {
+			return err
+		}
+		if err = a.recordStatusChange(q, userID, noteID, isCompleted); err != nil {
+			return err
+		}
+		if !isCompleted {
+			return nil
+		}
+		note, err := q.GetNoteById(a.ctx, noteID)
+		if err != nil {
+			return err
+		}
+		createdID, err = a.createNextOccurrence(q, note)
+		return err
 	})
 	if err != nil {
 		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при изменении статуса заметки!"})
 		a.ShowMainPage(rw, r, p)
 		return
 	}
+	a.publishNoteByID(events.NoteStatusChanged, noteID, userID)
+	if isCompleted {
+		a.enqueueWebhooks(a.ctx, webhook.EventNoteCompleted, noteID)
+	}
+	if createdID != 0 {
+		a.publishNoteByID(events.NoteCreated, createdID, userID)
+		a.enqueueWebhooks(a.ctx, webhook.EventNoteCreated, createdID)
+	}
 
 	http.Redirect(rw, r, "/", http.StatusSeeOther)
 }
 
-func (a App) ShowCreateNotePage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+func (a App) ShowCreateNotePage(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	notebooks, err := a.notebookTree(userID, 0)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	templates, err := a.noteTemplates(userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	tmpl := ParseTemplateFiles(rw, "createNote.html")
 
 	message := p.ByName("message")
-	noteName := p.ByName("noteName")
-	noteDesc := p.ByName("noteDesc")
-	deadline := p.ByName("deadline")
+	var recurrenceForm RecurrenceForm
+	if rule, err := recurrence.Parse(p.ByName("recurrence")); err == nil {
+		recurrenceForm = MapRecurrenceForm(rule)
+	}
+	notebookID, _ := strconv.ParseInt(p.ByName("notebookID"), 10, 64)
+	priority := NotePriority(p.ByName("priority"))
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	note := &NoteCreateDTO{
+		Name:        p.ByName("noteName"),
+		Description: p.ByName("noteDesc"),
+		Deadline:    p.ByName("deadline"),
+		Recurrence:  recurrenceForm,
+		NotebookID:  notebookID,
+		Priority:    priority,
+		Tags:        p.ByName("noteTags"),
+	}
+
+	templateID, _ := strconv.ParseInt(r.URL.Query().Get("template"), 10, 64)
+	if templateID != 0 && message == "" {
+		t, err := a.db.GetNoteTemplateById(a.ctx, repository.GetNoteTemplateByIdParams{ID: templateID, UserID: userID})
+		if err != nil {
+			message = "Шаблон не найден!"
+			templateID = 0
+		} else {
+			note = NoteFromTemplate(t, time.Now())
+		}
+	}
+
 	type CreateNotePageData struct {
-		Message string
-		Note    *NoteCreateDTO
+		Message    string
+		Note       *NoteCreateDTO
+		Notebooks  []*NotebookDTO
+		Priorities []PriorityOption
+		Templates  []*NoteTemplateDTO
+		TemplateID int64
+	}
+	data := CreateNotePageData{
+		Message:    message,
+		Note:       note,
+		Notebooks:  FlattenNotebookTree(notebooks),
+		Priorities: PriorityOptions(),
+		Templates:  templates,
+		TemplateID: templateID,
 	}
-	data := CreateNotePageData{Message: message, Note: &NoteCreateDTO{noteName, noteDesc, deadline}}
 
-	err := tmpl.ExecuteTemplate(rw, "createNote", data)
+	err = tmpl.ExecuteTemplate(rw, "createNote", data)
 	if err != nil {
 		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
@@ -448,13 +1020,28 @@ func (a App) AuthNeeded(next httprouter.Handle) httprouter.Handle {
 			return
 		}
 
-		user, ok := a.cache[token]
-		if !ok {
+		// the account is read on every request, so that changes made by an
+		// administrator on any instance apply at once
+		user, err := a.sessionUser(token)
+		if errors.Is(err, pgx.ErrNoRows) {
 			http.Redirect(rw, r, "/login", http.StatusUnauthorized)
 			return
 		}
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if user.DisabledAt.Valid {
+			http.Redirect(rw, r, "/login", http.StatusUnauthorized)
+			return
+		}
+		if user.PasswordResetRequired && r.URL.Path != passwordPath {
+			http.Redirect(rw, r, passwordPath, http.StatusSeeOther)
+			return
+		}
 
 		ps = append(ps, httprouter.Param{Key: "userID", Value: strconv.FormatInt(user.ID, 10)})
+		ps = append(ps, httprouter.Param{Key: "role", Value: user.Role})
 
 		next(rw, r, ps)
 	}
@@ -488,30 +1075,40 @@ func (a App) Register(rw http.ResponseWriter, r *http.Request, _ httprouter.Para
 }
 
 func (a App) CreateNewNote(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	noteName := strings.TrimSpace(r.FormValue("noteName"))
-	noteDesc := strings.TrimSpace(r.FormValue("noteDesc"))
+	now := time.Now()
+	noteName := strings.TrimSpace(notetemplate.Expand(r.FormValue("noteName"), now))
+	noteDesc := strings.TrimSpace(notetemplate.Expand(r.FormValue("noteDesc"), now))
 	hasDeadline := r.FormValue("deadlineDateCheckbox") == "on"
 	deadline := strings.TrimSpace(r.FormValue("deadlineDatePicker"))
+	noteTags := r.FormValue("noteTags")
+	tags := importer.SplitTags(noteTags)
 
-	if noteName == "" || noteDesc == "" {
-		p = append(p, httprouter.Param{Key: "message", Value: "Название и описание заметки не должны быть пустыми!"})
+	message := ValidateNote(noteName, noteDesc, hasDeadline, deadline)
+	if message == "" {
+		message = validateTags(tags)
+	}
+	if message != "" {
+		p = append(p, httprouter.Param{Key: "message", Value: message})
 		p = append(p, httprouter.Param{Key: "noteName", Value: noteName})
 		p = append(p, httprouter.Param{Key: "noteDesc", Value: noteDesc})
 		p = append(p, httprouter.Param{Key: "deadline", Value: deadline})
+		p = append(p, httprouter.Param{Key: "noteTags", Value: noteTags})
 		a.ShowCreateNotePage(rw, r, p)
 		return
 	}
 
-	if hasDeadline && deadline == "" {
-		p = append(p, httprouter.Param{Key: "message", Value: "Укажите дату дедлайна!"})
+	rule, err := recurrenceFromForm(r)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Некорректное правило повторения заметки!"})
 		p = append(p, httprouter.Param{Key: "noteName", Value: noteName})
 		p = append(p, httprouter.Param{Key: "noteDesc", Value: noteDesc})
+		p = append(p, httprouter.Param{Key: "deadline", Value: deadline})
+		p = append(p, httprouter.Param{Key: "noteTags", Value: noteTags})
 		a.ShowCreateNotePage(rw, r, p)
 		return
 	}
 
 	var userID int64
-	var err error
 	userIDParam := p.ByName("userID")
 	if userIDParam == "" {
 		http.Error(rw, "требуется параметр 'userID'", http.StatusBadRequest)
@@ -523,10 +1120,25 @@ func (a App) CreateNewNote(rw http.ResponseWriter, r *http.Request, p httprouter
 		return
 	}
 
+	notebook, err := a.userNotebook(userID, r.FormValue("notebookID"))
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Блокнот не найден!"})
+		p = append(p, httprouter.Param{Key: "noteName", Value: noteName})
+		p = append(p, httprouter.Param{Key: "noteDesc", Value: noteDesc})
+		p = append(p, httprouter.Param{Key: "deadline", Value: deadline})
+		p = append(p, httprouter.Param{Key: "noteTags", Value: noteTags})
+		a.ShowCreateNotePage(rw, r, p)
+		return
+	}
+
 	params := repository.CreateNoteParams{
 		UserID:      userID,
 		Name:        noteName,
 		Description: &noteDesc,
+		Recurrence:  ruleString(rule),
+		NotebookID:  notebookIDPtr(notebook),
+		Priority:    priorityFromForm(r),
+		Pinned:      r.FormValue("pinnedCheckbox") == "on",
 	}
 	if hasDeadline {
 		parsedDeadline, _ := time.Parse(layoutISO, deadline)
@@ -537,16 +1149,46 @@ func (a App) CreateNewNote(rw http.ResponseWriter, r *http.Request, p httprouter
 		}
 	}
 
-	_, err = a.db.CreateNote(a.ctx, params)
+	var noteID int64
+	err = a.inTx(func(q *repository.Queries) error {
+		var err error
+		noteID, err = q.CreateNote(a.ctx, params)
+		if err != nil {
+			return err
+		}
+		if err = a.syncReferences(q, noteID, noteDesc); err != nil {
+			return err
+		}
+		return a.addNoteTags(q, userID, noteID, tags)
+	})
 	if err != nil {
 		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при создании заметки!"})
 		a.ShowCreateNotePage(rw, r, p)
 		return
 	}
+	a.publishNoteByID(events.NoteCreated, noteID, userID)
+	a.enqueueWebhooks(a.ctx, webhook.EventNoteCreated, noteID)
 
 	http.Redirect(rw, r, "/", http.StatusSeeOther)
 }
 
-func NewApp(ctx context.Context, db *repository.Queries) *App {
-	return &App{ctx, db, make(map[string]*repository.User)}
+func (a App) inTx(fn func(q *repository.Queries) error) error {
+	tx, err := a.pool.Begin(a.ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(a.ctx) }()
+
+	if err = fn(a.db.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(a.ctx)
+}
+
+func NewApp(ctx context.Context, pool *pgxpool.Pool, blobs blobstore.BlobStore) *App {
+	bus := events.NewBus()
+	return &App{
+		ctx, pool, repository.New(pool), blobs, AttachmentLimitsFromEnv(),
+		newImportPreviews(), bus, bus, webhook.NewSender(nil), newCollabHub(),
+	}
 }
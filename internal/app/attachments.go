@@ -0,0 +1,364 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/blobstore"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/thumbnail"
+	"github.com/notjoji/web-notes/internal/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	megabyte      = 1 << 20
+	thumbnailSide = 240
+	sniffLen      = 512
+)
+
+var inlineContentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/bmp":       true,
+	"application/pdf": true,
+}
+
+var errAttachmentQuota = errors.New("attachment quota exceeded")
+
+type AttachmentLimits struct {
+	MaxFileSize int64
+	UserQuota   int64
+}
+
+// AttachmentLimitsFromEnv reads ATTACHMENT_MAX_MB and ATTACHMENT_QUOTA_MB
+// (20 MB per file and 100 MB per user by default).
+func AttachmentLimitsFromEnv() AttachmentLimits {
+	megabytes := func(name string, def int64) int64 {
+		value, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+		if err != nil || value <= 0 {
+			value = def
+		}
+		return value * megabyte
+	}
+	return AttachmentLimits{
+		MaxFileSize: megabytes("ATTACHMENT_MAX_MB", 20),
+		UserQuota:   megabytes("ATTACHMENT_QUOTA_MB", 100),
+	}
+}
+
+type AttachmentDTO struct {
+	ID           int64  `json:"id"`
+	NoteID       int64  `json:"noteId"`
+	FileName     string `json:"fileName"`
+	ContentType  string `json:"contentType"`
+	Size         int64  `json:"size"`
+	SizeLabel    string `json:"sizeLabel"`
+	HasThumbnail bool   `json:"hasThumbnail"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+func MapAttachment(attachment *repository.Attachment) *AttachmentDTO {
+	return &AttachmentDTO{
+		ID:           attachment.ID,
+		NoteID:       attachment.NoteID,
+		FileName:     attachment.FileName,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		SizeLabel:    FormatSize(attachment.Size),
+		HasThumbnail: attachment.ThumbKey != nil,
+		CreatedAt:    attachment.CreatedAt.Time.Format(layoutDateTime),
+	}
+}
+
+func FormatSize(size int64) string {
+	switch {
+	case size >= megabyte:
+		return fmt.Sprintf("%.1f МБ", float64(size)/megabyte)
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f КБ", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d Б", size)
+}
+
+// SniffContentType detects the type by content, ignoring what the client claims,
+// and rewinds the reader.
+func SniffContentType(r io.ReadSeeker) (string, error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	if err != nil {
+		return "application/octet-stream", nil
+	}
+	return contentType, nil
+}
+
+// AttachmentFileName keeps only the base name of the uploaded file and limits its length.
+func AttachmentFileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+func (a App) noteAttachments(noteID int64) ([]*AttachmentDTO, error) {
+	attachments, err := a.db.GetAttachmentsByNoteId(a.ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]*AttachmentDTO, len(attachments))
+	for i := range attachments {
+		dtos[i] = MapAttachment(attachments[i])
+	}
+	return dtos, nil
+}
+
+// deleteAttachmentBlobs is called after the rows are gone, so a failure only leaves
+// an orphaned blob behind and is logged instead of being reported to the user.
+func (a App) deleteAttachmentBlobs(ctx context.Context, attachments []*repository.Attachment) {
+	for _, attachment := range attachments {
+		keys := []string{attachment.BlobKey}
+		if attachment.ThumbKey != nil {
+			keys = append(keys, *attachment.ThumbKey)
+		}
+		for _, key := range keys {
+			if err := a.blobs.Delete(ctx, key); err != nil {
+				log.Printf("attachments: delete blob %s: %v", key, err)
+			}
+		}
+	}
+}
+
+func (a App) showNoteWithMessage(rw http.ResponseWriter, r *http.Request, p httprouter.Params, noteID int64, message string) {
+	p = append(p, httprouter.Param{Key: "message", Value: message})
+	r.URL.Path = fmt.Sprintf("/notes/%d", noteID)
+	a.ShowUpdateNotePage(rw, r, p)
+}
+
+func (a App) UploadAttachment(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	noteID, err := strconv.ParseInt(p.ByName("page"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	note, _, err := a.authorizeNote(userID, noteID, AccessEdit)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Заметка не найдена!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	limits := a.attachmentLimits
+	r.Body = http.MaxBytesReader(rw, r.Body, limits.MaxFileSize+megabyte)
+	if err = r.ParseMultipartForm(megabyte); err != nil {
+		a.showNoteWithMessage(rw, r, p, note.ID, fmt.Sprintf("Размер файла не должен превышать %s!", FormatSize(limits.MaxFileSize)))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		a.showNoteWithMessage(rw, r, p, note.ID, "Выберите файл для загрузки!")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > limits.MaxFileSize {
+		a.showNoteWithMessage(rw, r, p, note.ID, fmt.Sprintf("Размер файла не должен превышать %s!", FormatSize(limits.MaxFileSize)))
+		return
+	}
+	used, err := a.db.GetUserAttachmentsSize(a.ctx, note.UserID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	quotaMessage := fmt.Sprintf("Превышена квота на вложения (%s)!", FormatSize(limits.UserQuota))
+	if used+header.Size > limits.UserQuota {
+		a.showNoteWithMessage(rw, r, p, note.ID, quotaMessage)
+		return
+	}
+
+	contentType, err := SniffContentType(file)
+	if err != nil {
+		a.showNoteWithMessage(rw, r, p, note.ID, "Не удалось прочитать файл!")
+		return
+	}
+	token, err := utils.GenerateToken(24)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	key := fmt.Sprintf("%d/%s", note.UserID, token)
+
+	if err = a.blobs.Put(r.Context(), key, file, header.Size, contentType); err != nil {
+		log.Printf("attachments: put blob %s: %v", key, err)
+		a.showNoteWithMessage(rw, r, p, note.ID, "Возникла ошибка при сохранении файла!")
+		return
+	}
+	attachment := &repository.Attachment{BlobKey: key}
+
+	if strings.HasPrefix(contentType, "image/") {
+		if _, err = file.Seek(0, io.SeekStart); err == nil {
+			if thumb, err := thumbnail.Make(file, thumbnailSide); err == nil {
+				thumbKey := key + ".thumb"
+				err = a.blobs.Put(r.Context(), thumbKey, bytes.NewReader(thumb), int64(len(thumb)), thumbnail.ContentType)
+				if err == nil {
+					attachment.ThumbKey = &thumbKey
+				}
+			}
+		}
+	}
+
+	err = a.createAttachment(repository.CreateAttachmentParams{
+		NoteID:      note.ID,
+		UserID:      note.UserID,
+		BlobKey:     key,
+		ThumbKey:    attachment.ThumbKey,
+		FileName:    AttachmentFileName(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+	})
+	if err != nil {
+		a.deleteAttachmentBlobs(r.Context(), []*repository.Attachment{attachment})
+		message := "Возникла ошибка при сохранении вложения!"
+		if errors.Is(err, errAttachmentQuota) {
+			message = quotaMessage
+		}
+		a.showNoteWithMessage(rw, r, p, note.ID, message)
+		return
+	}
+
+	http.Redirect(rw, r, fmt.Sprintf("/notes/%d", note.ID), http.StatusSeeOther)
+}
+
+// createAttachment charges the attachment to the note owner. The owner's row stays
+// locked until the attachment is stored, so parallel uploads see each other's size.
+func (a App) createAttachment(params repository.CreateAttachmentParams) error {
+	return a.inTx(func(q *repository.Queries) error {
+		if err := q.LockUserForUpdate(a.ctx, params.UserID); err != nil {
+			return err
+		}
+		used, err := q.GetUserAttachmentsSize(a.ctx, params.UserID)
+		if err != nil {
+			return err
+		}
+		if used+params.Size > a.attachmentLimits.UserQuota {
+			return errAttachmentQuota
+		}
+		_, err = q.CreateAttachment(a.ctx, params)
+		return err
+	})
+}
+
+// authorizeAttachment loads the attachment and checks the user's access to its note.
+func (a App) authorizeAttachment(p httprouter.Params, required NoteAccess) (*repository.Attachment, error) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		return nil, err
+	}
+	attachmentID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	attachment, err := a.db.GetAttachmentById(a.ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err = a.authorizeNote(userID, attachment.NoteID, required); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// DownloadAttachment streams the blob through http.ServeContent, which handles
+// Range and conditional requests.
+func (a App) DownloadAttachment(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	attachment, err := a.authorizeAttachment(p, AccessView)
+	if err != nil {
+		http.Error(rw, "вложение не найдено", http.StatusNotFound)
+		return
+	}
+
+	disposition := "attachment"
+	if inlineContentTypes[attachment.ContentType] {
+		disposition = "inline"
+	}
+	rw.Header().Set("Content-Type", attachment.ContentType)
+	rw.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+		"filename": attachment.FileName,
+	}))
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.Header().Set("Cache-Control", "private")
+
+	content := blobstore.NewReadSeeker(r.Context(), a.blobs, attachment.BlobKey, attachment.Size)
+	defer content.Close()
+	http.ServeContent(rw, r, attachment.FileName, attachment.CreatedAt.Time, content)
+}
+
+func (a App) ShowAttachmentThumbnail(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	attachment, err := a.authorizeAttachment(p, AccessView)
+	if err != nil || attachment.ThumbKey == nil {
+		http.Error(rw, "миниатюра не найдена", http.StatusNotFound)
+		return
+	}
+
+	body, err := a.blobs.Get(r.Context(), *attachment.ThumbKey, 0)
+	if err != nil {
+		http.Error(rw, "миниатюра не найдена", http.StatusNotFound)
+		return
+	}
+	defer body.Close()
+
+	rw.Header().Set("Content-Type", thumbnail.ContentType)
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.Header().Set("Cache-Control", "private, max-age=86400")
+	_, _ = io.Copy(rw, body)
+}
+
+func (a App) DeleteAttachment(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	attachment, err := a.authorizeAttachment(p, AccessEdit)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Вложение не найдено!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	err = a.db.DeleteAttachmentById(a.ctx, attachment.ID)
+	if err != nil {
+		a.showNoteWithMessage(rw, r, p, attachment.NoteID, "Возникла ошибка при удалении вложения!")
+		return
+	}
+	a.deleteAttachmentBlobs(r.Context(), []*repository.Attachment{attachment})
+
+	http.Redirect(rw, r, fmt.Sprintf("/notes/%d", attachment.NoteID), http.StatusSeeOther)
+}
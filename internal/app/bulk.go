@@ -0,0 +1,456 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/importer"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/pkg/errors"
+)
+
+type BulkAction string
+
+const (
+	BulkComplete    BulkAction = "complete"
+	BulkReopen      BulkAction = "reopen"
+	BulkDelete      BulkAction = "delete"
+	BulkMove        BulkAction = "move"
+	BulkAddTag      BulkAction = "addTag"
+	BulkRemoveTag   BulkAction = "removeTag"
+	BulkSetDeadline BulkAction = "setDeadline"
+
+	maxBulkNotes = 1000
+)
+
+var bulkActionLabels = map[BulkAction]string{
+	BulkComplete:    "Завершить",
+	BulkReopen:      "Вернуть в работу",
+	BulkDelete:      "В корзину",
+	BulkMove:        "Переместить в блокнот",
+	BulkAddTag:      "Добавить тег",
+	BulkRemoveTag:   "Убрать тег",
+	BulkSetDeadline: "Установить дедлайн",
+}
+
+var bulkActionSummaries = map[BulkAction]string{
+	BulkComplete:    "Завершено заметок: %d",
+	BulkReopen:      "Возвращено в работу заметок: %d",
+	BulkDelete:      "Перемещено в корзину заметок: %d",
+	BulkMove:        "Перемещено в блокнот заметок: %d",
+	BulkAddTag:      "Тег добавлен к заметкам: %d",
+	BulkRemoveTag:   "Тег убран у заметок: %d",
+	BulkSetDeadline: "Дедлайн изменён у заметок: %d",
+}
+
+var bulkActionsOrdered = []BulkAction{
+	BulkComplete, BulkReopen, BulkDelete, BulkMove, BulkAddTag, BulkRemoveTag, BulkSetDeadline,
+}
+
+// bulkError is shown to the user as is; other errors are reported as a generic failure.
+type bulkError string
+
+func (e bulkError) Error() string {
+	return string(e)
+}
+
+const (
+	errBulkNoNotes     bulkError = "Выбранные заметки не найдены!"
+	errBulkNotebook    bulkError = "Блокнот не найден!"
+	errBulkTag         bulkError = "Тег не найден!"
+	errBulkTagDeleted  bulkError = "Тег удалён, действие нельзя отменить!"
+	errNothingToUndo   bulkError = "Нет действия для отмены!"
+	bulkFailureMessage           = "Возникла ошибка при выполнении действия!"
+)
+
+func bulkErrorMessage(err error) (string, bool) {
+	var e bulkError
+	if errors.As(err, &e) {
+		return string(e), true
+	}
+	return bulkFailureMessage, false
+}
+
+func (b BulkAction) Label() string {
+	return bulkActionLabels[b]
+}
+
+func BulkActions() []BulkAction {
+	return bulkActionsOrdered
+}
+
+type BulkRequest struct {
+	Action     BulkAction `json:"action"`
+	NoteIDs    []int64    `json:"noteIds"`
+	NotebookID int64      `json:"notebookId"`
+	Tag        string     `json:"tag"`
+	Deadline   string     `json:"deadline"`
+}
+
+type BulkResult struct {
+	Action   BulkAction `json:"action"`
+	Affected int64      `json:"affected"`
+	Message  string     `json:"message"`
+	Undone   bool       `json:"undone,omitempty"`
+	actionID int64
+	// notes completed or reopened by the action; after it they all look alike
+	changedIDs []int64
+}
+
+// Validate normalizes the request and returns a user message when it is invalid.
+// An empty deadline clears deadlines and notebook 0 moves notes out of notebooks.
+func (req *BulkRequest) Validate() string {
+	if _, ok := bulkActionLabels[req.Action]; !ok {
+		return "Выберите действие!"
+	}
+	if len(req.NoteIDs) == 0 {
+		return "Выберите заметки!"
+	}
+	if len(req.NoteIDs) > maxBulkNotes {
+		return fmt.Sprintf("Можно выбрать не более %d заметок!", maxBulkNotes)
+	}
+	switch req.Action {
+	case BulkAddTag, BulkRemoveTag:
+		tags := importer.NormalizeTags([]string{req.Tag})
+		if len(tags) == 0 {
+			return "Укажите тег!"
+		}
+		if utf8.RuneCountInString(tags[0]) > maxTagLength {
+			return fmt.Sprintf("Тег не должен быть длиннее %d символов!", maxTagLength)
+		}
+		req.Tag = tags[0]
+	case BulkSetDeadline:
+		req.Deadline = strings.TrimSpace(req.Deadline)
+		if req.Deadline != "" {
+			if _, err := time.Parse(layoutISO, req.Deadline); err != nil {
+				return "Некорректная дата дедлайна!"
+			}
+		}
+	}
+	return ""
+}
+
+func bulkRequestFromForm(r *http.Request) *BulkRequest {
+	req := &BulkRequest{
+		Action:   BulkAction(r.FormValue("action")),
+		Tag:      r.FormValue("tag"),
+		Deadline: r.FormValue("deadline"),
+	}
+	req.NotebookID, _ = strconv.ParseInt(r.FormValue("notebookID"), 10, 64)
+	for _, value := range r.Form["noteIDs"] {
+		if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+			req.NoteIDs = append(req.NoteIDs, id)
+		}
+	}
+	return req
+}
+
+// applyBulk changes the user's own notes in one transaction and snapshots their
+// previous state; only the latest bulk action is kept for undo.
+func (a App) applyBulk(userID int64, req *BulkRequest) (*BulkResult, error) {
+	var notebookID *int64
+	if req.Action == BulkMove && req.NotebookID != 0 {
+		notebook, err := a.userNotebook(userID, strconv.FormatInt(req.NotebookID, 10))
+		if err != nil || notebook == nil {
+			return nil, errBulkNotebook
+		}
+		notebookID = &notebook.ID
+	}
+
+	var affected, actionID int64
+	var changedIDs []int64
+	err := a.inTx(func(q *repository.Queries) error {
+		if err := q.DeleteBulkActionsByUserId(a.ctx, userID); err != nil {
+			return err
+		}
+
+		var tagID *int64
+		switch req.Action {
+		case BulkAddTag:
+			id, err := q.UpsertTag(a.ctx, repository.UpsertTagParams{UserID: userID, Name: req.Tag})
+			if err != nil {
+				return err
+			}
+			tagID = &id
+		case BulkRemoveTag:
+			tag, err := q.GetTagByName(a.ctx, repository.GetTagByNameParams{UserID: userID, Name: req.Tag})
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errBulkTag
+			}
+			if err != nil {
+				return err
+			}
+			tagID = &tag.ID
+		}
+
+		var err error
+		actionID, err = q.CreateBulkAction(a.ctx, repository.CreateBulkActionParams{
+			UserID: userID,
+			Action: string(req.Action),
+			TagID:  tagID,
+		})
+		if err != nil {
+			return err
+		}
+		affected, err = q.SnapshotBulkActionNotes(a.ctx, repository.SnapshotBulkActionNotesParams{
+			BulkActionID: actionID,
+			TagID:        tagID,
+			UserID:       userID,
+			NoteIds:      req.NoteIDs,
+		})
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return errBulkNoNotes
+		}
+
+		switch req.Action {
+		case BulkComplete, BulkReopen:
+			changedIDs, err = a.bulkSetStatus(q, userID, actionID, req.Action == BulkComplete)
+			return err
+		case BulkDelete:
+			return q.BulkTrash(a.ctx, actionID)
+		case BulkMove:
+			return q.BulkSetNotebook(a.ctx, repository.BulkSetNotebookParams{NotebookID: notebookID, BulkActionID: actionID})
+		case BulkAddTag:
+			return q.BulkAddTag(a.ctx, repository.BulkAddTagParams{TagID: *tagID, BulkActionID: actionID})
+		case BulkRemoveTag:
+			return q.BulkRemoveTag(a.ctx, repository.BulkRemoveTagParams{TagID: *tagID, BulkActionID: actionID})
+		case BulkSetDeadline:
+			var deadline pgtype.Date
+			if req.Deadline != "" {
+				parsed, _ := time.Parse(layoutISO, req.Deadline)
+				deadline = pgtype.Date{Time: parsed, Valid: true}
+			}
+			return q.BulkSetDeadline(a.ctx, repository.BulkSetDeadlineParams{DeadlineAt: deadline, BulkActionID: actionID})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BulkResult{
+		Action:     req.Action,
+		Affected:   affected,
+		Message:    fmt.Sprintf(bulkActionSummaries[req.Action], affected),
+		actionID:   actionID,
+		changedIDs: changedIDs,
+	}, nil
+}
+
+// bulkSetStatus completes or reopens the notes and, like ChangeStatusNote, records
+// the change in the activity feed and schedules the next occurrences of completed
+// recurring notes; the created notes are removed on undo. It returns the notes
+// whose status changed.
+func (a App) bulkSetStatus(q *repository.Queries, userID, actionID int64, isCompleted bool) ([]int64, error) {
+	notes, err := q.GetBulkActionNotes(a.ctx, actionID)
+	if err != nil {
+		return nil, err
+	}
+	err = q.BulkSetCompleted(a.ctx, repository.BulkSetCompletedParams{IsCompleted: isCompleted, BulkActionID: actionID})
+	if err != nil {
+		return nil, err
+	}
+	var changedIDs []int64
+	for _, note := range notes {
+		if note.IsCompleted == isCompleted {
+			continue
+		}
+		changedIDs = append(changedIDs, note.ID)
+		if err = a.recordStatusChange(q, userID, note.ID, isCompleted); err != nil {
+			return nil, err
+		}
+		if !isCompleted {
+			continue
+		}
+		createdID, err := a.createNextOccurrence(q, note)
+		if err != nil {
+			return nil, err
+		}
+		if createdID != 0 {
+			err = q.AddBulkActionCreatedNote(a.ctx, repository.AddBulkActionCreatedNoteParams{
+				BulkActionID: actionID,
+				NoteID:       createdID,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changedIDs, nil
+}
+
+func (a App) undoBulk(userID int64) (*BulkResult, error) {
+	var result *BulkResult
+	err := a.inTx(func(q *repository.Queries) error {
+		last, err := q.GetLastBulkAction(a.ctx, userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errNothingToUndo
+		}
+		if err != nil {
+			return err
+		}
+		undone, err := q.MarkBulkActionUndone(a.ctx, last.ID)
+		if err != nil {
+			return err
+		}
+		if undone == 0 {
+			return errNothingToUndo
+		}
+
+		action := BulkAction(last.Action)
+		switch action {
+		case BulkComplete, BulkReopen:
+			if err = q.DeleteBulkActionCreatedNotes(a.ctx, last.ID); err == nil {
+				err = q.UndoBulkCompleted(a.ctx, last.ID)
+			}
+		case BulkDelete:
+			err = q.UndoBulkTrash(a.ctx, last.ID)
+		case BulkMove:
+			err = q.UndoBulkNotebook(a.ctx, last.ID)
+		case BulkSetDeadline:
+			err = q.UndoBulkDeadline(a.ctx, last.ID)
+		case BulkAddTag, BulkRemoveTag:
+			if last.TagID == nil {
+				return errBulkTagDeleted
+			}
+			if action == BulkAddTag {
+				err = q.UndoBulkAddTag(a.ctx, repository.UndoBulkAddTagParams{BulkActionID: last.ID, TagID: *last.TagID})
+			} else {
+				err = q.UndoBulkRemoveTag(a.ctx, repository.UndoBulkRemoveTagParams{BulkActionID: last.ID, TagID: *last.TagID})
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		count, err := q.CountBulkActionNotes(a.ctx, last.ID)
+		if err != nil {
+			return err
+		}
+		result = &BulkResult{
+			Action:   action,
+			Affected: count,
+			Message:  fmt.Sprintf("Отменено действие «%s» для заметок: %d", action.Label(), count),
+			Undone:   true,
+			actionID: last.ID,
+		}
+		return nil
+	})
+	return result, err
+}
+
+// canUndoBulk reports whether the user's latest bulk action can still be undone.
+func (a App) canUndoBulk(userID int64) bool {
+	last, err := a.db.GetLastBulkAction(a.ctx, userID)
+	return err == nil && !last.UndoneAt.Valid
+}
+
+func (a App) BulkNotes(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = r.ParseForm(); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := bulkRequestFromForm(r)
+	if message := req.Validate(); message != "" {
+		p = append(p, httprouter.Param{Key: "message", Value: message})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+	result, err := a.applyBulk(userID, req)
+	if err != nil {
+		message, _ := bulkErrorMessage(err)
+		p = append(p, httprouter.Param{Key: "message", Value: message})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+	a.publishBulk(userID, result)
+
+	p = append(p, httprouter.Param{Key: "message", Value: result.Message})
+	a.ShowMainPage(rw, r, p)
+}
+
+func (a App) UndoBulkNotes(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := a.undoBulk(userID)
+	if err != nil {
+		message, _ := bulkErrorMessage(err)
+		p = append(p, httprouter.Param{Key: "message", Value: message})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+	a.publishBulk(userID, result)
+	p = append(p, httprouter.Param{Key: "message", Value: result.Message})
+	a.ShowMainPage(rw, r, p)
+}
+
+func (a App) APIBulkNotes(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req BulkRequest
+	if err = json.NewDecoder(http.MaxBytesReader(rw, r.Body, megabyte)).Decode(&req); err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	if message := req.Validate(); message != "" {
+		WriteJSONError(rw, http.StatusBadRequest, message)
+		return
+	}
+	result, err := a.applyBulk(userID, &req)
+	if err != nil {
+		message, ok := bulkErrorMessage(err)
+		status := http.StatusInternalServerError
+		if ok {
+			status = http.StatusNotFound
+		}
+		WriteJSONError(rw, status, message)
+		return
+	}
+	a.publishBulk(userID, result)
+	WriteJSON(rw, http.StatusOK, result)
+}
+
+func (a App) APIUndoBulkNotes(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := a.undoBulk(userID)
+	if err != nil {
+		message, ok := bulkErrorMessage(err)
+		status := http.StatusInternalServerError
+		if ok {
+			status = http.StatusConflict
+		}
+		WriteJSONError(rw, status, message)
+		return
+	}
+	a.publishBulk(userID, result)
+	WriteJSON(rw, http.StatusOK, result)
+}
@@ -0,0 +1,371 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/collab"
+	"github.com/notjoji/web-notes/internal/events"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/ws"
+	"github.com/pkg/errors"
+)
+
+const (
+	collabPersistInterval = 5 * time.Second
+	collabPingInterval    = 30 * time.Second
+	collabReadTimeout     = 75 * time.Second
+	maxCollabMessage      = 256 << 10
+
+	CollabInit     = "init"
+	CollabOp       = "op"
+	CollabAck      = "ack"
+	CollabPresence = "presence"
+	CollabError    = "error"
+	CollabSaved    = "saved"
+)
+
+type CollabEditor struct {
+	ID       int64  `json:"id"`
+	Login    string `json:"login"`
+	ReadOnly bool   `json:"readOnly"`
+}
+
+// CollabMessage is sent both ways over the note's websocket: editors send "op",
+// the server answers with "ack" and forwards the transformed "op" to the others.
+type CollabMessage struct {
+	Type     string          `json:"type"`
+	Rev      int             `json:"rev"`
+	Op       *collab.Op      `json:"op,omitempty"`
+	Text     string          `json:"text,omitempty"`
+	ClientID int64           `json:"clientId,omitempty"`
+	ReadOnly bool            `json:"readOnly,omitempty"`
+	Editors  []*CollabEditor `json:"editors,omitempty"`
+	Message  string          `json:"message,omitempty"`
+	Version  int32           `json:"version,omitempty"`
+}
+
+type collabClient struct {
+	editor *CollabEditor
+	userID int64
+	conn   *ws.Conn
+}
+
+func (c *collabClient) send(msg *CollabMessage) {
+	if err := c.conn.WriteJSON(msg); err != nil {
+		_ = c.conn.Close()
+	}
+}
+
+// collabSession is a note being edited. base is the stored text at revision saved
+// and version its note version; saves are refused once the note is changed elsewhere.
+type collabSession struct {
+	noteID     int64
+	mu         sync.Mutex
+	saving     sync.Mutex
+	doc        *collab.Document
+	clients    map[*collabClient]struct{}
+	saved      int
+	base       string
+	version    int32
+	lastEditor int64
+	stop       chan struct{}
+}
+
+func (s *collabSession) editors() []*CollabEditor {
+	editors := make([]*CollabEditor, 0, len(s.clients))
+	for c := range s.clients {
+		editors = append(editors, c.editor)
+	}
+	return editors
+}
+
+// broadcast sends the message to every client but skip; the caller holds s.mu.
+func (s *collabSession) broadcast(msg *CollabMessage, skip *collabClient) {
+	for c := range s.clients {
+		if c != skip {
+			c.send(msg)
+		}
+	}
+}
+
+// collabHub keeps one session per note being edited on this instance; editors
+// connected to other instances are merged only when the note is saved.
+type collabHub struct {
+	mu       sync.Mutex
+	sessions map[int64]*collabSession
+	clientID atomic.Int64
+}
+
+func newCollabHub() *collabHub {
+	return &collabHub{sessions: make(map[int64]*collabSession)}
+}
+
+func (a App) joinCollab(note *repository.Note, client *collabClient) *collabSession {
+	a.collab.mu.Lock()
+	session, ok := a.collab.sessions[note.ID]
+	if !ok {
+		session = &collabSession{
+			noteID:  note.ID,
+			doc:     collab.NewDocument(*note.Description, maxNoteDescriptionLength),
+			clients: make(map[*collabClient]struct{}),
+			base:    *note.Description,
+			version: note.Version,
+			stop:    make(chan struct{}),
+		}
+		a.collab.sessions[note.ID] = session
+		go a.persistCollab(session)
+	}
+	// the client is added before the hub is unlocked, so the session can't be closed meanwhile
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	a.collab.mu.Unlock()
+
+	session.clients[client] = struct{}{}
+	client.send(&CollabMessage{
+		Type:     CollabInit,
+		Rev:      session.doc.Rev,
+		Text:     session.doc.String(),
+		ClientID: client.editor.ID,
+		ReadOnly: client.editor.ReadOnly,
+		Editors:  session.editors(),
+	})
+	session.broadcast(&CollabMessage{Type: CollabPresence, Editors: session.editors()}, client)
+	return session
+}
+
+// leaveCollab saves the text when the last editor leaves. The hub stays locked
+// meanwhile, so that a new session can't load the note before it is saved.
+func (a App) leaveCollab(session *collabSession, client *collabClient) {
+	a.collab.mu.Lock()
+	defer a.collab.mu.Unlock()
+
+	session.mu.Lock()
+	delete(session.clients, client)
+	last := len(session.clients) == 0
+	if last {
+		delete(a.collab.sessions, session.noteID)
+		close(session.stop)
+	} else {
+		session.broadcast(&CollabMessage{Type: CollabPresence, Editors: session.editors()}, nil)
+	}
+	session.mu.Unlock()
+
+	if last {
+		a.saveCollab(session)
+	}
+}
+
+func (a App) persistCollab(session *collabSession) {
+	ticker := time.NewTicker(collabPersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-session.stop:
+			return
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			a.saveCollab(session)
+		}
+	}
+}
+
+// saveCollab stores the text if it changed since the last save and tells the
+// editors the new note version, so that their edit forms don't conflict with the
+// session's own saves. If the note was changed elsewhere meanwhile, that change is
+// merged into the document first. An emptied note is not saved, as notes must have
+// a description.
+func (a App) saveCollab(session *collabSession) {
+	session.saving.Lock()
+	defer session.saving.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		session.mu.Lock()
+		rev, text, editor, version := session.doc.Rev, session.doc.String(), session.lastEditor, session.version
+		changed := rev != session.saved
+		session.mu.Unlock()
+		if !changed || strings.TrimSpace(text) == "" {
+			return
+		}
+
+		err := a.inTx(func(q *repository.Queries) error {
+			var err error
+			version, err = q.UpdateNoteDescription(a.ctx, repository.UpdateNoteDescriptionParams{
+				Description: &text,
+				ID:          session.noteID,
+				Version:     version,
+			})
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errNoteVersionConflict
+			}
+			if err != nil {
+				return err
+			}
+			return a.syncReferences(q, session.noteID, text)
+		})
+		if errors.Is(err, errNoteVersionConflict) && attempt < 2 {
+			err = a.mergeCollab(session, attempt > 0)
+			if err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			log.Printf("collab: can't save note %d: %v", session.noteID, err)
+			return
+		}
+
+		session.mu.Lock()
+		session.saved, session.base, session.version = rev, text, version
+		session.broadcast(&CollabMessage{Type: CollabSaved, Version: version}, nil)
+		session.mu.Unlock()
+		a.publishNoteByID(events.NoteUpdated, session.noteID, editor)
+		return
+	}
+}
+
+// mergeCollab applies the changes made to the stored note since the last save as
+// an edit made at that revision. When that is not possible, or reload is set because
+// the note keeps changing, the editors start over from the stored text.
+func (a App) mergeCollab(session *collabSession, reload bool) error {
+	note, err := a.db.GetNoteById(a.ctx, session.noteID)
+	if err != nil {
+		return err
+	}
+	stored := *note.Description
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.version = note.Version
+	if !reload {
+		diff := collab.Diff(session.base, stored)
+		if diff.IsNoop() {
+			return nil
+		}
+		op, err := session.doc.Apply(session.saved, diff)
+		if err == nil {
+			session.broadcast(&CollabMessage{Type: CollabOp, Rev: session.doc.Rev, Op: &op}, nil)
+			return nil
+		}
+	}
+
+	session.doc = collab.NewDocument(stored, maxNoteDescriptionLength)
+	session.saved, session.base = 0, stored
+	for c := range session.clients {
+		c.send(&CollabMessage{Type: CollabError, Message: "Заметка изменена в другом месте, текст обновлён"})
+		c.send(&CollabMessage{
+			Type:     CollabInit,
+			Rev:      session.doc.Rev,
+			Text:     session.doc.String(),
+			ClientID: c.editor.ID,
+			ReadOnly: c.editor.ReadOnly,
+			Editors:  session.editors(),
+		})
+	}
+	return nil
+}
+
+func (a App) applyCollabOp(session *collabSession, client *collabClient, msg *CollabMessage) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if client.editor.ReadOnly {
+		client.send(&CollabMessage{Type: CollabError, Message: "Недостаточно прав для изменения заметки!"})
+		return
+	}
+	op, err := session.doc.Apply(msg.Rev, *msg.Op)
+	if err != nil {
+		// the editor starts over from the current text
+		message := "Изменение не применено, текст обновлён"
+		if errors.Is(err, collab.ErrTooLong) {
+			message = "Описание заметки не должно быть длиннее " + strconv.Itoa(maxNoteDescriptionLength) + " символов!"
+		}
+		client.send(&CollabMessage{Type: CollabError, Message: message})
+		client.send(&CollabMessage{
+			Type:     CollabInit,
+			Rev:      session.doc.Rev,
+			Text:     session.doc.String(),
+			ClientID: client.editor.ID,
+			Editors:  session.editors(),
+		})
+		return
+	}
+	session.lastEditor = client.userID
+	client.send(&CollabMessage{Type: CollabAck, Rev: session.doc.Rev})
+	session.broadcast(&CollabMessage{Type: CollabOp, Rev: session.doc.Rev, Op: &op}, client)
+}
+
+// EditNoteLive connects an editor of the note page to the note's collaborative session.
+func (a App) EditNoteLive(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	noteID, err := strconv.ParseInt(p.ByName("page"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+	note, access, err := a.authorizeNote(userID, noteID, AccessView)
+	if err != nil {
+		http.Error(rw, "Заметка не найдена!", http.StatusNotFound)
+		return
+	}
+	user, err := a.db.GetUserById(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := ws.Upgrade(rw, r, maxCollabMessage, SiteOrigin(r))
+	if err != nil {
+		return
+	}
+	client := &collabClient{
+		editor: &CollabEditor{ID: a.collab.clientID.Add(1), Login: user.Login, ReadOnly: access < AccessEdit},
+		userID: userID,
+		conn:   conn,
+	}
+	session := a.joinCollab(note, client)
+	defer a.leaveCollab(session, client)
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(collabPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if conn.Ping() != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(collabReadTimeout))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg CollabMessage
+		if json.Unmarshal(data, &msg) != nil || msg.Type != CollabOp || msg.Op == nil {
+			continue
+		}
+		a.applyCollabOp(session, client, &msg)
+	}
+}
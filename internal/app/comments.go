@@ -0,0 +1,349 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/mentions"
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+type ActivityKind string
+
+const (
+	ActivityComment   ActivityKind = "comment"
+	ActivityCompleted ActivityKind = "completed"
+	ActivityReopened  ActivityKind = "reopened"
+
+	NotificationMention = "mention"
+
+	maxCommentLength = 5000
+)
+
+var activityLabels = map[ActivityKind]string{
+	ActivityCompleted: "Статус изменён: " + string(Completed),
+	ActivityReopened:  "Статус изменён: " + string(Active),
+}
+
+type ActivityItemDTO struct {
+	Kind      ActivityKind `json:"kind"`
+	ID        int64        `json:"id"`
+	Author    string       `json:"author"`
+	Body      string       `json:"body,omitempty"`
+	Label     string       `json:"label,omitempty"`
+	CreatedAt string       `json:"createdAt"`
+	UpdatedAt string       `json:"updatedAt,omitempty"`
+	Own       bool         `json:"own"`
+	at        time.Time
+}
+
+func (i *ActivityItemDTO) IsComment() bool {
+	return i.Kind == ActivityComment
+}
+
+// BuildActivityFeed interleaves the comments with the status changes by time.
+func BuildActivityFeed(
+	comments []*repository.GetCommentsByNoteIdRow,
+	events []*repository.GetNoteActivityByNoteIdRow,
+	viewerID int64,
+) []*ActivityItemDTO {
+	feed := make([]*ActivityItemDTO, 0, len(comments)+len(events))
+	for _, c := range comments {
+		item := &ActivityItemDTO{
+			Kind:      ActivityComment,
+			ID:        c.ID,
+			Author:    c.Login,
+			Body:      c.Body,
+			CreatedAt: c.CreatedAt.Time.Format(layoutDateTime),
+			Own:       c.UserID == viewerID,
+			at:        c.CreatedAt.Time,
+		}
+		if c.UpdatedAt.Valid {
+			item.UpdatedAt = c.UpdatedAt.Time.Format(layoutDateTime)
+		}
+		feed = append(feed, item)
+	}
+	for _, e := range events {
+		feed = append(feed, &ActivityItemDTO{
+			Kind:      ActivityKind(e.Kind),
+			ID:        e.ID,
+			Author:    e.Login,
+			Label:     activityLabels[ActivityKind(e.Kind)],
+			CreatedAt: e.CreatedAt.Time.Format(layoutDateTime),
+			at:        e.CreatedAt.Time,
+		})
+	}
+	sort.SliceStable(feed, func(i, j int) bool {
+		return feed[i].at.Before(feed[j].at)
+	})
+	return feed
+}
+
+func ValidateComment(body string) string {
+	if body == "" {
+		return "Комментарий не должен быть пустым!"
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return fmt.Sprintf("Комментарий не должен быть длиннее %d символов!", maxCommentLength)
+	}
+	return ""
+}
+
+func (a App) noteActivity(viewerID, noteID int64) ([]*ActivityItemDTO, error) {
+	comments, err := a.db.GetCommentsByNoteId(a.ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := a.db.GetNoteActivityByNoteId(a.ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildActivityFeed(comments, events, viewerID), nil
+}
+
+func (a App) recordStatusChange(q *repository.Queries, userID, noteID int64, isCompleted bool) error {
+	kind := ActivityReopened
+	if isCompleted {
+		kind = ActivityCompleted
+	}
+	return q.CreateNoteActivity(a.ctx, repository.CreateNoteActivityParams{
+		NoteID: noteID,
+		UserID: userID,
+		Kind:   string(kind),
+	})
+}
+
+// notifyMentions notifies the mentioned users who can see the note; unknown logins
+// and the author are skipped.
+func (a App) notifyMentions(q *repository.Queries, note *repository.Note, authorID, commentID int64, logins []string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	users, err := q.GetUsersByLogins(a.ctx, logins)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if user.ID == authorID {
+			continue
+		}
+		if access, err := a.noteAccess(user.ID, note); err != nil || access < AccessView {
+			continue
+		}
+		err = q.CreateNotification(a.ctx, repository.CreateNotificationParams{
+			UserID:    user.ID,
+			ActorID:   authorID,
+			NoteID:    note.ID,
+			CommentID: &commentID,
+			Kind:      NotificationMention,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func commentsURL(noteID int64) string {
+	return "/notes/" + strconv.FormatInt(noteID, 10) + "#comments"
+}
+
+func (a App) CreateComment(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	body := strings.TrimSpace(r.FormValue("commentBody"))
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	noteID, err := strconv.ParseInt(p.ByName("page"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+	note, _, err := a.authorizeNote(userID, noteID, AccessView)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Заметка не найдена!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	if message := ValidateComment(body); message != "" {
+		a.showNoteWithMessage(rw, r, p, noteID, message)
+		return
+	}
+
+	err = a.inTx(func(q *repository.Queries) error {
+		commentID, err := q.CreateComment(a.ctx, repository.CreateCommentParams{
+			NoteID: noteID,
+			UserID: userID,
+			Body:   body,
+		})
+		if err != nil {
+			return err
+		}
+		return a.notifyMentions(q, note, userID, commentID, mentions.Parse(body))
+	})
+	if err != nil {
+		a.showNoteWithMessage(rw, r, p, noteID, "Возникла ошибка при добавлении комментария!")
+		return
+	}
+
+	http.Redirect(rw, r, commentsURL(noteID), http.StatusSeeOther)
+}
+
+// ownComment returns the user's comment on a note the user can still view.
+func (a App) ownComment(userID int64, idParam string) (*repository.NoteComment, *repository.Note, error) {
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	comment, err := a.db.GetCommentById(a.ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if comment.UserID != userID {
+		return nil, nil, errNoteAccessDenied
+	}
+	note, _, err := a.authorizeNote(userID, comment.NoteID, AccessView)
+	if err != nil {
+		return nil, nil, err
+	}
+	return comment, note, nil
+}
+
+func (a App) UpdateComment(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	body := strings.TrimSpace(r.FormValue("commentBody"))
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	comment, note, err := a.ownComment(userID, p.ByName("id"))
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Комментарий не найден!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	if message := ValidateComment(body); message != "" {
+		a.showNoteWithMessage(rw, r, p, note.ID, message)
+		return
+	}
+
+	err = a.inTx(func(q *repository.Queries) error {
+		_, err := q.UpdateComment(a.ctx, repository.UpdateCommentParams{Body: body, ID: comment.ID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		return a.notifyMentions(q, note, userID, comment.ID, mentions.Added(comment.Body, body))
+	})
+	if err != nil {
+		a.showNoteWithMessage(rw, r, p, note.ID, "Возникла ошибка при изменении комментария!")
+		return
+	}
+
+	http.Redirect(rw, r, commentsURL(note.ID), http.StatusSeeOther)
+}
+
+func (a App) DeleteComment(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	comment, note, err := a.ownComment(userID, p.ByName("id"))
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Комментарий не найден!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	_, err = a.db.DeleteComment(a.ctx, repository.DeleteCommentParams{ID: comment.ID, UserID: userID})
+	if err != nil {
+		a.showNoteWithMessage(rw, r, p, note.ID, "Возникла ошибка при удалении комментария!")
+		return
+	}
+
+	http.Redirect(rw, r, commentsURL(note.ID), http.StatusSeeOther)
+}
+
+type NotificationDTO struct {
+	ID        int64  `json:"id"`
+	Actor     string `json:"actor"`
+	NoteID    int64  `json:"noteId"`
+	NoteName  string `json:"noteName"`
+	URL       string `json:"url"`
+	CreatedAt string `json:"createdAt"`
+	Unread    bool   `json:"unread"`
+}
+
+func MapNotification(n *repository.GetNotificationsByUserIdRow) *NotificationDTO {
+	url := "/notes/" + strconv.FormatInt(n.NoteID, 10)
+	if n.CommentID != nil {
+		url += "#comment-" + strconv.FormatInt(*n.CommentID, 10)
+	}
+	return &NotificationDTO{
+		ID:        n.ID,
+		Actor:     n.ActorLogin,
+		NoteID:    n.NoteID,
+		NoteName:  n.NoteName,
+		URL:       url,
+		CreatedAt: n.CreatedAt.Time.Format(layoutDateTime),
+		Unread:    !n.ReadAt.Valid,
+	}
+}
+
+func (a App) ShowNotificationsPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.db.GetNotificationsByUserId(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	notifications := make([]*NotificationDTO, len(rows))
+	for i, row := range rows {
+		notifications[i] = MapNotification(row)
+	}
+
+	tmpl := ParseTemplateFiles(rw, "notifications.html")
+	type NotificationsPageData struct {
+		Message       string
+		Notifications []*NotificationDTO
+	}
+	data := NotificationsPageData{p.ByName("message"), notifications}
+
+	err = tmpl.ExecuteTemplate(rw, "notifications", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) MarkNotificationsRead(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = a.db.MarkNotificationsRead(a.ctx, userID); err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при обновлении уведомлений!"})
+		a.ShowNotificationsPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/notifications", http.StatusSeeOther)
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/pkg/errors"
+)
+
+var errNoteVersionConflict = errors.New("note was changed since it was loaded")
+
+// NoteETag is the entity tag of a note: it changes with every saved edit.
+func NoteETag(version int32) string {
+	return `"` + strconv.FormatInt(int64(version), 10) + `"`
+}
+
+// ETagMatches reports whether an If-Match header value lists the entity tag.
+// Weak tags never match, as If-Match uses the strong comparison.
+func ETagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NoteUpdateFromForm returns the values submitted with the note edit form; the
+// conflict view shows them next to the saved note.
+func NoteUpdateFromForm(r *http.Request) *NoteUpdateDTO {
+	noteID, _ := strconv.ParseInt(strings.TrimSpace(r.FormValue("noteID")), 10, 64)
+	version, _ := strconv.ParseInt(strings.TrimSpace(r.FormValue("noteVersion")), 10, 32)
+	hasDeadline := r.FormValue("deadlineDateCheckbox") == "on"
+	deadline := ""
+	if hasDeadline {
+		deadline = strings.TrimSpace(r.FormValue("deadlineDatePicker"))
+	}
+	return &NoteUpdateDTO{
+		ID:          noteID,
+		Version:     int32(version),
+		Name:        strings.TrimSpace(r.FormValue("noteName")),
+		Description: strings.TrimSpace(r.FormValue("noteDesc")),
+		HasDeadline: hasDeadline,
+		Deadline:    deadline,
+		IsCompleted: r.FormValue("completedCheckbox") == "on",
+		Priority:    MapPriority(priorityFromForm(r)),
+		Pinned:      r.FormValue("pinnedCheckbox") == "on",
+	}
+}
+
+// saveNote updates the note if it is still at params.Version and returns the new
+// version, or errNoteVersionConflict if someone saved it in between.
+func (a App) saveNote(note *repository.Note, params repository.UpdateNoteParams) (int32, error) {
+	var version int32
+	err := a.inTx(func(q *repository.Queries) error {
+		var err error
+		version, err = q.UpdateNote(a.ctx, params)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errNoteVersionConflict
+		}
+		if err != nil {
+			return err
+		}
+		if err = a.syncReferences(q, note.ID, *params.Description); err != nil {
+			return err
+		}
+		return a.renameReferences(q, note, note.Name, params.Name)
+	})
+	return version, err
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+const layoutTime = "15:04"
+
+type DigestSettingsDTO struct {
+	Enabled   bool   `json:"enabled"`
+	Email     string `json:"email"`
+	SendTime  string `json:"sendTime"`
+	Timezone  string `json:"timezone"`
+	DaysAhead int32  `json:"daysAhead"`
+}
+
+func MapDigestSettings(s *repository.DigestSetting) *DigestSettingsDTO {
+	minutes := s.SendTime.Microseconds / int64(time.Minute/time.Microsecond)
+	return &DigestSettingsDTO{
+		Enabled:   s.Enabled,
+		Email:     s.Email,
+		SendTime:  fmt.Sprintf("%02d:%02d", minutes/60, minutes%60),
+		Timezone:  s.Timezone,
+		DaysAhead: s.DaysAhead,
+	}
+}
+
+// ParseDigestEmail accepts an address with or without a display name and returns
+// the bare address the mail server expects.
+func ParseDigestEmail(email string) (string, bool) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", false
+	}
+	return addr.Address, true
+}
+
+func (a App) ShowDigestSettingsPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := strconv.ParseInt(p.ByName("userID"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'userID' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	settings := &DigestSettingsDTO{SendTime: "08:00", Timezone: "Europe/Moscow", DaysAhead: 3}
+	if s, err := a.db.GetDigestSettingsByUserId(a.ctx, userID); err == nil {
+		settings = MapDigestSettings(s)
+	}
+
+	tmpl := ParseTemplateFiles(rw, "digestSettings.html")
+	type DigestSettingsPageData struct {
+		Message  string
+		Settings *DigestSettingsDTO
+	}
+	data := DigestSettingsPageData{p.ByName("message"), settings}
+
+	err = tmpl.ExecuteTemplate(rw, "digestSettings", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) SaveDigestSettings(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	enabled := r.FormValue("enabledCheckbox") == "on"
+	email := strings.TrimSpace(r.FormValue("email"))
+	sendTime := strings.TrimSpace(r.FormValue("sendTime"))
+	timezone := strings.TrimSpace(r.FormValue("timezone"))
+	daysAheadParam := strings.TrimSpace(r.FormValue("daysAhead"))
+
+	userID, err := strconv.ParseInt(p.ByName("userID"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'userID' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	email, ok := ParseDigestEmail(email)
+	if !ok {
+		p = append(p, httprouter.Param{Key: "message", Value: "Укажите корректный email!"})
+		a.ShowDigestSettingsPage(rw, r, p)
+		return
+	}
+	parsedTime, err := time.Parse(layoutTime, sendTime)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Укажите время отправки в формате ЧЧ:ММ!"})
+		a.ShowDigestSettingsPage(rw, r, p)
+		return
+	}
+	if _, err = time.LoadLocation(timezone); err != nil || timezone == "" {
+		p = append(p, httprouter.Param{Key: "message", Value: "Неизвестный часовой пояс!"})
+		a.ShowDigestSettingsPage(rw, r, p)
+		return
+	}
+	daysAhead, err := strconv.ParseInt(daysAheadParam, 10, 32)
+	if err != nil || daysAhead < 0 || daysAhead > 30 {
+		p = append(p, httprouter.Param{Key: "message", Value: "Количество дней должно быть от 0 до 30!"})
+		a.ShowDigestSettingsPage(rw, r, p)
+		return
+	}
+
+	sinceMidnight := time.Duration(parsedTime.Hour())*time.Hour + time.Duration(parsedTime.Minute())*time.Minute
+	err = a.db.UpsertDigestSettings(a.ctx, repository.UpsertDigestSettingsParams{
+		UserID:    userID,
+		Enabled:   enabled,
+		Email:     email,
+		SendTime:  pgtype.Time{Microseconds: sinceMidnight.Microseconds(), Valid: true},
+		Timezone:  timezone,
+		DaysAhead: int32(daysAhead),
+	})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при сохранении настроек сводки!"})
+		a.ShowDigestSettingsPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/", http.StatusSeeOther)
+}
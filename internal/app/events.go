@@ -0,0 +1,134 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/events"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/webhook"
+)
+
+const (
+	eventsHeartbeat  = 25 * time.Second
+	eventsRetryDelay = 5000
+)
+
+// FanOut makes the note events reach the subscribers of every instance sharing the
+// notifier; the returned fanout has to be fed with the received notifications.
+func (a *App) FanOut(notifier events.Notifier, channel string) *events.Fanout {
+	fanout := events.NewFanout(a.ctx, a.bus, notifier, channel)
+	a.publisher = fanout
+	return fanout
+}
+
+// noteAudience returns the owner of the note and everyone it is shared with,
+// directly or through a notebook.
+func (a App) noteAudience(note *repository.Note) ([]int64, error) {
+	shared, err := a.db.GetNoteAudience(a.ctx, note.ID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]int64{note.UserID}, shared...), nil
+}
+
+func (a App) publishNote(kind events.Kind, note *repository.Note, actorID int64) {
+	users, err := a.noteAudience(note)
+	if err != nil {
+		log.Printf("events: audience of note %d: %v", note.ID, err)
+		return
+	}
+	e := events.Event{Kind: kind, NoteID: note.ID, ActorID: actorID, Users: users}
+	if kind != events.NoteDeleted {
+		e.Note = MapNote(note)
+	}
+	a.publisher.Publish(e)
+}
+
+// publishNoteByID reloads the note so that the event carries its saved state.
+func (a App) publishNoteByID(kind events.Kind, noteID, actorID int64) {
+	note, err := a.db.GetNoteById(a.ctx, noteID)
+	if err != nil {
+		log.Printf("events: note %d: %v", noteID, err)
+		return
+	}
+	a.publishNote(kind, note, actorID)
+}
+
+func (a App) publishBulk(userID int64, result *BulkResult) {
+	notes, err := a.db.GetBulkActionNotes(a.ctx, result.actionID)
+	if err != nil {
+		log.Printf("events: bulk action %d: %v", result.actionID, err)
+		return
+	}
+	kind := events.NoteUpdated
+	switch {
+	case result.Action == BulkDelete && result.Undone:
+		kind = events.NoteCreated
+	case result.Action == BulkDelete:
+		kind = events.NoteDeleted
+	case result.Action == BulkComplete, result.Action == BulkReopen:
+		kind = events.NoteStatusChanged
+	}
+	for _, note := range notes {
+		a.publishNote(kind, note, userID)
+	}
+	if result.Action == BulkComplete && !result.Undone {
+		for _, noteID := range result.changedIDs {
+			a.enqueueWebhooks(a.ctx, webhook.EventNoteCompleted, noteID)
+		}
+	}
+}
+
+// Events streams the changes of the notes the user can see as server-sent events.
+func (a App) Events(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "потоковая передача не поддерживается", http.StatusInternalServerError)
+		return
+	}
+
+	sub := a.bus.Subscribe(userID)
+	defer sub.Close()
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("Connection", "keep-alive")
+	rw.Header().Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+	if _, err = rw.Write([]byte("retry: " + strconv.Itoa(eventsRetryDelay) + "\n\n")); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err = rw.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err = events.WriteSSE(rw, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,420 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/blobstore"
+	"github.com/notjoji/web-notes/internal/export"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+
+	exportTTL         = 7 * 24 * time.Hour
+	exportContentType = "application/zip"
+	// exportLease is how long a claimed export belongs to the instance building it
+	// without a renewal; the builder renews it while it works.
+	exportLease = 10 * time.Minute
+)
+
+var exportStatusLabels = map[string]string{
+	ExportPending: "В очереди",
+	ExportRunning: "Формируется",
+	ExportReady:   "Готов",
+	ExportFailed:  "Ошибка",
+}
+
+type ExportDTO struct {
+	ID          int64  `json:"id"`
+	Status      string `json:"status"`
+	StatusLabel string `json:"statusLabel"`
+	SizeLabel   string `json:"sizeLabel"`
+	Error       string `json:"error,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+	ExpiresAt   string `json:"expiresAt,omitempty"`
+}
+
+func MapExport(e *repository.Export) *ExportDTO {
+	dto := &ExportDTO{
+		ID:          e.ID,
+		Status:      e.Status,
+		StatusLabel: exportStatusLabels[e.Status],
+		CreatedAt:   e.CreatedAt.Time.Format(layoutDateTime),
+	}
+	if e.Error != nil {
+		dto.Error = *e.Error
+	}
+	if e.Status == ExportReady {
+		dto.SizeLabel = FormatSize(e.Size)
+		dto.ExpiresAt = e.FinishedAt.Time.Add(exportTTL).Format(layoutDateTime)
+	}
+	return dto
+}
+
+func (e *ExportDTO) Ready() bool {
+	return e.Status == ExportReady
+}
+
+func (e *ExportDTO) InProgress() bool {
+	return e.Status == ExportPending || e.Status == ExportRunning
+}
+
+type ExportNotebookDTO struct {
+	ID       int64  `json:"id"`
+	ParentID int64  `json:"parentId,omitempty"`
+	Name     string `json:"name"`
+}
+
+type ExportAccountDTO struct {
+	ID               int64                `json:"id"`
+	Login            string               `json:"login"`
+	ExportedAt       string               `json:"exportedAt"`
+	NotesCount       int                  `json:"notesCount"`
+	AttachmentsCount int                  `json:"attachmentsCount"`
+	Notebooks        []*ExportNotebookDTO `json:"notebooks"`
+	Digest           *DigestSettingsDTO   `json:"digest,omitempty"`
+}
+
+// ExportNoteDTO extends NoteDTO so that notes.json can be imported back via /import.
+type ExportNoteDTO struct {
+	*NoteDTO
+	Completed   bool     `json:"completed"`
+	NotebookID  int64    `json:"notebookId,omitempty"`
+	Trashed     bool     `json:"trashed,omitempty"`
+	File        string   `json:"file"`
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// WriteExport writes the user's account, notes and attachments into a zip archive:
+// account.json, notes.json, notes/*.md and attachments/<note id>/*.
+func (a App) WriteExport(ctx context.Context, userID int64, w io.Writer, now time.Time) error {
+	user, err := a.db.GetUserById(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "get user")
+	}
+	notes, err := a.db.GetAllNotesByUserId(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "get notes")
+	}
+	notebooks, err := a.db.GetNotebooksByUserId(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "get notebooks")
+	}
+	attachments, err := a.db.GetAttachmentsByUserId(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "get attachments")
+	}
+
+	dtos := make([]*NoteDTO, len(notes))
+	for i := range notes {
+		dtos[i] = MapNote(notes[i])
+	}
+	if err = a.fillTags(dtos); err != nil {
+		return errors.Wrap(err, "get tags")
+	}
+
+	archive := export.NewArchive(w, now)
+	attachmentFiles := make(map[int64][]string)
+	for _, attachment := range attachments {
+		name := archive.Name(path.Join("attachments", strconv.FormatInt(attachment.NoteID, 10),
+			export.FileName(attachment.FileName)))
+		body, err := a.blobs.Get(ctx, attachment.BlobKey, 0)
+		if errors.Is(err, blobstore.ErrNotFound) {
+			log.Printf("export: attachment %d has no blob, skipped", attachment.ID)
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "get attachment %d", attachment.ID)
+		}
+		err = archive.WriteFile(name, body)
+		body.Close()
+		if err != nil {
+			return err
+		}
+		attachmentFiles[attachment.NoteID] = append(attachmentFiles[attachment.NoteID], name)
+	}
+
+	exported := make([]*ExportNoteDTO, len(notes))
+	for i, note := range notes {
+		dto := dtos[i]
+		file := archive.Name(fmt.Sprintf("notes/%d-%s.md", note.ID, export.FileName(note.Name)))
+		markdown := export.Markdown(export.MarkdownNote{
+			Name:        dto.Name,
+			Description: dto.Description,
+			CreatedAt:   dto.CreatedAt,
+			Deadline:    dto.Deadline,
+			Completed:   note.IsCompleted,
+			Priority:    string(dto.Priority),
+			Tags:        dto.Tags,
+		})
+		if err = archive.WriteFile(file, strings.NewReader(markdown)); err != nil {
+			return err
+		}
+
+		exported[i] = &ExportNoteDTO{
+			NoteDTO:     dto,
+			Completed:   note.IsCompleted,
+			Trashed:     note.TrashedAt.Valid,
+			File:        file,
+			Attachments: attachmentFiles[note.ID],
+		}
+		if note.NotebookID != nil {
+			exported[i].NotebookID = *note.NotebookID
+		}
+	}
+	if err = archive.WriteJSON("notes.json", exported); err != nil {
+		return err
+	}
+
+	account := &ExportAccountDTO{
+		ID:               user.ID,
+		Login:            user.Login,
+		ExportedAt:       now.Format(time.RFC3339),
+		NotesCount:       len(notes),
+		AttachmentsCount: len(attachments),
+		Notebooks:        make([]*ExportNotebookDTO, len(notebooks)),
+	}
+	for i, notebook := range notebooks {
+		account.Notebooks[i] = &ExportNotebookDTO{ID: notebook.ID, Name: notebook.Name}
+		if notebook.ParentID != nil {
+			account.Notebooks[i].ParentID = *notebook.ParentID
+		}
+	}
+	if settings, err := a.db.GetDigestSettingsByUserId(ctx, userID); err == nil {
+		account.Digest = MapDigestSettings(settings)
+	}
+	if err = archive.WriteJSON("account.json", account); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// RunExports builds queued exports in the background. Exports interrupted by a
+// restart are queued again, and archives are removed after exportTTL.
+func (a App) RunExports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.reclaimExports(ctx)
+		a.processExports(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reclaimExports queues again the exports whose lease expired: the instance
+// building them stopped.
+func (a App) reclaimExports(ctx context.Context) {
+	if n, err := a.db.ReclaimExports(ctx, exportLease.Seconds()); err != nil {
+		log.Println("export: can't requeue exports:", err)
+	} else if n > 0 {
+		log.Printf("export: %d interrupted exports queued again", n)
+	}
+}
+
+// holdExportLease renews the lease of the export until the returned function is called.
+func (a App) holdExportLease(ctx context.Context, id int64) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(exportLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := a.db.RenewExportLease(ctx, id); err != nil && ctx.Err() == nil {
+				log.Printf("export: can't renew export %d: %v", id, err)
+			}
+		}
+	}()
+	return cancel
+}
+
+func (a App) processExports(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := a.db.ClaimExport(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			log.Println("export: can't claim export:", err)
+			break
+		}
+		release := a.holdExportLease(ctx, job.ID)
+		err = a.buildExport(ctx, job)
+		release()
+		if err != nil {
+			log.Printf("export: export %d failed: %v", job.ID, err)
+			message := "Не удалось сформировать архив"
+			if err = a.db.FailExport(ctx, repository.FailExportParams{ID: job.ID, Error: &message}); err != nil {
+				log.Printf("export: can't mark export %d failed: %v", job.ID, err)
+			}
+		}
+	}
+	a.cleanupExports(ctx)
+}
+
+// buildExport writes the archive to a temporary file first: blob stores need the size up front.
+func (a App) buildExport(ctx context.Context, job *repository.Export) error {
+	tmp, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err = a.WriteExport(ctx, job.UserID, tmp, time.Now()); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateToken(24)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("exports/%d/%s.zip", job.UserID, token)
+	if err = a.blobs.Put(ctx, key, tmp, size, exportContentType); err != nil {
+		return err
+	}
+	return a.db.FinishExport(ctx, repository.FinishExportParams{ID: job.ID, BlobKey: &key, Size: size})
+}
+
+func (a App) cleanupExports(ctx context.Context) {
+	expired, err := a.db.GetExpiredExports(ctx, pgtype.Timestamptz{Time: time.Now().Add(-exportTTL), Valid: true})
+	if err != nil {
+		log.Println("export: can't load expired exports:", err)
+		return
+	}
+	for _, e := range expired {
+		if e.BlobKey != nil {
+			if err = a.blobs.Delete(ctx, *e.BlobKey); err != nil {
+				log.Printf("export: delete blob %s: %v", *e.BlobKey, err)
+				continue
+			}
+		}
+		if err = a.db.DeleteExportById(ctx, e.ID); err != nil {
+			log.Printf("export: delete export %d: %v", e.ID, err)
+		}
+	}
+}
+
+func (a App) ShowExportsPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	exports, err := a.db.GetExportsByUserId(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dtos := make([]*ExportDTO, len(exports))
+	inProgress := false
+	for i := range exports {
+		dtos[i] = MapExport(exports[i])
+		inProgress = inProgress || dtos[i].InProgress()
+	}
+
+	tmpl := ParseTemplateFiles(rw, "exports.html")
+	type ExportsPageData struct {
+		Message    string
+		Exports    []*ExportDTO
+		InProgress bool
+	}
+	data := ExportsPageData{p.ByName("message"), dtos, inProgress}
+
+	err = tmpl.ExecuteTemplate(rw, "exports", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) CreateExport(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	active, err := a.db.CountActiveExportsByUserId(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if active > 0 {
+		p = append(p, httprouter.Param{Key: "message", Value: "Архив уже формируется, дождитесь его готовности!"})
+		a.ShowExportsPage(rw, r, p)
+		return
+	}
+
+	if _, err = a.db.CreateExport(a.ctx, userID); err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при создании архива!"})
+		a.ShowExportsPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/exports", http.StatusSeeOther)
+}
+
+func (a App) DownloadExport(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exportID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	e, err := a.db.GetExportById(a.ctx, exportID)
+	if err != nil || e.UserID != userID || e.Status != ExportReady || e.BlobKey == nil {
+		http.Error(rw, "архив не найден", http.StatusNotFound)
+		return
+	}
+
+	fileName := fmt.Sprintf("web-notes-export-%s.zip", e.FinishedAt.Time.Format(layoutISO))
+	rw.Header().Set("Content-Type", exportContentType)
+	rw.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": fileName,
+	}))
+	rw.Header().Set("Cache-Control", "private")
+
+	content := blobstore.NewReadSeeker(r.Context(), a.blobs, *e.BlobKey, e.Size)
+	defer content.Close()
+	http.ServeContent(rw, r, fileName, e.FinishedAt.Time, content)
+}
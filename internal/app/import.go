@@ -0,0 +1,450 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/events"
+	"github.com/notjoji/web-notes/internal/importer"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/utils"
+	"github.com/notjoji/web-notes/internal/webhook"
+	"github.com/pkg/errors"
+)
+
+const (
+	maxImportSize     = 50 * megabyte
+	maxTagLength      = 50
+	importPreviewTTL  = 15 * time.Minute
+	ImportFormatJSON  = "json"
+	ImportFormatCSV   = "csv"
+	ImportFormatMDZip = "markdown"
+	ImportFormatKeep  = "keep"
+	ImportFormatENEX  = "enex"
+)
+
+type ImportReport struct {
+	Imported int                 `json:"imported"`
+	Errors   []importer.RowError `json:"errors"`
+	Preview  []*ImportPreviewDTO `json:"preview,omitempty"`
+}
+
+type ImportPreviewDTO struct {
+	Source    string   `json:"source"`
+	Name      string   `json:"name"`
+	Deadline  string   `json:"deadline,omitempty"`
+	CreatedAt string   `json:"createdAt,omitempty"`
+	Completed bool     `json:"completed"`
+	Trashed   bool     `json:"trashed"`
+	Pinned    bool     `json:"pinned"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+func MapImportPreview(note *importer.Note) *ImportPreviewDTO {
+	return &ImportPreviewDTO{
+		Source:    note.Source,
+		Name:      note.Name,
+		Deadline:  note.Deadline,
+		CreatedAt: note.CreatedAt,
+		Completed: note.Completed,
+		Trashed:   note.Trashed,
+		Pinned:    note.Pinned,
+		Tags:      note.Tags,
+	}
+}
+
+type importedNote struct {
+	params repository.ImportNoteParams
+	tags   []string
+}
+
+type importPreview struct {
+	userID    int64
+	notes     []*importedNote
+	expiresAt time.Time
+}
+
+// importPreviews keeps validated notes between the dry run and its confirmation,
+// so the file does not have to be uploaded twice.
+type importPreviews struct {
+	mu    sync.Mutex
+	items map[string]*importPreview
+}
+
+func newImportPreviews() *importPreviews {
+	return &importPreviews{items: make(map[string]*importPreview)}
+}
+
+func (s *importPreviews) put(userID int64, notes []*importedNote, now time.Time) (string, error) {
+	token, err := utils.GenerateToken(24)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, preview := range s.items {
+		if now.After(preview.expiresAt) || preview.userID == userID {
+			delete(s.items, key)
+		}
+	}
+	s.items[token] = &importPreview{userID, notes, now.Add(importPreviewTTL)}
+	return token, nil
+}
+
+func (s *importPreviews) take(token string, userID int64, now time.Time) []*importedNote {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	preview, ok := s.items[token]
+	if !ok || preview.userID != userID {
+		return nil
+	}
+	delete(s.items, token)
+	if now.After(preview.expiresAt) {
+		return nil
+	}
+	return preview.notes
+}
+
+// ParseJSONNotes reads an array of notes in the format returned by GET /api/notes
+// or written to notes.json of an account export.
+func ParseJSONNotes(r io.Reader) ([]*importer.Note, error) {
+	var dtos []*ExportNoteDTO
+	decoder := json.NewDecoder(r)
+	if err := decoder.Decode(&dtos); err != nil {
+		return nil, errors.Wrap(err, "некорректный JSON")
+	}
+	if len(dtos) > importer.MaxRecords {
+		return nil, errors.Errorf("не более %d записей за один импорт", importer.MaxRecords)
+	}
+
+	notes := make([]*importer.Note, 0, len(dtos))
+	for i, dto := range dtos {
+		if dto == nil || dto.NoteDTO == nil {
+			continue
+		}
+		notes = append(notes, &importer.Note{
+			Source:      fmt.Sprintf("запись %d", i+1),
+			Name:        strings.TrimSpace(dto.Name),
+			Description: strings.TrimSpace(dto.Description),
+			Deadline:    dto.Deadline,
+			CreatedAt:   dto.CreatedAt,
+			Completed:   dto.Completed || dto.Type == Completed,
+			Trashed:     dto.Trashed,
+			Priority:    string(dto.Priority),
+			Pinned:      dto.Pinned,
+			Tags:        importer.NormalizeTags(dto.Tags),
+		})
+	}
+	return notes, nil
+}
+
+// ParseNoteTime parses a creation time of an imported note: a date, a time as
+// shown on the pages (in the server's zone) or RFC 3339.
+func ParseNoteTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(layoutDateTime, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(layoutISO, value, time.Local)
+}
+
+// ValidateImportedNotes applies the rules of CreateNewNote to every note and
+// returns the insert parameters only when all of them are valid.
+func ValidateImportedNotes(userID int64, notes []*importer.Note) ([]*importedNote, []importer.RowError) {
+	var rowErrors []importer.RowError
+	result := make([]*importedNote, 0, len(notes))
+	for _, note := range notes {
+		if message := ValidateNote(note.Name, note.Description, note.Deadline != "", note.Deadline); message != "" {
+			rowErrors = append(rowErrors, importer.RowError{Source: note.Source, Message: message})
+			continue
+		}
+
+		priority := int16(1)
+		if note.Priority != "" {
+			var ok bool
+			if priority, ok = ParsePriority(note.Priority); !ok {
+				rowErrors = append(rowErrors, importer.RowError{Source: note.Source, Message: "Некорректный приоритет!"})
+				continue
+			}
+		}
+
+		var createdAt pgtype.Timestamptz
+		if note.CreatedAt != "" {
+			parsed, err := ParseNoteTime(note.CreatedAt)
+			if err != nil {
+				rowErrors = append(rowErrors, importer.RowError{Source: note.Source, Message: "Некорректная дата создания!"})
+				continue
+			}
+			createdAt = pgtype.Timestamptz{Time: parsed, Valid: true}
+		}
+
+		if tag := longTag(note.Tags); tag != "" {
+			rowErrors = append(rowErrors, importer.RowError{
+				Source:  note.Source,
+				Message: fmt.Sprintf("Тег «%s» длиннее %d символов!", tag, maxTagLength),
+			})
+			continue
+		}
+
+		description := note.Description
+		params := repository.ImportNoteParams{
+			UserID:      userID,
+			Name:        note.Name,
+			Description: &description,
+			IsCompleted: note.Completed,
+			Priority:    priority,
+			Pinned:      note.Pinned,
+			CreatedAt:   createdAt,
+		}
+		if note.Trashed {
+			params.TrashedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		}
+		if note.Deadline != "" {
+			parsed, _ := time.Parse(layoutISO, note.Deadline)
+			params.DeadlineAt = pgtype.Date{Time: parsed, Valid: true}
+		}
+		result = append(result, &importedNote{params, note.Tags})
+	}
+	if len(rowErrors) > 0 {
+		return nil, rowErrors
+	}
+	return result, nil
+}
+
+func longTag(tags []string) string {
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return tag
+		}
+	}
+	return ""
+}
+
+// parseImportFile reads the uploaded file in the requested format. Row errors are
+// returned separately from errors that make the whole file unreadable.
+func parseImportFile(r *http.Request) ([]*importer.Note, []importer.RowError, error) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, nil, errors.New("Выберите файл для импорта!")
+	}
+	defer file.Close()
+
+	switch r.FormValue("format") {
+	case ImportFormatJSON:
+		notes, err := ParseJSONNotes(file)
+		return notes, nil, err
+	case ImportFormatCSV:
+		mapping := importer.Mapping{
+			Name:        strings.TrimSpace(r.FormValue("nameColumn")),
+			Description: strings.TrimSpace(r.FormValue("descriptionColumn")),
+			Deadline:    strings.TrimSpace(r.FormValue("deadlineColumn")),
+			Completed:   strings.TrimSpace(r.FormValue("completedColumn")),
+			Tags:        strings.TrimSpace(r.FormValue("tagsColumn")),
+		}
+		if mapping == (importer.Mapping{}) {
+			mapping = importer.DefaultMapping()
+		}
+		return importer.ParseCSV(file, mapping)
+	case ImportFormatMDZip:
+		return importer.ParseMarkdownZip(file, header.Size)
+	case ImportFormatKeep:
+		if strings.EqualFold(path.Ext(header.Filename), ".json") {
+			content, err := io.ReadAll(io.LimitReader(file, importer.MaxFileSize))
+			if err != nil {
+				return nil, nil, err
+			}
+			note, err := importer.ParseKeepNote(header.Filename, content)
+			if err != nil {
+				return nil, nil, err
+			}
+			return []*importer.Note{note}, nil, nil
+		}
+		return importer.ParseKeepZip(file, header.Size)
+	case ImportFormatENEX:
+		return importer.ParseENEX(file)
+	}
+	return nil, nil, errors.New("Неизвестный формат импорта!")
+}
+
+func (a App) importNotes(notes []*importedNote) error {
+	noteIDs := make([]int64, 0, len(notes))
+	err := a.inTx(func(q *repository.Queries) error {
+		tagIDs := make(map[string]int64)
+		for _, note := range notes {
+			noteID, err := q.ImportNote(a.ctx, note.params)
+			if err != nil {
+				return err
+			}
+			noteIDs = append(noteIDs, noteID)
+			if err = a.syncReferences(q, noteID, *note.params.Description); err != nil {
+				return err
+			}
+			for _, tag := range note.tags {
+				tagID, ok := tagIDs[tag]
+				if !ok {
+					tagID, err = q.UpsertTag(a.ctx, repository.UpsertTagParams{UserID: note.params.UserID, Name: tag})
+					if err != nil {
+						return err
+					}
+					tagIDs[tag] = tagID
+				}
+				if err = q.AddNoteTag(a.ctx, repository.AddNoteTagParams{NoteID: noteID, TagID: tagID}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, noteID := range noteIDs {
+		a.publishNoteByID(events.NoteCreated, noteID, notes[i].params.UserID)
+		a.enqueueWebhooks(a.ctx, webhook.EventNoteCreated, noteID)
+	}
+	return nil
+}
+
+// prepareImport parses and validates the upload without writing anything. When at
+// least one row is invalid nothing is imported, so a fixed file can be uploaded
+// again without creating duplicates.
+func (a App) prepareImport(
+	rw http.ResponseWriter, r *http.Request, userID int64,
+) ([]*importedNote, *ImportReport, int, error) {
+	r.Body = http.MaxBytesReader(rw, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(megabyte); err != nil {
+		return nil, nil, http.StatusRequestEntityTooLarge,
+			errors.Errorf("Размер файла не должен превышать %s!", FormatSize(maxImportSize))
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	notes, rowErrors, err := parseImportFile(r)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+	valid, validationErrors := ValidateImportedNotes(userID, notes)
+	rowErrors = append(rowErrors, validationErrors...)
+	if len(rowErrors) > 0 {
+		return nil, &ImportReport{Errors: rowErrors}, http.StatusUnprocessableEntity, nil
+	}
+	if len(valid) == 0 {
+		return nil, nil, http.StatusBadRequest, errors.New("В файле нет заметок для импорта!")
+	}
+
+	report := &ImportReport{Preview: make([]*ImportPreviewDTO, len(notes))}
+	for i := range notes {
+		report.Preview[i] = MapImportPreview(notes[i])
+	}
+	return valid, report, http.StatusOK, nil
+}
+
+func (a App) ShowImportPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	a.showImportPage(rw, p.ByName("message"), nil, "")
+}
+
+func (a App) showImportPage(rw http.ResponseWriter, message string, report *ImportReport, token string) {
+	tmpl := ParseTemplateFiles(rw, "import.html")
+	type ImportPageData struct {
+		Message string
+		Report  *ImportReport
+		Mapping importer.Mapping
+		Token   string
+	}
+	data := ImportPageData{message, report, importer.DefaultMapping(), token}
+
+	err := tmpl.ExecuteTemplate(rw, "import", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) ImportNotes(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notes, report, _, err := a.prepareImport(rw, r, userID)
+	if err != nil {
+		a.showImportPage(rw, err.Error(), nil, "")
+		return
+	}
+	if len(report.Errors) > 0 {
+		a.showImportPage(rw, "Заметки не импортированы: исправьте ошибки и загрузите файл снова!", report, "")
+		return
+	}
+
+	if r.FormValue("action") == "preview" {
+		token, err := a.importPreviews.put(userID, notes, time.Now())
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		message := fmt.Sprintf("Будет импортировано заметок: %d. Проверьте список и подтвердите импорт.", len(notes))
+		a.showImportPage(rw, message, report, token)
+		return
+	}
+
+	if err = a.importNotes(notes); err != nil {
+		a.showImportPage(rw, "Возникла ошибка при импорте заметок!", nil, "")
+		return
+	}
+	a.showImportPage(rw, fmt.Sprintf("Импортировано заметок: %d", len(notes)), nil, "")
+}
+
+func (a App) ConfirmImport(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notes := a.importPreviews.take(r.FormValue("token"), userID, time.Now())
+	if notes == nil {
+		a.showImportPage(rw, "Предпросмотр устарел, загрузите файл снова!", nil, "")
+		return
+	}
+	if err = a.importNotes(notes); err != nil {
+		a.showImportPage(rw, "Возникла ошибка при импорте заметок!", nil, "")
+		return
+	}
+	a.showImportPage(rw, fmt.Sprintf("Импортировано заметок: %d", len(notes)), nil, "")
+}
+
+// APIImportNotes imports the file; with ?dryRun=true it only returns the preview.
+func (a App) APIImportNotes(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	notes, report, status, err := a.prepareImport(rw, r, userID)
+	if err != nil {
+		WriteJSONError(rw, status, err.Error())
+		return
+	}
+	if len(report.Errors) > 0 || r.URL.Query().Get("dryRun") == "true" {
+		WriteJSON(rw, status, report)
+		return
+	}
+
+	if err = a.importNotes(notes); err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, "Возникла ошибка при импорте заметок!")
+		return
+	}
+	WriteJSON(rw, http.StatusOK, &ImportReport{Imported: len(notes)})
+}
@@ -0,0 +1,254 @@
+package app
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/wikilinks"
+	"github.com/pkg/errors"
+)
+
+// LinkTargets are the notes the [[links]] of a page resolve to. Names are matched
+// case-insensitively among the notes of the linking note's owner, so a shared note
+// links the same for every viewer; only notes the viewer can open are resolved.
+type LinkTargets struct {
+	ByID   map[int64]*repository.Note
+	ByName map[string]*repository.Note
+}
+
+func (t *LinkTargets) resolve(link wikilinks.Link) *wikilinks.Target {
+	var note *repository.Note
+	if link.ID != 0 {
+		note = t.ByID[link.ID]
+	} else {
+		note = t.ByName[strings.ToLower(link.Name)]
+	}
+	if note == nil {
+		return nil
+	}
+	title := link.Name
+	if title == "" {
+		title = note.Name
+	}
+	return &wikilinks.Target{URL: "/notes/" + strconv.FormatInt(note.ID, 10), Title: title}
+}
+
+func RenderNoteLinks(description string, targets *LinkTargets) template.HTML {
+	return wikilinks.Render(description, targets.resolve)
+}
+
+func (a App) linkTargets(userID, ownerID int64, descriptions []string) (*LinkTargets, error) {
+	targets := &LinkTargets{ByID: map[int64]*repository.Note{}, ByName: map[string]*repository.Note{}}
+	ids := make([]int64, 0)
+	names := make([]string, 0)
+	for _, description := range descriptions {
+		for _, link := range wikilinks.Parse(description) {
+			if link.ID != 0 {
+				ids = append(ids, link.ID)
+			} else {
+				names = append(names, strings.ToLower(link.Name))
+			}
+		}
+	}
+	if len(ids)+len(names) == 0 {
+		return targets, nil
+	}
+
+	notes, err := a.db.GetLinkTargets(a.ctx, repository.GetLinkTargetsParams{Ids: ids, UserID: ownerID, Names: names})
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range notes {
+		if note.UserID != userID {
+			if access, err := a.noteAccess(userID, note); err != nil || access < AccessView {
+				continue
+			}
+		}
+		if note.UserID == ownerID {
+			key := strings.ToLower(note.Name)
+			if _, ok := targets.ByName[key]; !ok {
+				targets.ByName[key] = note
+			}
+		}
+		targets.ByID[note.ID] = note
+	}
+	return targets, nil
+}
+
+// renderLinks fills the rendered descriptions of the listed notes; links of shared
+// notes are resolved among their owners' notes.
+func (a App) renderLinks(userID int64, dtos []*NoteDTO) error {
+	byOwner := make(map[int64][]*NoteDTO)
+	for _, dto := range dtos {
+		byOwner[dto.UserID] = append(byOwner[dto.UserID], dto)
+	}
+	for ownerID, owned := range byOwner {
+		descriptions := make([]string, len(owned))
+		for i, dto := range owned {
+			descriptions[i] = dto.Description
+		}
+		targets, err := a.linkTargets(userID, ownerID, descriptions)
+		if err != nil {
+			return err
+		}
+		for _, dto := range owned {
+			dto.Body = RenderNoteLinks(dto.Description, targets)
+		}
+	}
+	return nil
+}
+
+// syncReferences stores the links of a note description so that backlinks and
+// broken links can be found without parsing every note.
+func (a App) syncReferences(q *repository.Queries, noteID int64, description string) error {
+	if err := q.DeleteNoteReferences(a.ctx, noteID); err != nil {
+		return err
+	}
+	for _, link := range wikilinks.Unique(wikilinks.Parse(description)) {
+		params := repository.AddNoteReferenceParams{NoteID: noteID}
+		if link.ID != 0 {
+			params.TargetID = &link.ID
+		} else {
+			name := link.Name
+			params.TargetName = &name
+		}
+		if err := q.AddNoteReference(a.ctx, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameReferences rewrites [[oldName]] links in the owner's notes after a rename.
+// Links are kept as is while another note still has the old name.
+func (a App) renameReferences(q *repository.Queries, note *repository.Note, oldName, newName string) error {
+	if oldName == newName {
+		return nil
+	}
+	others, err := q.CountNotesByName(a.ctx, repository.CountNotesByNameParams{
+		UserID:    note.UserID,
+		ExcludeID: note.ID,
+		Name:      oldName,
+	})
+	if err != nil || others > 0 {
+		return err
+	}
+
+	notes, err := q.GetNotesReferencingName(a.ctx, repository.GetNotesReferencingNameParams{
+		UserID: note.UserID,
+		Name:   oldName,
+	})
+	if err != nil {
+		return err
+	}
+	for _, n := range notes {
+		if err = a.renameLinksInNote(q, n, oldName, newName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameLinksInNote saves the note with renamed links over the version it was
+// read at. If it was changed in between, e.g. by a collaborative editing session,
+// the rename is applied to the fresh text.
+func (a App) renameLinksInNote(q *repository.Queries, n *repository.Note, oldName, newName string) error {
+	for attempt := 0; attempt < 3; attempt++ {
+		description := wikilinks.Rename(*n.Description, oldName, newName)
+		if description == *n.Description {
+			return nil
+		}
+		_, err := q.UpdateNoteDescription(a.ctx, repository.UpdateNoteDescriptionParams{
+			Description: &description,
+			ID:          n.ID,
+			Version:     n.Version,
+		})
+		if err == nil {
+			return a.syncReferences(q, n.ID, description)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		n, err = q.GetNoteById(a.ctx, n.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return errNoteVersionConflict
+}
+
+func (a App) backlinks(userID int64, note *repository.Note) ([]*NoteDTO, error) {
+	notes, err := a.db.GetBacklinks(a.ctx, repository.GetBacklinksParams{
+		TargetID: note.ID,
+		Name:     note.Name,
+		UserID:   note.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]*NoteDTO, 0, len(notes))
+	for _, n := range notes {
+		if n.UserID != userID {
+			if access, err := a.noteAccess(userID, n); err != nil || access < AccessView {
+				continue
+			}
+		}
+		dtos = append(dtos, MapNote(n))
+	}
+	return dtos, nil
+}
+
+type BrokenLinkDTO struct {
+	NoteID   int64  `json:"noteId"`
+	NoteName string `json:"noteName"`
+	Link     string `json:"link"`
+}
+
+func MapBrokenLink(row *repository.GetBrokenReferencesRow) *BrokenLinkDTO {
+	link := wikilinks.Link{}
+	if row.TargetID != nil {
+		link.ID = *row.TargetID
+	} else if row.TargetName != nil {
+		link.Name = *row.TargetName
+	}
+	return &BrokenLinkDTO{NoteID: row.NoteID, NoteName: row.NoteName, Link: link.String()}
+}
+
+func (a App) ShowBrokenLinksPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := a.db.GetBrokenReferences(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	links := make([]*BrokenLinkDTO, len(rows))
+	for i, row := range rows {
+		links[i] = MapBrokenLink(row)
+	}
+
+	tmpl := ParseTemplateFiles(rw, "brokenLinks.html")
+	type BrokenLinksPageData struct {
+		Message string
+		Links   []*BrokenLinkDTO
+	}
+	data := BrokenLinksPageData{p.ByName("message"), links}
+
+	err = tmpl.ExecuteTemplate(rw, "brokenLinks", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
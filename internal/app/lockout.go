@@ -0,0 +1,175 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+const (
+	AuditWrongPassword = "password"
+	AuditWrongCode     = "code"
+	AuditLocked        = "locked"
+	AuditDisabled      = "disabled"
+)
+
+const (
+	loginAuditLimit    = 50
+	trackedLoginMaxLen = 64
+	wrongLoginMessage  = "Вы ввели неверный логин или пароль!"
+)
+
+// LoginPolicy slows down password guessing: after FreeAttempts failures every next
+// one doubles the wait starting from BaseDelay, and after LockoutAfter failures the
+// key is locked for LockoutDuration. Failures older than ResetAfter are forgotten.
+type LoginPolicy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	ResetAfter      time.Duration
+}
+
+var (
+	LoginPolicyByLogin = LoginPolicy{3, time.Second, 5 * time.Minute, 10, 30 * time.Minute, 24 * time.Hour}
+	LoginPolicyByIP    = LoginPolicy{20, time.Second, 5 * time.Minute, 100, 30 * time.Minute, 24 * time.Hour}
+)
+
+// BlockedFor returns how long attempts are refused after the given number of
+// consecutive failures.
+func (p LoginPolicy) BlockedFor(failures int) time.Duration {
+	switch {
+	case failures >= p.LockoutAfter:
+		return p.LockoutDuration
+	case failures < p.FreeAttempts:
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+type loginKey struct {
+	key    string
+	policy LoginPolicy
+}
+
+// LoginKeys returns the limiter keys of an attempt. Logins are tracked whether
+// they exist or not, so a lockout does not reveal registered accounts.
+func LoginKeys(login, ip string) []string {
+	return []string{"login:" + truncateLogin(login), "ip:" + ip}
+}
+
+func loginKeys(login, ip string) []loginKey {
+	keys := LoginKeys(login, ip)
+	return []loginKey{{keys[0], LoginPolicyByLogin}, {keys[1], LoginPolicyByIP}}
+}
+
+func truncateLogin(login string) string {
+	if runes := []rune(login); len(runes) > trackedLoginMaxLen {
+		return string(runes[:trackedLoginMaxLen])
+	}
+	return login
+}
+
+// ClientIP is the address of the connection. Forwarding headers are ignored, as
+// a client could put any address there.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func formatWait(wait time.Duration) string {
+	if wait < time.Minute {
+		return fmt.Sprintf("%d сек.", int((wait+time.Second-1)/time.Second))
+	}
+	return FormatDuration(wait)
+}
+
+func LockoutMessage(wait time.Duration) string {
+	return fmt.Sprintf("Слишком много неудачных попыток входа. Повторите через %s", formatWait(wait))
+}
+
+// reserveAttempt counts an attempt as failed before it is checked, unless one of
+// the keys is locked, and returns how long it stays locked by the database clock.
+// The rows stay locked until the count is stored, so parallel attempts see each
+// other's failures. A successful attempt gives its reservation back.
+func (a App) reserveAttempt(keys []loginKey) (time.Duration, error) {
+	var wait time.Duration
+	err := a.inTx(func(q *repository.Queries) error {
+		for _, key := range keys {
+			attempt, err := q.GetLoginAttemptForUpdate(a.ctx, key.key)
+			if err != nil {
+				return err
+			}
+			if attempt.LockedUntil.Valid {
+				wait = max(wait, attempt.LockedUntil.Time.Sub(attempt.Now.Time))
+			}
+		}
+		if wait > 0 {
+			return nil
+		}
+		return a.recordFailures(q, keys)
+	})
+	return wait, err
+}
+
+func (a App) releaseAttempt(key loginKey) error {
+	return a.db.ReleaseLoginAttempt(a.ctx, repository.ReleaseLoginAttemptParams{
+		FreeAttempts: int32(key.policy.FreeAttempts),
+		Key:          key.key,
+	})
+}
+
+func (a App) reserveLogin(login, ip string) (time.Duration, error) {
+	return a.reserveAttempt(loginKeys(login, ip))
+}
+
+// releaseLogin forgets the failures of the login, but only gives back the attempt
+// of the address, which may be guessing other accounts.
+func (a App) releaseLogin(login, ip string) error {
+	keys := loginKeys(login, ip)
+	if err := a.db.ClearLoginAttempts(a.ctx, keys[0].key); err != nil {
+		return err
+	}
+	return a.releaseAttempt(keys[1])
+}
+
+func (a App) auditLogin(login, ip, reason string) error {
+	return a.db.CreateLoginAudit(a.ctx, repository.CreateLoginAuditParams{
+		Login:  truncateLogin(login),
+		Ip:     ip,
+		Reason: reason,
+	})
+}
+
+func (a App) recordFailures(q *repository.Queries, keys []loginKey) error {
+	for _, key := range keys {
+		failures, err := q.RecordLoginFailure(a.ctx, repository.RecordLoginFailureParams{
+			Key:          key.key,
+			ResetSeconds: key.policy.ResetAfter.Seconds(),
+		})
+		if err != nil {
+			return err
+		}
+		if delay := key.policy.BlockedFor(int(failures)); delay > 0 {
+			err = q.LockLoginAttempts(a.ctx, repository.LockLoginAttemptsParams{
+				Seconds: delay.Seconds(),
+				Key:     key.key,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
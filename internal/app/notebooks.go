@@ -0,0 +1,424 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/events"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/pkg/errors"
+)
+
+var errMoveIntoDeletedNotebook = errors.New("target notebook is being deleted")
+
+type NotebookDTO struct {
+	ID       int64          `json:"id"`
+	ParentID int64          `json:"parentId"`
+	Name     string         `json:"name"`
+	Count    int64          `json:"count"`
+	Depth    int            `json:"depth"`
+	Active   bool           `json:"active"`
+	Children []*NotebookDTO `json:"children"`
+}
+
+// Indent is used to render nested notebooks inside <select> options.
+func (n *NotebookDTO) Indent() string {
+	return strings.Repeat("— ", n.Depth)
+}
+
+// BuildNotebookTree returns root notebooks with nested children; notebooks whose parent
+// is missing from the list are treated as roots.
+func BuildNotebookTree(notebooks []*repository.Notebook, counts map[int64]int64, activeID int64) []*NotebookDTO {
+	byID := make(map[int64]*NotebookDTO, len(notebooks))
+	for _, nb := range notebooks {
+		dto := &NotebookDTO{
+			ID:       nb.ID,
+			Name:     nb.Name,
+			Count:    counts[nb.ID],
+			Active:   nb.ID == activeID,
+			Children: []*NotebookDTO{},
+		}
+		if nb.ParentID != nil {
+			dto.ParentID = *nb.ParentID
+		}
+		byID[nb.ID] = dto
+	}
+
+	roots := make([]*NotebookDTO, 0)
+	for _, nb := range notebooks {
+		dto := byID[nb.ID]
+		parent, ok := byID[dto.ParentID]
+		if dto.ParentID == 0 || !ok {
+			roots = append(roots, dto)
+			continue
+		}
+		parent.Children = append(parent.Children, dto)
+	}
+	setNotebookDepth(roots, 0)
+	return roots
+}
+
+func setNotebookDepth(notebooks []*NotebookDTO, depth int) {
+	for _, nb := range notebooks {
+		nb.Depth = depth
+		setNotebookDepth(nb.Children, depth+1)
+	}
+}
+
+func FlattenNotebookTree(roots []*NotebookDTO) []*NotebookDTO {
+	flat := make([]*NotebookDTO, 0)
+	for _, nb := range roots {
+		flat = append(flat, nb)
+		flat = append(flat, FlattenNotebookTree(nb.Children)...)
+	}
+	return flat
+}
+
+func (a App) notebookTree(userID, activeID int64) ([]*NotebookDTO, error) {
+	notebooks, err := a.db.GetNotebooksByUserId(a.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := a.db.GetNotebookNoteCounts(a.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.NotebookID] = row.NotesCount
+	}
+	return BuildNotebookTree(notebooks, counts, activeID), nil
+}
+
+// userNotebook parses a notebook id and checks that it belongs to the user.
+// Empty id means "no notebook" and returns nil without error.
+func (a App) userNotebook(userID int64, idParam string) (*repository.Notebook, error) {
+	idParam = strings.TrimSpace(idParam)
+	if idParam == "" || idParam == "0" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	notebook, err := a.db.GetNotebookById(a.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if notebook.UserID != userID {
+		return nil, errors.New("notebook belongs to another user")
+	}
+	return notebook, nil
+}
+
+func notebookIDPtr(notebook *repository.Notebook) *int64 {
+	if notebook == nil {
+		return nil
+	}
+	return &notebook.ID
+}
+
+func paramUserID(p httprouter.Params) (int64, error) {
+	userIDParam := p.ByName("userID")
+	if userIDParam == "" {
+		return 0, errors.New("требуется параметр 'userID'")
+	}
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		return 0, errors.New("параметр 'userID' невалидный")
+	}
+	return userID, nil
+}
+
+func (a App) ShowNotebooksPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tree, err := a.notebookTree(userID, 0)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tmpl := ParseTemplateFiles(rw, "notebooks.html")
+	type NotebooksPageData struct {
+		Message   string
+		Notebooks []*NotebookDTO
+	}
+	data := NotebooksPageData{p.ByName("message"), FlattenNotebookTree(tree)}
+
+	err = tmpl.ExecuteTemplate(rw, "notebooks", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) CreateNotebook(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	name := strings.TrimSpace(r.FormValue("notebookName"))
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if name == "" {
+		p = append(p, httprouter.Param{Key: "message", Value: "Название блокнота не должно быть пустым!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	parent, err := a.userNotebook(userID, r.FormValue("parentID"))
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Родительский блокнот не найден!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	_, err = a.db.CreateNotebook(a.ctx, repository.CreateNotebookParams{
+		UserID:   userID,
+		ParentID: notebookIDPtr(parent),
+		Name:     name,
+	})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при создании блокнота!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/notebooks", http.StatusSeeOther)
+}
+
+func (a App) RenameNotebook(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	name := strings.TrimSpace(r.FormValue("notebookName"))
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notebook, err := a.userNotebook(userID, p.ByName("id"))
+	if err != nil || notebook == nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	if name == "" {
+		p = append(p, httprouter.Param{Key: "message", Value: "Название блокнота не должно быть пустым!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	err = a.db.RenameNotebook(a.ctx, repository.RenameNotebookParams{Name: name, ID: notebook.ID})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при переименовании блокнота!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/notebooks", http.StatusSeeOther)
+}
+
+func (a App) MoveNotebook(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notebook, err := a.userNotebook(userID, p.ByName("id"))
+	if err != nil || notebook == nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	parent, err := a.userNotebook(userID, r.FormValue("parentID"))
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Родительский блокнот не найден!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	if parent != nil {
+		subtree, err := a.db.GetNotebookSubtreeIds(a.ctx, notebook.ID)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, id := range subtree {
+			if id == parent.ID {
+				p = append(p, httprouter.Param{Key: "message", Value: "Нельзя переместить блокнот внутрь самого себя!"})
+				a.ShowNotebooksPage(rw, r, p)
+				return
+			}
+		}
+	}
+
+	err = a.db.MoveNotebook(a.ctx, repository.MoveNotebookParams{ParentID: notebookIDPtr(parent), ID: notebook.ID})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при перемещении блокнота!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/notebooks", http.StatusSeeOther)
+}
+
+// DeleteNotebook removes the notebook with all nested notebooks; their notes are either
+// moved to another notebook (or out of notebooks) or put into the trash.
+func (a App) DeleteNotebook(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	notesAction := r.FormValue("notesAction")
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notebook, err := a.userNotebook(userID, p.ByName("id"))
+	if err != nil || notebook == nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	var target *repository.Notebook
+	switch notesAction {
+	case "trash":
+	case "move":
+		if target, err = a.userNotebook(userID, r.FormValue("targetID")); err != nil {
+			p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при удалении блокнота!"})
+			a.ShowNotebooksPage(rw, r, p)
+			return
+		}
+	default:
+		p = append(p, httprouter.Param{Key: "message", Value: "Выберите, что сделать с заметками блокнота!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	err = a.inTx(func(q *repository.Queries) error {
+		subtree, err := q.GetNotebookSubtreeIds(a.ctx, notebook.ID)
+		if err != nil {
+			return err
+		}
+		if notesAction == "trash" {
+			_, err = q.TrashNotesInNotebooks(a.ctx, subtree)
+		} else {
+			for _, id := range subtree {
+				if target != nil && id == target.ID {
+					return errMoveIntoDeletedNotebook
+				}
+			}
+			_, err = q.MoveNotesBetweenNotebooks(a.ctx, repository.MoveNotesBetweenNotebooksParams{
+				TargetID:    notebookIDPtr(target),
+				NotebookIds: subtree,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		return q.DeleteNotebookById(a.ctx, notebook.ID)
+	})
+	if errors.Is(err, errMoveIntoDeletedNotebook) {
+		p = append(p, httprouter.Param{Key: "message", Value: "Нельзя переместить заметки в удаляемый блокнот!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при удалении блокнота!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/notebooks", http.StatusSeeOther)
+}
+
+func (a App) ShowTrashPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notes, err := a.db.GetTrashedNotesByUserId(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tmpl := ParseTemplateFiles(rw, "trash.html")
+	type TrashPageData struct {
+		Message string
+		Notes   []*NoteDTO
+	}
+	dtos := make([]*NoteDTO, len(notes))
+	for i := range notes {
+		dtos[i] = MapNote(notes[i])
+	}
+	data := TrashPageData{p.ByName("message"), dtos}
+
+	err = tmpl.ExecuteTemplate(rw, "trash", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) RestoreNote(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	noteID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, _, err = a.authorizeNote(userID, noteID, AccessOwner)
+	if err == nil {
+		err = a.db.RestoreNote(a.ctx, noteID)
+	}
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при восстановлении заметки!"})
+		a.ShowTrashPage(rw, r, p)
+		return
+	}
+	a.publishNoteByID(events.NoteCreated, noteID, userID)
+
+	http.Redirect(rw, r, "/trash", http.StatusSeeOther)
+}
+
+func (a App) TrashNote(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	noteID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, _, err = a.authorizeNote(userID, noteID, AccessOwner)
+	if err == nil {
+		err = a.db.TrashNote(a.ctx, noteID)
+	}
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при перемещении заметки в корзину!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+	a.publishNoteByID(events.NoteDeleted, noteID, userID)
+
+	http.Redirect(rw, r, "/", http.StatusSeeOther)
+}
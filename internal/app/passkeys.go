@@ -0,0 +1,420 @@
+package app
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/utils"
+	"github.com/notjoji/web-notes/internal/webauthn"
+	"github.com/pkg/errors"
+)
+
+const (
+	passkeyCookie     = "webauthn"
+	passkeyTTL        = 5 * time.Minute
+	passkeyNameMaxLen = 50
+
+	passkeyNotVerifiedMessage = "Ключ не проверил PIN-код или биометрию. Настройте их на ключе или войдите с паролем."
+)
+
+// SiteOrigin is the origin the site's pages are served from; WEBAUTHN_ORIGIN
+// overrides the one taken from the request, which is needed behind a proxy.
+func SiteOrigin(r *http.Request) string {
+	if origin := os.Getenv("WEBAUTHN_ORIGIN"); origin != "" {
+		return origin
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// RelyingPartyFor returns the site passkeys are registered for. WEBAUTHN_RP_ID
+// overrides the domain taken from the origin.
+func RelyingPartyFor(r *http.Request) webauthn.RelyingParty {
+	origin := SiteOrigin(r)
+	id := os.Getenv("WEBAUTHN_RP_ID")
+	if id == "" {
+		if u, err := url.Parse(origin); err == nil {
+			id = u.Hostname()
+		}
+	}
+	// a passkey replaces both the password and the second factor, so a touch alone
+	// is not enough
+	return webauthn.RelyingParty{ID: id, Name: totpIssuer, Origin: origin, UserVerification: true}
+}
+
+type PasskeyDTO struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	CreatedAt  string `json:"createdAt"`
+	LastUsedAt string `json:"lastUsedAt,omitempty"`
+}
+
+func MapPasskey(credential *repository.WebauthnCredential) *PasskeyDTO {
+	return &PasskeyDTO{
+		ID:         credential.ID,
+		Name:       credential.Name,
+		CreatedAt:  formatNoteTime(credential.CreatedAt),
+		LastUsedAt: formatNoteTime(credential.LastUsedAt),
+	}
+}
+
+type PasskeyParam struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type PasskeyDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type PasskeyEntity struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type PasskeySelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PasskeyCreationOptions and PasskeyRequestOptions follow PublicKeyCredentialCreationOptions
+// and PublicKeyCredentialRequestOptions with binary fields in base64url.
+type PasskeyCreationOptions struct {
+	Challenge              string              `json:"challenge"`
+	RP                     PasskeyEntity       `json:"rp"`
+	User                   PasskeyEntity       `json:"user"`
+	PubKeyCredParams       []PasskeyParam      `json:"pubKeyCredParams"`
+	ExcludeCredentials     []PasskeyDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection PasskeySelection    `json:"authenticatorSelection"`
+	Attestation            string              `json:"attestation"`
+	Timeout                int64               `json:"timeout"`
+}
+
+type PasskeyRequestOptions struct {
+	Challenge        string `json:"challenge"`
+	RPID             string `json:"rpId"`
+	UserVerification string `json:"userVerification"`
+	Timeout          int64  `json:"timeout"`
+}
+
+type PasskeyRegistration struct {
+	Name              string `json:"name"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AttestationObject string `json:"attestationObject"`
+}
+
+type PasskeyAssertion struct {
+	ID                string `json:"id"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+}
+
+type PasskeyLogin struct {
+	Redirect string `json:"redirect"`
+}
+
+// ValidatePasskeyName trims the name; an empty name is replaced by a default one.
+func ValidatePasskeyName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Ключ доступа"
+	}
+	if utf8.RuneCountInString(name) > passkeyNameMaxLen {
+		return "", "Название не должно превышать " + strconv.Itoa(passkeyNameMaxLen) + " символов!"
+	}
+	return name, ""
+}
+
+// userHandle identifies the account inside the authenticator; it holds no
+// personal data, as the specification requires.
+func userHandle(userID int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
+
+// A passkey ceremony keeps the challenge of a registration or login between the
+// options sent to the browser and its response, in the database, so the response
+// may reach any instance. A challenge is used once; logins are stored with user 0.
+func (a App) beginPasskeyCeremony(rw http.ResponseWriter, userID int64) ([]byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	token, err := utils.GenerateToken(24)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.db.DeleteExpiredPasskeyCeremonies(a.ctx); err != nil {
+		return nil, err
+	}
+	err = a.db.CreatePasskeyCeremony(a.ctx, repository.CreatePasskeyCeremonyParams{
+		TokenHash:  utils.GetHashedString(token),
+		UserID:     userID,
+		Challenge:  challenge,
+		TtlSeconds: passkeyTTL.Seconds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	http.SetCookie(rw, &http.Cookie{
+		Name: passkeyCookie, Value: url.QueryEscape(token), Expires: now.Add(passkeyTTL), Path: "/", HttpOnly: true,
+	})
+	return challenge, nil
+}
+
+func (a App) finishPasskeyCeremony(rw http.ResponseWriter, r *http.Request, userID int64) []byte {
+	token, err := utils.ReadCookie(passkeyCookie, r)
+	if err != nil {
+		return nil
+	}
+	http.SetCookie(rw, &http.Cookie{Name: passkeyCookie, Path: "/", MaxAge: -1})
+	challenge, err := a.db.TakePasskeyCeremony(a.ctx, repository.TakePasskeyCeremonyParams{
+		TokenHash: utils.GetHashedString(token),
+		UserID:    userID,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("passkeys: can't read the challenge: %v", err)
+	}
+	return challenge
+}
+
+func (a App) ShowPasskeysPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	credentials, err := a.db.GetWebauthnCredentialsByUserId(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	passkeys := make([]*PasskeyDTO, len(credentials))
+	for i, credential := range credentials {
+		passkeys[i] = MapPasskey(credential)
+	}
+
+	tmpl := ParseTemplateFiles(rw, "passkeys.html")
+	type PasskeysPageData struct {
+		Message  string
+		Passkeys []*PasskeyDTO
+	}
+	data := PasskeysPageData{p.ByName("message"), passkeys}
+
+	err = tmpl.ExecuteTemplate(rw, "passkeys", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) DeletePasskey(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+	deleted, err := a.db.DeleteWebauthnCredential(a.ctx, repository.DeleteWebauthnCredentialParams{ID: id, UserID: userID})
+	if err != nil || deleted == 0 {
+		p = append(p, httprouter.Param{Key: "message", Value: "Ключ не найден!"})
+		a.ShowPasskeysPage(rw, r, p)
+		return
+	}
+	http.Redirect(rw, r, "/settings/passkeys", http.StatusSeeOther)
+}
+
+func (a App) APIBeginPasskeyRegistration(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	user, err := a.db.GetUserById(a.ctx, userID)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	credentials, err := a.db.GetWebauthnCredentialsByUserId(a.ctx, userID)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	challenge, err := a.beginPasskeyCeremony(rw, userID)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rp := RelyingPartyFor(r)
+	options := PasskeyCreationOptions{
+		Challenge:              webauthn.EncodeBase64(challenge),
+		RP:                     PasskeyEntity{ID: rp.ID, Name: rp.Name},
+		User:                   PasskeyEntity{ID: webauthn.EncodeBase64(userHandle(userID)), Name: user.Login, DisplayName: user.Login},
+		PubKeyCredParams:       []PasskeyParam{{"public-key", webauthn.AlgES256}},
+		ExcludeCredentials:     make([]PasskeyDescriptor, len(credentials)),
+		AuthenticatorSelection: PasskeySelection{ResidentKey: "required", UserVerification: "required"},
+		Attestation:            "none",
+		Timeout:                passkeyTTL.Milliseconds(),
+	}
+	for i, credential := range credentials {
+		options.ExcludeCredentials[i] = PasskeyDescriptor{"public-key", webauthn.EncodeBase64(credential.CredentialID)}
+	}
+	WriteJSON(rw, http.StatusOK, options)
+}
+
+func (a App) APIFinishPasskeyRegistration(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	var req PasskeyRegistration
+	if err = json.NewDecoder(http.MaxBytesReader(rw, r.Body, megabyte)).Decode(&req); err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	name, message := ValidatePasskeyName(req.Name)
+	if message != "" {
+		WriteJSONError(rw, http.StatusBadRequest, message)
+		return
+	}
+	challenge := a.finishPasskeyCeremony(rw, r, userID)
+	if challenge == nil {
+		WriteJSONError(rw, http.StatusBadRequest, "Время регистрации ключа истекло, попробуйте снова.")
+		return
+	}
+	clientData, err := webauthn.DecodeBase64(req.ClientDataJSON)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, "некорректный ответ аутентификатора")
+		return
+	}
+	attestation, err := webauthn.DecodeBase64(req.AttestationObject)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, "некорректный ответ аутентификатора")
+		return
+	}
+	credential, err := RelyingPartyFor(r).VerifyRegistration(challenge, clientData, attestation)
+	if errors.Is(err, webauthn.ErrUserNotVerified) {
+		WriteJSONError(rw, http.StatusBadRequest, passkeyNotVerifiedMessage)
+		return
+	}
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := a.db.CreateWebauthnCredential(a.ctx, repository.CreateWebauthnCredentialParams{
+		UserID:       userID,
+		Name:         name,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+	})
+	if err != nil {
+		WriteJSONError(rw, http.StatusConflict, "Не удалось сохранить ключ: возможно, он уже добавлен.")
+		return
+	}
+	WriteJSON(rw, http.StatusCreated, PasskeyDTO{ID: id, Name: name, CreatedAt: time.Now().Format(layoutDateTime)})
+}
+
+func (a App) APIBeginPasskeyLogin(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	challenge, err := a.beginPasskeyCeremony(rw, 0)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// no allowCredentials: the browser offers the passkeys it has for the site
+	WriteJSON(rw, http.StatusOK, PasskeyRequestOptions{
+		Challenge:        webauthn.EncodeBase64(challenge),
+		RPID:             RelyingPartyFor(r).ID,
+		UserVerification: "required",
+		Timeout:          passkeyTTL.Milliseconds(),
+	})
+}
+
+// APIFinishPasskeyLogin opens a session without asking for a TOTP code: a
+// passkey already proves possession of the device.
+func (a App) APIFinishPasskeyLogin(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req PasskeyAssertion
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, megabyte)).Decode(&req); err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, "некорректный JSON")
+		return
+	}
+	challenge := a.finishPasskeyCeremony(rw, r, 0)
+	if challenge == nil {
+		WriteJSONError(rw, http.StatusBadRequest, "Время входа истекло, попробуйте снова.")
+		return
+	}
+	var fields [4][]byte
+	for i, value := range []string{req.ID, req.ClientDataJSON, req.AuthenticatorData, req.Signature} {
+		decoded, err := webauthn.DecodeBase64(value)
+		if err != nil {
+			WriteJSONError(rw, http.StatusBadRequest, "некорректный ответ аутентификатора")
+			return
+		}
+		fields[i] = decoded
+	}
+	stored, err := a.db.GetWebauthnCredentialByCredentialId(a.ctx, fields[0])
+	if err != nil {
+		WriteJSONError(rw, http.StatusUnauthorized, "Ключ не зарегистрирован.")
+		return
+	}
+	credential := &webauthn.Credential{ID: stored.CredentialID, PublicKey: stored.PublicKey, SignCount: uint32(stored.SignCount)}
+	signCount, err := RelyingPartyFor(r).VerifyAssertion(credential, challenge, fields[1], fields[2], fields[3])
+	if errors.Is(err, webauthn.ErrUserNotVerified) {
+		WriteJSONError(rw, http.StatusUnauthorized, passkeyNotVerifiedMessage)
+		return
+	}
+	if err != nil {
+		WriteJSONError(rw, http.StatusUnauthorized, err.Error())
+		return
+	}
+	updated, err := a.db.UpdateWebauthnCredentialUsage(a.ctx, repository.UpdateWebauthnCredentialUsageParams{
+		SignCount:         int64(signCount),
+		ID:                stored.ID,
+		PreviousSignCount: stored.SignCount,
+	})
+	if err != nil || updated == 0 {
+		WriteJSONError(rw, http.StatusConflict, "Ключ уже использован, попробуйте снова.")
+		return
+	}
+
+	user, err := a.db.GetUserById(a.ctx, stored.UserID)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if user.DisabledAt.Valid {
+		_ = a.auditLogin(user.Login, ClientIP(r), AuditDisabled)
+		WriteJSONError(rw, http.StatusForbidden, "Учётная запись заблокирована администратором!")
+		return
+	}
+	target, err := a.openSession(rw, r, user)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	WriteJSON(rw, http.StatusOK, PasskeyLogin{target})
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/utils"
+)
+
+// passwordPath stays reachable while a password reset is required; AuthNeeded
+// redirects every other page there.
+const passwordPath = "/password"
+
+// ValidatePasswordChange checks the new password against the confirmation and
+// the current password hash.
+func ValidatePasswordChange(currentHash, current, password, confirmPassword string) string {
+	switch {
+	case current == "" || password == "" || confirmPassword == "":
+		return "Все поля должны быть заполнены!"
+	case utils.GetHashedString(current) != currentHash:
+		return "Текущий пароль указан неверно!"
+	case password != confirmPassword:
+		return "Пароли не совпадают!"
+	case password == current:
+		return "Новый пароль должен отличаться от текущего!"
+	}
+	return ""
+}
+
+func (a App) ShowPasswordPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, err := a.db.GetUserById(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tmpl := ParseTemplateFiles(rw, "password.html")
+	type PasswordPageData struct {
+		Message       string
+		ResetRequired bool
+	}
+	data := PasswordPageData{p.ByName("message"), user.PasswordResetRequired}
+
+	err = tmpl.ExecuteTemplate(rw, "password", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) ChangePassword(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, err := a.db.GetUserById(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	password := strings.TrimSpace(r.FormValue("password"))
+	message := ValidatePasswordChange(user.Password, strings.TrimSpace(r.FormValue("currentPassword")),
+		password, strings.TrimSpace(r.FormValue("confirmPassword")))
+	if message != "" {
+		p = append(p, httprouter.Param{Key: "message", Value: message})
+		a.ShowPasswordPage(rw, r, p)
+		return
+	}
+
+	user.Password = utils.GetHashedString(password)
+	user.PasswordResetRequired = false
+	err = a.db.UpdateUserPassword(a.ctx, repository.UpdateUserPasswordParams{
+		ID:       user.ID,
+		Password: user.Password,
+	})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при смене пароля!"})
+		a.ShowPasswordPage(rw, r, p)
+		return
+	}
+	http.Redirect(rw, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/events"
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+type NotePriority string
+
+const (
+	PriorityLow    NotePriority = "low"
+	PriorityNormal NotePriority = "normal"
+	PriorityHigh   NotePriority = "high"
+	PriorityUrgent NotePriority = "urgent"
+)
+
+// priorities are indexed by the value stored in notes.priority.
+var priorities = []NotePriority{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+var priorityLabels = map[NotePriority]string{
+	PriorityLow:    "Низкий",
+	PriorityNormal: "Обычный",
+	PriorityHigh:   "Высокий",
+	PriorityUrgent: "Срочный",
+}
+
+var priorityClasses = map[NotePriority]string{
+	PriorityLow:    "bg-secondary",
+	PriorityNormal: "bg-info text-dark",
+	PriorityHigh:   "bg-warning text-dark",
+	PriorityUrgent: "bg-danger",
+}
+
+func MapPriority(value int16) NotePriority {
+	if value < 0 || int(value) >= len(priorities) {
+		return PriorityNormal
+	}
+	return priorities[value]
+}
+
+func ParsePriority(s string) (int16, bool) {
+	for i, priority := range priorities {
+		if string(priority) == s {
+			return int16(i), true
+		}
+	}
+	return 0, false
+}
+
+func (p NotePriority) Label() string {
+	return priorityLabels[p]
+}
+
+func (p NotePriority) Class() string {
+	return priorityClasses[p]
+}
+
+type PriorityOption struct {
+	Value NotePriority
+	Label string
+}
+
+func PriorityOptions() []PriorityOption {
+	options := make([]PriorityOption, len(priorities))
+	for i, priority := range priorities {
+		options[i] = PriorityOption{priority, priority.Label()}
+	}
+	return options
+}
+
+const (
+	SortByCreated  = "created"
+	SortByPriority = "priority"
+	SortByDeadline = "deadline"
+	SortByUpdated  = "updated"
+)
+
+// SortNotes keeps pinned notes first and orders the rest by the requested key;
+// the incoming order (creation date) is used as a tie-breaker.
+func SortNotes(notes []*repository.Note, by string) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Pinned != notes[j].Pinned {
+			return notes[i].Pinned
+		}
+		switch by {
+		case SortByPriority:
+			return notes[i].Priority > notes[j].Priority
+		case SortByDeadline:
+			if notes[i].DeadlineAt.Valid != notes[j].DeadlineAt.Valid {
+				return notes[i].DeadlineAt.Valid
+			}
+			return notes[i].DeadlineAt.Time.Before(notes[j].DeadlineAt.Time)
+		case SortByUpdated:
+			return notes[i].UpdatedAt.Time.After(notes[j].UpdatedAt.Time)
+		}
+		return false
+	})
+}
+
+func FilterNotesByPriority(notes []*repository.Note, priority NotePriority) []*repository.Note {
+	if priority == "" {
+		return notes
+	}
+	filtered := make([]*repository.Note, 0, len(notes))
+	for _, note := range notes {
+		if MapPriority(note.Priority) == priority {
+			filtered = append(filtered, note)
+		}
+	}
+	return filtered
+}
+
+func priorityFromForm(r *http.Request) int16 {
+	priority, ok := ParsePriority(strings.TrimSpace(r.FormValue("priority")))
+	if !ok {
+		return 1
+	}
+	return priority
+}
+
+func (a App) TogglePinNote(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	noteID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	note, _, err := a.authorizeNote(userID, noteID, AccessOwner)
+	if err == nil {
+		err = a.db.SetNotePinned(a.ctx, repository.SetNotePinnedParams{Pinned: !note.Pinned, ID: noteID})
+	}
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при закреплении заметки!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+	a.publishNoteByID(events.NoteUpdated, noteID, userID)
+
+	http.Redirect(rw, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/utils"
+)
+
+const (
+	layoutDateTime  = "2006-01-02 15:04"
+	publicLinkBytes = 32
+)
+
+type PublicLinkDTO struct {
+	ID          int64  `json:"id"`
+	NoteID      int64  `json:"noteId"`
+	NoteName    string `json:"noteName"`
+	URL         string `json:"url"`
+	HasPassword bool   `json:"hasPassword"`
+	ExpiresAt   string `json:"expiresAt"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+func MapPublicLink(link *repository.GetActivePublicLinksByUserIdRow, baseURL string) *PublicLinkDTO {
+	dto := &PublicLinkDTO{
+		ID:          link.ID,
+		NoteID:      link.NoteID,
+		NoteName:    link.NoteName,
+		URL:         baseURL + "/s/" + link.Token,
+		HasPassword: link.Password != nil,
+		CreatedAt:   link.CreatedAt.Time.Format(layoutDateTime),
+	}
+	if link.ExpiresAt.Valid {
+		dto.ExpiresAt = link.ExpiresAt.Time.Format(layoutDateTime)
+	}
+	return dto
+}
+
+// PublicLinkActive reports whether the link can still be opened at the given moment.
+func PublicLinkActive(link *repository.PublicLink, now time.Time) bool {
+	if link.RevokedAt.Valid {
+		return false
+	}
+	return !link.ExpiresAt.Valid || link.ExpiresAt.Time.After(now)
+}
+
+// HashLinkPassword salts the password with the link token, so equal passwords
+// of different links produce different hashes.
+func HashLinkPassword(token, password string) string {
+	return utils.GetHashedString(token + password)
+}
+
+func CheckLinkPassword(link *repository.PublicLink, password string) bool {
+	if link.Password == nil {
+		return true
+	}
+	hash := HashLinkPassword(link.Token, password)
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(*link.Password)) == 1
+}
+
+var LinkPolicyByToken = LoginPolicy{3, time.Second, 5 * time.Minute, 20, 30 * time.Minute, 24 * time.Hour}
+
+// LinkPasswordKeys returns the limiter keys of a public link password attempt.
+// They are kept apart from the login keys, so guessing a link password does not
+// lock the visitor out of their account.
+func LinkPasswordKeys(token, ip string) []string {
+	return []string{"link:" + token, "link-ip:" + ip}
+}
+
+func linkPasswordKeys(token, ip string) []loginKey {
+	keys := LinkPasswordKeys(token, ip)
+	return []loginKey{{keys[0], LinkPolicyByToken}, {keys[1], LoginPolicyByIP}}
+}
+
+func LinkLockoutMessage(wait time.Duration) string {
+	return fmt.Sprintf("Слишком много неверных паролей. Повторите через %s", formatWait(wait))
+}
+
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func (a App) CreatePublicLink(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	noteID, err := strconv.ParseInt(p.ByName("page"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	note, _, err := a.authorizeNote(userID, noteID, AccessOwner)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Заметка не найдена!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	expiresIn, err := strconv.Atoi(r.FormValue("expiresIn"))
+	if err != nil || expiresIn < 0 {
+		expiresIn = 0
+	}
+	token, err := utils.GenerateToken(publicLinkBytes)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	params := repository.CreatePublicLinkParams{
+		NoteID: note.ID,
+		UserID: userID,
+		Token:  token,
+	}
+	if password := strings.TrimSpace(r.FormValue("linkPassword")); password != "" {
+		hash := HashLinkPassword(token, password)
+		params.Password = &hash
+	}
+	if expiresIn > 0 {
+		params.ExpiresAt = pgtype.Timestamptz{
+			Time:  time.Now().AddDate(0, 0, expiresIn),
+			Valid: true,
+		}
+	}
+
+	_, err = a.db.CreatePublicLink(a.ctx, params)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при создании публичной ссылки!"})
+		r.URL.Path = fmt.Sprintf("/notes/%d", note.ID)
+		a.ShowUpdateNotePage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/publicLinks", http.StatusSeeOther)
+}
+
+func (a App) ShowPublicLinksPage(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	links, err := a.db.GetActivePublicLinksByUserId(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tmpl := ParseTemplateFiles(rw, "publicLinks.html")
+	type PublicLinksPageData struct {
+		Message string
+		Links   []*PublicLinkDTO
+	}
+	dtos := make([]*PublicLinkDTO, len(links))
+	for i := range links {
+		dtos[i] = MapPublicLink(links[i], baseURL(r))
+	}
+	data := PublicLinksPageData{p.ByName("message"), dtos}
+
+	err = tmpl.ExecuteTemplate(rw, "publicLinks", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) RevokePublicLink(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	linkID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := a.db.RevokePublicLink(a.ctx, repository.RevokePublicLinkParams{ID: linkID, UserID: userID})
+	if err != nil || revoked == 0 {
+		p = append(p, httprouter.Param{Key: "message", Value: "Ссылка не найдена!"})
+		a.ShowPublicLinksPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/publicLinks", http.StatusSeeOther)
+}
+
+func (a App) showPublicNote(rw http.ResponseWriter, status int, token, message string, note *NoteDTO, needPassword bool) {
+	tmpl := ParseTemplateFiles(rw, "publicNote.html")
+	type PublicNotePageData struct {
+		Message      string
+		Token        string
+		NeedPassword bool
+		Note         *NoteDTO
+	}
+	data := PublicNotePageData{message, token, needPassword, note}
+
+	rw.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	rw.Header().Set("Referrer-Policy", "no-referrer")
+	rw.WriteHeader(status)
+	err := tmpl.ExecuteTemplate(rw, "publicNote", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+// ShowPublicNote serves /s/:token without authentication; the password, if any,
+// is submitted with POST and checked on every request.
+func (a App) ShowPublicNote(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	token := p.ByName("token")
+
+	link, err := a.db.GetPublicLinkByToken(a.ctx, token)
+	if err != nil || !PublicLinkActive(link, time.Now()) {
+		a.showPublicNote(rw, http.StatusNotFound, "", "Ссылка недействительна или срок её действия истёк!", nil, false)
+		return
+	}
+
+	if link.Password != nil {
+		if r.Method != http.MethodPost {
+			a.showPublicNote(rw, http.StatusOK, token, "", nil, true)
+			return
+		}
+		ip := ClientIP(r)
+		keys := linkPasswordKeys(token, ip)
+		wait, err := a.reserveAttempt(keys)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if wait > 0 {
+			a.showPublicNote(rw, http.StatusTooManyRequests, token, LinkLockoutMessage(wait), nil, true)
+			return
+		}
+		if !CheckLinkPassword(link, r.FormValue("linkPassword")) {
+			a.showPublicNote(rw, http.StatusForbidden, token, "Неверный пароль!", nil, true)
+			return
+		}
+		if err = a.db.ClearLoginAttempts(a.ctx, keys[0].key); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err = a.releaseAttempt(keys[1]); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	note, err := a.db.GetNoteById(a.ctx, link.NoteID)
+	if err != nil || note.TrashedAt.Valid {
+		a.showPublicNote(rw, http.StatusNotFound, "", "Заметка не найдена!", nil, false)
+		return
+	}
+
+	a.showPublicNote(rw, http.StatusOK, token, "", MapNote(note), false)
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/events"
+	"github.com/notjoji/web-notes/internal/recurrence"
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+type RecurrenceForm struct {
+	Freq     string   `json:"freq"`
+	Interval int      `json:"interval"`
+	Weekdays []string `json:"weekdays"`
+	MonthDay int      `json:"monthDay"`
+}
+
+func (f RecurrenceForm) HasWeekday(code string) bool {
+	for _, weekday := range f.Weekdays {
+		if weekday == code {
+			return true
+		}
+	}
+	return false
+}
+
+func MapRecurrenceForm(rule *recurrence.Rule) RecurrenceForm {
+	if rule == nil {
+		return RecurrenceForm{}
+	}
+	weekdays := make([]string, len(rule.Weekdays))
+	for i, weekday := range rule.Weekdays {
+		weekdays[i] = recurrence.WeekdayCode(weekday)
+	}
+	return RecurrenceForm{
+		Freq:     string(rule.Freq),
+		Interval: rule.Interval,
+		Weekdays: weekdays,
+		MonthDay: rule.MonthDay,
+	}
+}
+
+func parseNoteRecurrence(note *repository.Note) *recurrence.Rule {
+	if note.Recurrence == nil {
+		return nil
+	}
+	rule, err := recurrence.Parse(*note.Recurrence)
+	if err != nil {
+		return nil
+	}
+	return rule
+}
+
+func seriesID(note *repository.Note) int64 {
+	if note.SeriesID != nil {
+		return *note.SeriesID
+	}
+	return note.ID
+}
+
+// recurrenceFromForm returns nil rule when the note does not repeat.
+func recurrenceFromForm(r *http.Request) (*recurrence.Rule, error) {
+	freq := strings.TrimSpace(r.FormValue("recurrenceFreq"))
+	if freq == "" {
+		return nil, nil
+	}
+
+	interval := 1
+	if intervalParam := strings.TrimSpace(r.FormValue("recurrenceInterval")); intervalParam != "" {
+		var err error
+		interval, err = strconv.Atoi(intervalParam)
+		if err != nil {
+			return nil, err
+		}
+	}
+	monthDay := 0
+	if monthDayParam := strings.TrimSpace(r.FormValue("recurrenceMonthDay")); monthDayParam != "" {
+		var err error
+		monthDay, err = strconv.Atoi(monthDayParam)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rule := &recurrence.Rule{
+		Freq:     recurrence.Freq(freq),
+		Interval: interval,
+		MonthDay: monthDay,
+	}
+	if rule.Freq == recurrence.Weekly {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		weekdays, err := recurrence.ParseWeekdays(r.Form["recurrenceWeekday"])
+		if err != nil {
+			return nil, err
+		}
+		rule.Weekdays = weekdays
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func ruleString(rule *recurrence.Rule) *string {
+	if rule == nil {
+		return nil
+	}
+	s := rule.String()
+	return &s
+}
+
+// createNextOccurrence schedules the next note of a recurring series unless the series
+// already has an open occurrence (e.g. the note was reopened and completed again).
+// It returns the id of the created note or 0.
+func (a App) createNextOccurrence(q *repository.Queries, note *repository.Note) (int64, error) {
+	rule := parseNoteRecurrence(note)
+	if rule == nil {
+		return 0, nil
+	}
+
+	series := seriesID(note)
+	open, err := q.CountOpenSeriesNotes(a.ctx, repository.CountOpenSeriesNotesParams{
+		SeriesID: series,
+		ID:       note.ID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if open > 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	deadline := now
+	if note.DeadlineAt.Valid {
+		deadline = note.DeadlineAt.Time
+	}
+	id, err := q.CreateNote(a.ctx, repository.CreateNoteParams{
+		UserID:      note.UserID,
+		Name:        note.Name,
+		Description: note.Description,
+		DeadlineAt: pgtype.Date{
+			Time:             rule.Next(deadline, now),
+			InfinityModifier: 0,
+			Valid:            true,
+		},
+		Recurrence: note.Recurrence,
+		SeriesID:   &series,
+		NotebookID: note.NotebookID,
+		Priority:   note.Priority,
+		Pinned:     note.Pinned,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, a.syncReferences(q, id, *note.Description)
+}
+
+func (a App) StopSeries(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	noteIDParam := strings.TrimSpace(r.FormValue("noteID"))
+
+	noteID, err := strconv.ParseInt(noteIDParam, 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'noteID' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	note, _, err := a.authorizeNote(userID, noteID, AccessOwner)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Заметка не найдена!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	_, err = a.db.StopNoteSeries(a.ctx, seriesID(note))
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при остановке серии заметок!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+	a.publishNoteByID(events.NoteUpdated, noteID, userID)
+
+	http.Redirect(rw, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"time"
+
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/utils"
+)
+
+const (
+	sessionTTL        = 60 * time.Minute
+	sessionTokenBytes = 32
+)
+
+// Sessions are kept in the database, so that every instance sees a logout, a
+// revoked session or a change of the account at once.
+
+// SessionKey is stored instead of the token, so a copy of the sessions table
+// can't be used to log in.
+func SessionKey(token string) string {
+	return utils.GetHashedString(token)
+}
+
+func (a App) createSession(userID int64) (string, error) {
+	token, err := utils.GenerateToken(sessionTokenBytes)
+	if err != nil {
+		return "", err
+	}
+	if err = a.db.DeleteExpiredSessions(a.ctx); err != nil {
+		return "", err
+	}
+	err = a.db.CreateSession(a.ctx, repository.CreateSessionParams{
+		TokenHash:  SessionKey(token),
+		UserID:     userID,
+		TtlSeconds: sessionTTL.Seconds(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sessionUser returns the current state of the account logged in with the token.
+func (a App) sessionUser(token string) (*repository.User, error) {
+	return a.db.GetSessionUser(a.ctx, SessionKey(token))
+}
+
+func (a App) deleteSession(token string) error {
+	return a.db.DeleteSession(a.ctx, SessionKey(token))
+}
@@ -0,0 +1,348 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/pkg/errors"
+)
+
+type SharePermission string
+
+const (
+	PermissionView SharePermission = "view"
+	PermissionEdit SharePermission = "edit"
+)
+
+var permissionLabels = map[SharePermission]string{
+	PermissionView: "Просмотр",
+	PermissionEdit: "Редактирование",
+}
+
+func ParseSharePermission(s string) (SharePermission, bool) {
+	permission := SharePermission(s)
+	_, ok := permissionLabels[permission]
+	return permission, ok
+}
+
+func (p SharePermission) Label() string {
+	return permissionLabels[p]
+}
+
+// NoteAccess is ordered so that a higher level includes every lower one.
+type NoteAccess int
+
+const (
+	AccessNone NoteAccess = iota
+	AccessView
+	AccessEdit
+	AccessOwner
+)
+
+var errNoteAccessDenied = errors.New("note is not accessible")
+
+type ShareDTO struct {
+	ID         int64           `json:"id"`
+	Login      string          `json:"login"`
+	Permission SharePermission `json:"permission"`
+}
+
+func MapShare(share *repository.GetSharesByNoteIdRow) *ShareDTO {
+	return &ShareDTO{
+		ID:         share.ID,
+		Login:      share.Login,
+		Permission: SharePermission(share.Permission),
+	}
+}
+
+func MapSharedNote(row *repository.GetNotesSharedWithUserRow) *NoteDTO {
+	dto := MapNote(&repository.Note{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Name:        row.Name,
+		Description: row.Description,
+		IsCompleted: row.IsCompleted,
+		CreatedAt:   row.CreatedAt,
+		DeadlineAt:  row.DeadlineAt,
+		Recurrence:  row.Recurrence,
+		SeriesID:    row.SeriesID,
+		NotebookID:  row.NotebookID,
+		TrashedAt:   row.TrashedAt,
+		Priority:    row.Priority,
+		Pinned:      row.Pinned,
+		Version:     row.Version,
+		UpdatedAt:   row.UpdatedAt,
+		CompletedAt: row.CompletedAt,
+	})
+	dto.Owner = row.OwnerLogin
+	dto.Permission = PermissionView
+	if row.CanEdit {
+		dto.Permission = PermissionEdit
+	}
+	return dto
+}
+
+// noteAccess resolves the user's access to a note: owners have full access, other users
+// need a share of the note itself or of one of the notebooks containing it.
+func (a App) noteAccess(userID int64, note *repository.Note) (NoteAccess, error) {
+	if note.UserID == userID {
+		return AccessOwner, nil
+	}
+	if note.TrashedAt.Valid {
+		return AccessNone, nil
+	}
+	permission, err := a.db.GetNoteSharePermission(a.ctx, repository.GetNoteSharePermissionParams{
+		NoteID: note.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		return AccessNone, err
+	}
+	switch {
+	case permission.CanEdit:
+		return AccessEdit, nil
+	case permission.SharesCount > 0:
+		return AccessView, nil
+	}
+	return AccessNone, nil
+}
+
+func (a App) authorizeNote(userID, noteID int64, required NoteAccess) (*repository.Note, NoteAccess, error) {
+	note, err := a.db.GetNoteById(a.ctx, noteID)
+	if err != nil {
+		return nil, AccessNone, err
+	}
+	access, err := a.noteAccess(userID, note)
+	if err != nil {
+		return nil, AccessNone, err
+	}
+	if access < required {
+		return nil, access, errNoteAccessDenied
+	}
+	return note, access, nil
+}
+
+func (a App) sharedNotes(userID int64) ([]*NoteDTO, error) {
+	rows, err := a.db.GetNotesSharedWithUser(a.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]*NoteDTO, len(rows))
+	for i := range rows {
+		dtos[i] = MapSharedNote(rows[i])
+	}
+	return dtos, nil
+}
+
+func (a App) showSharesPage(rw http.ResponseWriter, p httprouter.Params, title, action string, shares []*ShareDTO) {
+	tmpl := ParseTemplateFiles(rw, "share.html")
+	type SharePageData struct {
+		Message     string
+		Title       string
+		Action      string
+		Shares      []*ShareDTO
+		Permissions []SharePermission
+	}
+	data := SharePageData{p.ByName("message"), title, action, shares, []SharePermission{PermissionView, PermissionEdit}}
+
+	err := tmpl.ExecuteTemplate(rw, "share", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+// shareGrantee validates the share form and returns the user the owner shares with.
+func (a App) shareGrantee(r *http.Request, ownerID int64) (*repository.User, SharePermission, string) {
+	login := strings.TrimSpace(r.FormValue("login"))
+	permission, ok := ParseSharePermission(r.FormValue("permission"))
+	if login == "" || !ok {
+		return nil, "", "Укажите логин пользователя и уровень доступа!"
+	}
+	user, err := a.db.GetUserByLogin(a.ctx, login)
+	if err != nil {
+		return nil, "", "Пользователь не найден!"
+	}
+	if user.ID == ownerID {
+		return nil, "", "Нельзя поделиться с самим собой!"
+	}
+	return user, permission, ""
+}
+
+func (a App) ShowNoteSharesPage(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	noteID, err := strconv.ParseInt(p.ByName("page"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	note, _, err := a.authorizeNote(userID, noteID, AccessOwner)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Заметка не найдена!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	rows, err := a.db.GetSharesByNoteId(a.ctx, &note.ID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shares := make([]*ShareDTO, len(rows))
+	for i := range rows {
+		shares[i] = MapShare(rows[i])
+	}
+
+	title := fmt.Sprintf("Доступ к заметке «%s»", note.Name)
+	a.showSharesPage(rw, p, title, fmt.Sprintf("/notes/%d/share", note.ID), shares)
+}
+
+func (a App) ShareNote(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	noteID, err := strconv.ParseInt(p.ByName("page"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	note, _, err := a.authorizeNote(userID, noteID, AccessOwner)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Заметка не найдена!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	grantee, permission, message := a.shareGrantee(r, userID)
+	if message != "" {
+		p = append(p, httprouter.Param{Key: "message", Value: message})
+		a.ShowNoteSharesPage(rw, r, p)
+		return
+	}
+
+	err = a.db.ShareNote(a.ctx, repository.ShareNoteParams{
+		OwnerID:    userID,
+		UserID:     grantee.ID,
+		NoteID:     &note.ID,
+		Permission: string(permission),
+	})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при открытии доступа к заметке!"})
+		a.ShowNoteSharesPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, fmt.Sprintf("/notes/%d/share", note.ID), http.StatusSeeOther)
+}
+
+func (a App) ShowNotebookSharesPage(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notebook, err := a.userNotebook(userID, p.ByName("id"))
+	if err != nil || notebook == nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Блокнот не найден!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	rows, err := a.db.GetSharesByNotebookId(a.ctx, &notebook.ID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shares := make([]*ShareDTO, len(rows))
+	for i := range rows {
+		shares[i] = MapShare((*repository.GetSharesByNoteIdRow)(rows[i]))
+	}
+
+	title := fmt.Sprintf("Доступ к блокноту «%s»", notebook.Name)
+	a.showSharesPage(rw, p, title, fmt.Sprintf("/notebooks/%d/share", notebook.ID), shares)
+}
+
+func (a App) ShareNotebook(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notebook, err := a.userNotebook(userID, p.ByName("id"))
+	if err != nil || notebook == nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Блокнот не найден!"})
+		a.ShowNotebooksPage(rw, r, p)
+		return
+	}
+
+	grantee, permission, message := a.shareGrantee(r, userID)
+	if message != "" {
+		p = append(p, httprouter.Param{Key: "message", Value: message})
+		a.ShowNotebookSharesPage(rw, r, p)
+		return
+	}
+
+	err = a.db.ShareNotebook(a.ctx, repository.ShareNotebookParams{
+		OwnerID:    userID,
+		UserID:     grantee.ID,
+		NotebookID: &notebook.ID,
+		Permission: string(permission),
+	})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при открытии доступа к блокноту!"})
+		a.ShowNotebookSharesPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, fmt.Sprintf("/notebooks/%d/share", notebook.ID), http.StatusSeeOther)
+}
+
+func (a App) DeleteShare(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shareID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	share, err := a.db.GetShareById(a.ctx, shareID)
+	if err != nil || share.OwnerID != userID {
+		p = append(p, httprouter.Param{Key: "message", Value: "Доступ не найден!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	back := "/"
+	if share.NoteID != nil {
+		back = fmt.Sprintf("/notes/%d/share", *share.NoteID)
+	} else if share.NotebookID != nil {
+		back = fmt.Sprintf("/notebooks/%d/share", *share.NotebookID)
+	}
+
+	err = a.db.DeleteShare(a.ctx, repository.DeleteShareParams{ID: share.ID, OwnerID: userID})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при закрытии доступа!"})
+		a.ShowMainPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, back, http.StatusSeeOther)
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/chart"
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+const (
+	statsWeeks     = 12
+	createdColor   = "#0d6efd"
+	completedColor = "#198754"
+)
+
+type WeekStatDTO struct {
+	Week      string `json:"week"`
+	Created   int64  `json:"created"`
+	Completed int64  `json:"completed"`
+}
+
+type StatBreakdownDTO struct {
+	Name      string `json:"name"`
+	Notes     int64  `json:"notes"`
+	Completed int64  `json:"completed"`
+}
+
+// StatsDTO summarises the notes of a user that are not in the trash. OnTimeRate
+// is the share of completed notes with a deadline finished by the end of its day.
+type StatsDTO struct {
+	Notes                 int64               `json:"notes"`
+	Completed             int64               `json:"completed"`
+	Overdue               int64               `json:"overdue"`
+	CompletedWithDeadline int64               `json:"completedWithDeadline"`
+	CompletedOnTime       int64               `json:"completedOnTime"`
+	OnTimeRate            float64             `json:"onTimeRate"`
+	AvgCompletionSeconds  int64               `json:"avgCompletionSeconds"`
+	Weeks                 []*WeekStatDTO      `json:"weeks"`
+	Tags                  []*StatBreakdownDTO `json:"tags"`
+	Notebooks             []*StatBreakdownDTO `json:"notebooks"`
+}
+
+func (s *StatsDTO) OnTimeLabel() string {
+	if s.CompletedWithDeadline == 0 {
+		return "—"
+	}
+	return fmt.Sprintf("%.0f%%", s.OnTimeRate*100)
+}
+
+func (s *StatsDTO) AvgCompletionLabel() string {
+	if s.Completed == 0 {
+		return "—"
+	}
+	return FormatDuration(time.Duration(s.AvgCompletionSeconds) * time.Second)
+}
+
+// FormatDuration returns a rough human readable duration: minutes, hours and
+// minutes, or days and hours.
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "меньше минуты"
+	case d < time.Hour:
+		return fmt.Sprintf("%d мин.", int(d.Minutes()))
+	case d < 24*time.Hour:
+		hours, minutes := int(d.Hours()), int(d.Minutes())%60
+		if minutes == 0 {
+			return fmt.Sprintf("%d ч.", hours)
+		}
+		return fmt.Sprintf("%d ч. %d мин.", hours, minutes)
+	}
+	days, hours := int(d.Hours())/24, int(d.Hours())%24
+	if hours == 0 {
+		return fmt.Sprintf("%d дн.", days)
+	}
+	return fmt.Sprintf("%d дн. %d ч.", days, hours)
+}
+
+func MapStats(
+	totals *repository.GetNoteCompletionStatsRow, weeks []*repository.GetWeeklyNoteStatsRow,
+	tags []*repository.GetTagNoteStatsRow, notebooks []*repository.GetNotebookNoteStatsRow,
+) *StatsDTO {
+	stats := &StatsDTO{
+		Notes:                 totals.NotesCount,
+		Completed:             totals.CompletedCount,
+		Overdue:               totals.OverdueCount,
+		CompletedWithDeadline: totals.CompletedWithDeadlineCount,
+		CompletedOnTime:       totals.CompletedOnTimeCount,
+		AvgCompletionSeconds:  int64(totals.AvgCompletionSeconds),
+		Weeks:                 make([]*WeekStatDTO, len(weeks)),
+		Tags:                  make([]*StatBreakdownDTO, len(tags)),
+		Notebooks:             make([]*StatBreakdownDTO, len(notebooks)),
+	}
+	if stats.CompletedWithDeadline > 0 {
+		stats.OnTimeRate = float64(stats.CompletedOnTime) / float64(stats.CompletedWithDeadline)
+	}
+	for i, week := range weeks {
+		stats.Weeks[i] = &WeekStatDTO{week.WeekStart.Time.Format(layoutISO), week.CreatedCount, week.CompletedCount}
+	}
+	for i, tag := range tags {
+		stats.Tags[i] = &StatBreakdownDTO{tag.Name, tag.NotesCount, tag.CompletedCount}
+	}
+	for i, notebook := range notebooks {
+		name := "Без блокнота"
+		if notebook.Name != nil {
+			name = *notebook.Name
+		}
+		stats.Notebooks[i] = &StatBreakdownDTO{name, notebook.NotesCount, notebook.CompletedCount}
+	}
+	return stats
+}
+
+func (a App) userStats(userID int64) (*StatsDTO, error) {
+	totals, err := a.db.GetNoteCompletionStats(a.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	weeks, err := a.db.GetWeeklyNoteStats(a.ctx, repository.GetWeeklyNoteStatsParams{
+		Since:  pgtype.Timestamptz{Time: time.Now().AddDate(0, 0, -7*(statsWeeks-1)), Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags, err := a.db.GetTagNoteStats(a.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	notebooks, err := a.db.GetNotebookNoteStats(a.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return MapStats(totals, weeks, tags, notebooks), nil
+}
+
+// WeeklyChart shows notes created and completed per week; weeks are labelled by their Monday.
+func WeeklyChart(weeks []*WeekStatDTO) template.HTML {
+	labels := make([]string, len(weeks))
+	created := chart.Series{Name: "Создано", Color: createdColor, Values: make([]int64, len(weeks))}
+	completed := chart.Series{Name: "Выполнено", Color: completedColor, Values: make([]int64, len(weeks))}
+	for i, week := range weeks {
+		labels[i] = week.Week
+		if day, err := time.Parse(layoutISO, week.Week); err == nil {
+			labels[i] = day.Format("02.01")
+		}
+		created.Values[i] = week.Created
+		completed.Values[i] = week.Completed
+	}
+	return template.HTML(chart.Bars("Заметки по неделям", labels, []chart.Series{created, completed}))
+}
+
+func BreakdownChart(title string, items []*StatBreakdownDTO) template.HTML {
+	labels := make([]string, len(items))
+	values := make([]int64, len(items))
+	for i, item := range items {
+		labels[i] = item.Name
+		values[i] = item.Notes
+	}
+	return template.HTML(chart.HBars(title, labels, values, createdColor))
+}
+
+func (a App) ShowStatsPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stats, err := a.userStats(userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tmpl := ParseTemplateFiles(rw, "stats.html")
+	type StatsPageData struct {
+		Stats          *StatsDTO
+		WeeklyChart    template.HTML
+		TagsChart      template.HTML
+		NotebooksChart template.HTML
+	}
+	data := StatsPageData{
+		Stats:          stats,
+		WeeklyChart:    WeeklyChart(stats.Weeks),
+		TagsChart:      BreakdownChart("Заметки по тегам", stats.Tags),
+		NotebooksChart: BreakdownChart("Заметки по блокнотам", stats.Notebooks),
+	}
+
+	err = tmpl.ExecuteTemplate(rw, "stats", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) APIGetStats(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		WriteJSONError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	stats, err := a.userStats(userID)
+	if err != nil {
+		WriteJSONError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	WriteJSON(rw, http.StatusOK, stats)
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+// fillTags loads the tags of all listed notes with a single query.
+func (a App) fillTags(dtos []*NoteDTO) error {
+	if len(dtos) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(dtos))
+	byID := make(map[int64]*NoteDTO, len(dtos))
+	for i, dto := range dtos {
+		ids[i] = dto.ID
+		byID[dto.ID] = dto
+	}
+
+	rows, err := a.db.GetTagsByNoteIds(a.ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if dto, ok := byID[row.NoteID]; ok {
+			dto.Tags = append(dto.Tags, row.Name)
+		}
+	}
+	return nil
+}
+
+func validateTags(tags []string) string {
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return fmt.Sprintf("Тег не должен быть длиннее %d символов!", maxTagLength)
+		}
+	}
+	return ""
+}
+
+func (a App) addNoteTags(q *repository.Queries, userID, noteID int64, tags []string) error {
+	for _, tag := range tags {
+		tagID, err := q.UpsertTag(a.ctx, repository.UpsertTagParams{UserID: userID, Name: tag})
+		if err != nil {
+			return err
+		}
+		if err = q.AddNoteTag(a.ctx, repository.AddNoteTagParams{NoteID: noteID, TagID: tagID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/importer"
+	"github.com/notjoji/web-notes/internal/notetemplate"
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+const maxTemplateNameLength = 50
+
+type NoteTemplateDTO struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	Tags           string `json:"tags"`
+	DeadlineOffset string `json:"deadlineOffset"`
+	DeadlineLabel  string `json:"deadlineLabel"`
+}
+
+func MapNoteTemplate(t *repository.NoteTemplate) *NoteTemplateDTO {
+	dto := &NoteTemplateDTO{
+		ID:          t.ID,
+		Name:        t.Name,
+		Title:       t.Title,
+		Description: t.Description,
+		Tags:        strings.Join(t.Tags, ", "),
+	}
+	if t.DeadlineOffset != nil {
+		if offset, err := notetemplate.ParseOffset(*t.DeadlineOffset); err == nil {
+			dto.DeadlineOffset = offset.String()
+			dto.DeadlineLabel = offset.Describe()
+		}
+	}
+	return dto
+}
+
+// NoteTemplateForm is a template as entered on the templates page; Validate
+// normalizes the tags and the relative deadline.
+type NoteTemplateForm struct {
+	Name           string
+	Title          string
+	Description    string
+	Tags           []string
+	DeadlineOffset *string
+}
+
+func noteTemplateFromForm(r *http.Request) *NoteTemplateForm {
+	form := &NoteTemplateForm{
+		Name:        strings.TrimSpace(r.FormValue("templateName")),
+		Title:       strings.TrimSpace(r.FormValue("templateTitle")),
+		Description: strings.TrimSpace(r.FormValue("templateDesc")),
+		Tags:        importer.SplitTags(r.FormValue("templateTags")),
+	}
+	if offset := strings.TrimSpace(r.FormValue("templateDeadline")); offset != "" {
+		form.DeadlineOffset = &offset
+	}
+	return form
+}
+
+func (f *NoteTemplateForm) Validate() string {
+	if f.Name == "" {
+		return "Название шаблона не должно быть пустым!"
+	}
+	if utf8.RuneCountInString(f.Name) > maxTemplateNameLength {
+		return fmt.Sprintf("Название шаблона не должно быть длиннее %d символов!", maxTemplateNameLength)
+	}
+	if utf8.RuneCountInString(f.Title) > maxNoteNameLength {
+		return fmt.Sprintf("Название заметки не должно быть длиннее %d символов!", maxNoteNameLength)
+	}
+	if utf8.RuneCountInString(f.Description) > maxNoteDescriptionLength {
+		return fmt.Sprintf("Описание заметки не должно быть длиннее %d символов!", maxNoteDescriptionLength)
+	}
+	if message := validateTags(f.Tags); message != "" {
+		return message
+	}
+	if f.DeadlineOffset != nil {
+		offset, err := notetemplate.ParseOffset(*f.DeadlineOffset)
+		if err != nil {
+			return "Некорректный срок дедлайна! Пример: +3 дня, +1 неделя, +2 месяца"
+		}
+		normalized := offset.String()
+		f.DeadlineOffset = &normalized
+	}
+	return ""
+}
+
+// NoteFromTemplate prefills the create page. Placeholders are kept as is and are
+// expanded when the note is created; the relative deadline is counted from now.
+func NoteFromTemplate(t *repository.NoteTemplate, now time.Time) *NoteCreateDTO {
+	note := &NoteCreateDTO{
+		Name:        t.Title,
+		Description: t.Description,
+		Tags:        strings.Join(t.Tags, ", "),
+		Priority:    PriorityNormal,
+	}
+	if note.Name == "" {
+		note.Name = t.Name
+	}
+	if t.DeadlineOffset != nil {
+		if offset, err := notetemplate.ParseOffset(*t.DeadlineOffset); err == nil {
+			note.Deadline = offset.Apply(now).Format(layoutISO)
+		}
+	}
+	return note
+}
+
+func (a App) noteTemplates(userID int64) ([]*NoteTemplateDTO, error) {
+	templates, err := a.db.GetNoteTemplatesByUserId(a.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]*NoteTemplateDTO, len(templates))
+	for i, t := range templates {
+		dtos[i] = MapNoteTemplate(t)
+	}
+	return dtos, nil
+}
+
+func (a App) templateNameTaken(userID, id int64, name string) (bool, error) {
+	templates, err := a.db.GetNoteTemplatesByUserId(a.ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range templates {
+		if t.ID != id && t.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (a App) ShowTemplatesPage(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	templates, err := a.noteTemplates(userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// a rejected new template is shown again in the create form
+	draft := &NoteTemplateDTO{}
+	if r.Method == http.MethodPost && p.ByName("id") == "" {
+		draft = &NoteTemplateDTO{
+			Name:           r.FormValue("templateName"),
+			Title:          r.FormValue("templateTitle"),
+			Description:    r.FormValue("templateDesc"),
+			Tags:           r.FormValue("templateTags"),
+			DeadlineOffset: r.FormValue("templateDeadline"),
+		}
+	}
+
+	tmpl := ParseTemplateFiles(rw, "templates.html")
+	type TemplatesPageData struct {
+		Message      string
+		Draft        *NoteTemplateDTO
+		Templates    []*NoteTemplateDTO
+		Placeholders []notetemplate.Placeholder
+	}
+	data := TemplatesPageData{p.ByName("message"), draft, templates, notetemplate.Placeholders}
+
+	err = tmpl.ExecuteTemplate(rw, "templates", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) CreateTemplate(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	form := noteTemplateFromForm(r)
+	if message := form.Validate(); message != "" {
+		p = append(p, httprouter.Param{Key: "message", Value: message})
+		a.ShowTemplatesPage(rw, r, p)
+		return
+	}
+	if taken, err := a.templateNameTaken(userID, 0, form.Name); err != nil || taken {
+		p = append(p, httprouter.Param{Key: "message", Value: "Шаблон с таким названием уже существует!"})
+		a.ShowTemplatesPage(rw, r, p)
+		return
+	}
+
+	_, err = a.db.CreateNoteTemplate(a.ctx, repository.CreateNoteTemplateParams{
+		UserID:         userID,
+		Name:           form.Name,
+		Title:          form.Title,
+		Description:    form.Description,
+		Tags:           form.Tags,
+		DeadlineOffset: form.DeadlineOffset,
+	})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при создании шаблона!"})
+		a.ShowTemplatesPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/templates", http.StatusSeeOther)
+}
+
+func (a App) UpdateTemplate(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	form := noteTemplateFromForm(r)
+	if message := form.Validate(); message != "" {
+		p = append(p, httprouter.Param{Key: "message", Value: message})
+		a.ShowTemplatesPage(rw, r, p)
+		return
+	}
+	if taken, err := a.templateNameTaken(userID, id, form.Name); err != nil || taken {
+		p = append(p, httprouter.Param{Key: "message", Value: "Шаблон с таким названием уже существует!"})
+		a.ShowTemplatesPage(rw, r, p)
+		return
+	}
+
+	updated, err := a.db.UpdateNoteTemplate(a.ctx, repository.UpdateNoteTemplateParams{
+		Name:           form.Name,
+		Title:          form.Title,
+		Description:    form.Description,
+		Tags:           form.Tags,
+		DeadlineOffset: form.DeadlineOffset,
+		ID:             id,
+		UserID:         userID,
+	})
+	if err != nil || updated == 0 {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при сохранении шаблона!"})
+		a.ShowTemplatesPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/templates", http.StatusSeeOther)
+}
+
+func (a App) DeleteTemplate(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	if _, err = a.db.DeleteNoteTemplate(a.ctx, repository.DeleteNoteTemplateParams{ID: id, UserID: userID}); err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при удалении шаблона!"})
+		a.ShowTemplatesPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/templates", http.StatusSeeOther)
+}
@@ -0,0 +1,387 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/qrcode"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/totp"
+	"github.com/notjoji/web-notes/internal/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	totpIssuer         = "Web Notes"
+	secondFactorCookie = "mfa"
+	secondFactorTTL    = 5 * time.Minute
+	recoveryCodeCount  = 10
+	recoveryCodeLen    = 10
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// A pending login remembers a user that entered a correct password until they
+// enter a code from the authenticator app or a recovery code. It is kept in the
+// database, so the code may be checked by any instance.
+func (a App) createPendingLogin(userID int64) (string, error) {
+	token, err := utils.GenerateToken(24)
+	if err != nil {
+		return "", err
+	}
+	if err = a.db.DeleteExpiredPendingLogins(a.ctx); err != nil {
+		return "", err
+	}
+	err = a.db.CreatePendingLogin(a.ctx, repository.CreatePendingLoginParams{
+		TokenHash:  utils.GetHashedString(token),
+		UserID:     userID,
+		TtlSeconds: secondFactorTTL.Seconds(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (a App) pendingLoginUserID(token string) (int64, error) {
+	return a.db.GetPendingLoginUserId(a.ctx, utils.GetHashedString(token))
+}
+
+func (a App) deletePendingLogin(token string) error {
+	return a.db.DeletePendingLogin(a.ctx, utils.GetHashedString(token))
+}
+
+// NewRecoveryCodes returns single-use codes formatted as xxxxx-xxxxx.
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	b := make([]byte, 8)
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.Wrap(err, "generate recovery code")
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:recoveryCodeLen]
+		codes[i] = code[:recoveryCodeLen/2] + "-" + code[recoveryCodeLen/2:]
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode drops the case, spaces and dashes a user may type differently.
+func NormalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
+
+// verifySecondFactor accepts a code from the authenticator app or an unused
+// recovery code. Every code is accepted only once.
+func (a App) verifySecondFactor(user *repository.User, code string) (bool, error) {
+	if user.TotpSecret != nil {
+		key, err := totp.DecodeSecret(*user.TotpSecret)
+		if err != nil {
+			return false, err
+		}
+		if step, ok := totp.Validate(key, code, time.Now()); ok {
+			used, err := a.db.UseTotpCounter(a.ctx, repository.UseTotpCounterParams{
+				ID:              user.ID,
+				TotpLastCounter: int64(step),
+			})
+			return used > 0, err
+		}
+	}
+	used, err := a.db.UseRecoveryCode(a.ctx, repository.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: utils.GetHashedString(NormalizeRecoveryCode(code)),
+	})
+	return used > 0, err
+}
+
+func (a App) replaceRecoveryCodes(userID int64) ([]string, error) {
+	codes, err := NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	err = a.inTx(func(q *repository.Queries) error {
+		if err := q.DeleteRecoveryCodes(a.ctx, userID); err != nil {
+			return err
+		}
+		for _, code := range codes {
+			err := q.CreateRecoveryCode(a.ctx, repository.CreateRecoveryCodeParams{
+				UserID:   userID,
+				CodeHash: utils.GetHashedString(NormalizeRecoveryCode(code)),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return codes, err
+}
+
+func (a App) resetTwoFactor(userID int64) (int64, error) {
+	var updated int64
+	err := a.inTx(func(q *repository.Queries) error {
+		var err error
+		if updated, err = q.ResetUserTotp(a.ctx, userID); err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(a.ctx, userID)
+	})
+	return updated, err
+}
+
+func (a App) askSecondFactor(rw http.ResponseWriter, r *http.Request, user *repository.User) {
+	now := time.Now()
+	token, err := a.createPendingLogin(user.ID)
+	if err != nil {
+		a.ShowLoginPage(rw, "Ошибка авторизации: "+err.Error())
+		return
+	}
+	http.SetCookie(rw, &http.Cookie{
+		Name: secondFactorCookie, Value: url.QueryEscape(token), Expires: now.Add(secondFactorTTL),
+		Path: "/login", HttpOnly: true,
+	})
+	http.Redirect(rw, r, "/login/2fa", http.StatusSeeOther)
+}
+
+func (a App) ShowSecondFactorPage(rw http.ResponseWriter, message string) {
+	tmpl := ParseTemplateFiles(rw, "secondFactor.html")
+	data := PageData{message}
+	err := tmpl.ExecuteTemplate(rw, "secondFactor", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) LoginSecondFactor(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	token, err := utils.ReadCookie(secondFactorCookie, r)
+	if err != nil {
+		a.ShowLoginPage(rw, "Время на ввод кода истекло, войдите снова.")
+		return
+	}
+	userID, err := a.pendingLoginUserID(token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		a.ShowLoginPage(rw, "Время на ввод кода истекло, войдите снова.")
+		return
+	}
+	if err != nil {
+		a.ShowLoginPage(rw, "Ошибка авторизации: "+err.Error())
+		return
+	}
+	user, err := a.db.GetUserById(a.ctx, userID)
+	if err != nil {
+		a.ShowLoginPage(rw, "Ошибка авторизации: "+err.Error())
+		return
+	}
+	ip := ClientIP(r)
+	wait, err := a.reserveLogin(user.Login, ip)
+	if err != nil {
+		a.ShowSecondFactorPage(rw, "Ошибка авторизации: "+err.Error())
+		return
+	}
+	if wait > 0 {
+		_ = a.auditLogin(user.Login, ip, AuditLocked)
+		a.ShowSecondFactorPage(rw, LockoutMessage(wait))
+		return
+	}
+	if user.DisabledAt.Valid {
+		_ = a.deletePendingLogin(token)
+		a.ShowLoginPage(rw, "Учётная запись заблокирована администратором!")
+		return
+	}
+
+	ok, err := a.verifySecondFactor(user, r.FormValue("code"))
+	if err != nil {
+		a.ShowSecondFactorPage(rw, "Ошибка авторизации: "+err.Error())
+		return
+	}
+	if !ok {
+		if err = a.auditLogin(user.Login, ip, AuditWrongCode); err != nil {
+			a.ShowSecondFactorPage(rw, "Ошибка авторизации: "+err.Error())
+			return
+		}
+		a.ShowSecondFactorPage(rw, "Неверный или уже использованный код!")
+		return
+	}
+
+	if err = a.releaseLogin(user.Login, ip); err != nil {
+		a.ShowSecondFactorPage(rw, "Ошибка авторизации: "+err.Error())
+		return
+	}
+	if err = a.deletePendingLogin(token); err != nil {
+		a.ShowSecondFactorPage(rw, "Ошибка авторизации: "+err.Error())
+		return
+	}
+	http.SetCookie(rw, &http.Cookie{Name: secondFactorCookie, Path: "/login", MaxAge: -1})
+	a.startSession(rw, r, user)
+}
+
+type TwoFactorPageData struct {
+	Message       string
+	Enabled       bool
+	Secret        string
+	URI           string
+	QR            template.HTML
+	RecoveryCodes []string
+	RecoveryLeft  int64
+}
+
+// TOTPQRCode draws the enrollment URI on the server, so the secret isn't handed
+// to a script; if the URI doesn't fit, the page only shows the key.
+func TOTPQRCode(uri string) template.HTML {
+	code, err := qrcode.Encode([]byte(uri))
+	if err != nil {
+		return ""
+	}
+	return template.HTML(code.SVG(192))
+}
+
+func (a App) renderTwoFactorPage(rw http.ResponseWriter, userID int64, message string, codes []string) {
+	user, err := a.db.GetUserById(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data := TwoFactorPageData{Message: message, Enabled: user.TotpEnabledAt.Valid, RecoveryCodes: codes}
+	switch {
+	case data.Enabled:
+		data.RecoveryLeft, err = a.db.CountUnusedRecoveryCodes(a.ctx, userID)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case user.TotpSecret != nil:
+		data.Secret = *user.TotpSecret
+		data.URI = totp.URI(totpIssuer, user.Login, data.Secret)
+		data.QR = TOTPQRCode(data.URI)
+	}
+
+	tmpl := ParseTemplateFiles(rw, "twoFactor.html")
+	err = tmpl.ExecuteTemplate(rw, "twoFactor", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) ShowTwoFactorPage(rw http.ResponseWriter, _ *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.renderTwoFactorPage(rw, userID, p.ByName("message"), nil)
+}
+
+func (a App) EnrollTwoFactor(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	secret, err := totp.NewSecret()
+	if err == nil {
+		_, err = a.db.SetUserTotpSecret(a.ctx, repository.SetUserTotpSecretParams{ID: userID, TotpSecret: &secret})
+	}
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при подключении приложения!"})
+		a.ShowTwoFactorPage(rw, r, p)
+		return
+	}
+	http.Redirect(rw, r, "/settings/2fa", http.StatusSeeOther)
+}
+
+func (a App) ConfirmTwoFactor(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, err := a.db.GetUserById(a.ctx, userID)
+	if err != nil || user.TotpSecret == nil {
+		http.Redirect(rw, r, "/settings/2fa", http.StatusSeeOther)
+		return
+	}
+	key, err := totp.DecodeSecret(*user.TotpSecret)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	step, ok := totp.Validate(key, r.FormValue("code"), time.Now())
+	if !ok {
+		p = append(p, httprouter.Param{Key: "message", Value: "Код не подошёл. Проверьте время на телефоне и попробуйте снова."})
+		a.ShowTwoFactorPage(rw, r, p)
+		return
+	}
+	enabled, err := a.db.EnableUserTotp(a.ctx, repository.EnableUserTotpParams{ID: userID, TotpLastCounter: int64(step)})
+	if err != nil || enabled == 0 {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при подключении приложения!"})
+		a.ShowTwoFactorPage(rw, r, p)
+		return
+	}
+	codes, err := a.replaceRecoveryCodes(userID)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при создании кодов восстановления!"})
+		a.ShowTwoFactorPage(rw, r, p)
+		return
+	}
+	a.renderTwoFactorPage(rw, userID, "Двухфакторная аутентификация включена.", codes)
+}
+
+// checkedSecondFactor handles the actions that need a fresh code; it shows the
+// page with an error and returns false if the code is wrong.
+func (a App) checkedSecondFactor(rw http.ResponseWriter, r *http.Request, p httprouter.Params, userID int64) bool {
+	user, err := a.db.GetUserById(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	ok, err := a.verifySecondFactor(user, r.FormValue("code"))
+	if err != nil || !ok {
+		p = append(p, httprouter.Param{Key: "message", Value: "Неверный или уже использованный код!"})
+		a.ShowTwoFactorPage(rw, r, p)
+		return false
+	}
+	return true
+}
+
+func (a App) DisableTwoFactor(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !a.checkedSecondFactor(rw, r, p, userID) {
+		return
+	}
+	if _, err = a.resetTwoFactor(userID); err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при отключении!"})
+		a.ShowTwoFactorPage(rw, r, p)
+		return
+	}
+	http.Redirect(rw, r, "/settings/2fa", http.StatusSeeOther)
+}
+
+func (a App) RegenerateRecoveryCodes(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !a.checkedSecondFactor(rw, r, p, userID) {
+		return
+	}
+	codes, err := a.replaceRecoveryCodes(userID)
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при создании кодов восстановления!"})
+		a.ShowTwoFactorPage(rw, r, p)
+		return
+	}
+	a.renderTwoFactorPage(rw, userID, "Старые коды восстановления больше не действуют.", codes)
+}
@@ -0,0 +1,531 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/notjoji/web-notes/internal/repository"
+	"github.com/notjoji/web-notes/internal/webhook"
+	"github.com/pkg/errors"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryRunning   = "running"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+
+	maxWebhookURLLength    = 2048
+	maxWebhookSecretLength = 100
+	maxWebhookErrorLength  = 255
+
+	// webhookLease is how long a claimed delivery belongs to the instance sending
+	// it; it is well above the request timeout, so only deliveries of a stopped
+	// instance are taken over.
+	webhookLease = 5 * time.Minute
+)
+
+var webhookEventLabels = map[string]string{
+	webhook.EventNoteCreated:   "Заметка создана",
+	webhook.EventNoteCompleted: "Заметка завершена",
+	webhook.EventNoteOverdue:   "Заметка просрочена",
+	webhook.EventTest:          "Тестовое событие",
+}
+
+var webhookDeliveryStatusLabels = map[string]string{
+	WebhookDeliveryPending:   "Ожидает отправки",
+	WebhookDeliveryRunning:   "Отправляется",
+	WebhookDeliveryDelivered: "Доставлено",
+	WebhookDeliveryFailed:    "Не доставлено",
+}
+
+type WebhookEventOption struct {
+	Value string
+	Label string
+}
+
+func WebhookEventOptions() []WebhookEventOption {
+	options := make([]WebhookEventOption, len(webhook.Events))
+	for i, event := range webhook.Events {
+		options[i] = WebhookEventOption{event, webhookEventLabels[event]}
+	}
+	return options
+}
+
+type WebhookDTO struct {
+	ID        int64    `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Labels    []string `json:"labels"`
+	Secret    string   `json:"secret"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+func MapWebhook(w *repository.Webhook) *WebhookDTO {
+	labels := make([]string, len(w.Events))
+	for i, event := range w.Events {
+		labels[i] = webhookEventLabels[event]
+	}
+	return &WebhookDTO{
+		ID:        w.ID,
+		URL:       w.Url,
+		Events:    w.Events,
+		Labels:    labels,
+		Secret:    w.Secret,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt.Time.Format(layoutDateTime),
+	}
+}
+
+type WebhookDeliveryDTO struct {
+	ID             int64  `json:"id"`
+	URL            string `json:"url"`
+	Event          string `json:"event"`
+	EventLabel     string `json:"eventLabel"`
+	Status         string `json:"status"`
+	StatusLabel    string `json:"statusLabel"`
+	Attempts       int32  `json:"attempts"`
+	ResponseStatus int32  `json:"responseStatus,omitempty"`
+	Error          string `json:"error,omitempty"`
+	CreatedAt      string `json:"createdAt"`
+	NextAttemptAt  string `json:"nextAttemptAt,omitempty"`
+}
+
+func MapWebhookDelivery(d *repository.GetWebhookDeliveriesByUserIdRow) *WebhookDeliveryDTO {
+	dto := &WebhookDeliveryDTO{
+		ID:          d.ID,
+		URL:         d.Url,
+		Event:       d.Event,
+		EventLabel:  webhookEventLabels[d.Event],
+		Status:      d.Status,
+		StatusLabel: webhookDeliveryStatusLabels[d.Status],
+		Attempts:    d.Attempts,
+		CreatedAt:   d.CreatedAt.Time.Format(layoutDateTime),
+	}
+	if d.ResponseStatus != nil {
+		dto.ResponseStatus = *d.ResponseStatus
+	}
+	if d.Error != nil {
+		dto.Error = *d.Error
+	}
+	if d.Status == WebhookDeliveryPending && d.Attempts > 0 {
+		dto.NextAttemptAt = d.NextAttemptAt.Time.Format(layoutDateTime)
+	}
+	return dto
+}
+
+func (d *WebhookDeliveryDTO) Failed() bool {
+	return d.Status == WebhookDeliveryFailed
+}
+
+// WebhookForm is a subscription as entered on the webhooks page; an empty secret
+// is generated by Validate.
+type WebhookForm struct {
+	URL    string
+	Events []string
+	Secret string
+}
+
+func webhookFromForm(r *http.Request) *WebhookForm {
+	_ = r.ParseForm()
+	return &WebhookForm{
+		URL:    strings.TrimSpace(r.FormValue("webhookURL")),
+		Events: r.Form["webhookEvents"],
+		Secret: strings.TrimSpace(r.FormValue("webhookSecret")),
+	}
+}
+
+func (f *WebhookForm) Has(event string) bool {
+	for _, e := range f.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *WebhookForm) Validate() string {
+	if f.URL == "" {
+		return "Адрес вебхука не должен быть пустым!"
+	}
+	if utf8.RuneCountInString(f.URL) > maxWebhookURLLength || webhook.ValidateURL(f.URL) != nil {
+		return "Адрес вебхука должен быть ссылкой вида http(s)://..."
+	}
+	if len(f.Events) == 0 {
+		return "Выберите хотя бы одно событие!"
+	}
+	for _, event := range f.Events {
+		if _, ok := webhookEventLabels[event]; !ok || event == webhook.EventTest {
+			return "Неизвестное событие!"
+		}
+	}
+	if utf8.RuneCountInString(f.Secret) > maxWebhookSecretLength {
+		return fmt.Sprintf("Секрет не должен быть длиннее %d символов!", maxWebhookSecretLength)
+	}
+	if f.Secret == "" {
+		f.Secret = webhook.NewSecret()
+	}
+	return ""
+}
+
+type WebhookPayload struct {
+	Event     string   `json:"event"`
+	CreatedAt string   `json:"createdAt"`
+	Note      *NoteDTO `json:"note,omitempty"`
+}
+
+// enqueueWebhooks queues the event for the note owner's webhooks; the deliveries
+// are sent by RunWebhooks.
+func (a App) enqueueWebhooks(ctx context.Context, event string, noteID int64) {
+	hooks, err := a.db.GetNoteWebhooks(ctx, repository.GetNoteWebhooksParams{NoteID: noteID, Event: event})
+	if err != nil {
+		log.Printf("webhook: hooks of note %d: %v", noteID, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	note, err := a.db.GetNoteById(ctx, noteID)
+	if err != nil {
+		log.Printf("webhook: note %d: %v", noteID, err)
+		return
+	}
+	payload, err := json.Marshal(WebhookPayload{
+		Event:     event,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Note:      MapNote(note),
+	})
+	if err != nil {
+		log.Printf("webhook: payload of note %d: %v", noteID, err)
+		return
+	}
+	for _, hook := range hooks {
+		_, err = a.db.CreateWebhookDelivery(ctx, repository.CreateWebhookDeliveryParams{
+			WebhookID: hook.ID,
+			Event:     event,
+			Payload:   string(payload),
+		})
+		if err != nil {
+			log.Printf("webhook: queue %s for webhook %d: %v", event, hook.ID, err)
+		}
+	}
+}
+
+// RunWebhooks sends queued deliveries and queues the notes that became overdue.
+// Failed attempts are retried with a growing delay up to webhook.MaxAttempts.
+func (a App) RunWebhooks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.reclaimWebhookDeliveries(ctx)
+		a.enqueueOverdueWebhooks(ctx)
+		a.processWebhookDeliveries(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reclaimWebhookDeliveries queues again the deliveries whose lease expired: the
+// instance sending them stopped before recording the result.
+func (a App) reclaimWebhookDeliveries(ctx context.Context) {
+	if n, err := a.db.ReclaimWebhookDeliveries(ctx, webhookLease.Seconds()); err != nil {
+		log.Println("webhook: can't requeue deliveries:", err)
+	} else if n > 0 {
+		log.Printf("webhook: %d interrupted deliveries queued again", n)
+	}
+}
+
+func (a App) enqueueOverdueWebhooks(ctx context.Context) {
+	notes, err := a.db.GetOverdueNotesForWebhooks(ctx)
+	if err != nil {
+		log.Println("webhook: can't get overdue notes:", err)
+		return
+	}
+	for _, note := range notes {
+		// another instance may have queued the note already
+		marked, err := a.db.MarkNoteOverdueSent(ctx, repository.MarkNoteOverdueSentParams{
+			NoteID:     note.ID,
+			DeadlineAt: note.DeadlineAt,
+		})
+		if err != nil {
+			log.Printf("webhook: can't mark note %d overdue: %v", note.ID, err)
+			continue
+		}
+		if marked > 0 {
+			a.enqueueWebhooks(ctx, webhook.EventNoteOverdue, note.ID)
+		}
+	}
+}
+
+func (a App) processWebhookDeliveries(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivery, err := a.db.ClaimWebhookDelivery(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return
+		}
+		if err != nil {
+			log.Println("webhook: can't claim delivery:", err)
+			return
+		}
+		hook, err := a.db.GetWebhookById(ctx, delivery.WebhookID)
+		if err != nil {
+			log.Printf("webhook: webhook of delivery %d: %v", delivery.ID, err)
+			continue
+		}
+		result := &webhook.Result{Err: errors.New("webhook is disabled")}
+		if hook.Active {
+			result = a.sendWebhook(ctx, hook, delivery)
+		}
+		if err = a.recordWebhookResult(ctx, delivery, result, hook.Active); err != nil {
+			log.Printf("webhook: can't record delivery %d: %v", delivery.ID, err)
+		}
+	}
+}
+
+func (a App) sendWebhook(ctx context.Context, hook *repository.Webhook, delivery *repository.WebhookDelivery) *webhook.Result {
+	return a.webhooks.Send(ctx, &webhook.Request{
+		URL:        hook.Url,
+		Secret:     hook.Secret,
+		Event:      delivery.Event,
+		DeliveryID: delivery.ID,
+		Body:       []byte(delivery.Payload),
+	})
+}
+
+func (a App) recordWebhookResult(ctx context.Context, delivery *repository.WebhookDelivery, result *webhook.Result, retry bool) error {
+	var status *int32
+	if result.Status != 0 {
+		s := int32(result.Status)
+		status = &s
+	}
+	if result.OK() {
+		return a.db.FinishWebhookDelivery(ctx, repository.FinishWebhookDeliveryParams{ID: delivery.ID, ResponseStatus: status})
+	}
+
+	message := result.Err.Error()
+	if len(message) > maxWebhookErrorLength {
+		message = strings.ToValidUTF8(message[:maxWebhookErrorLength], "")
+	}
+	attempts := int(delivery.Attempts) + 1
+	if !retry || attempts >= webhook.MaxAttempts {
+		return a.db.FailWebhookDelivery(ctx, repository.FailWebhookDeliveryParams{
+			ResponseStatus: status,
+			Error:          &message,
+			ID:             delivery.ID,
+		})
+	}
+	return a.db.RetryWebhookDelivery(ctx, repository.RetryWebhookDeliveryParams{
+		ResponseStatus: status,
+		Error:          &message,
+		NextAttemptAt:  pgtype.Timestamptz{Time: time.Now().Add(webhook.Backoff(attempts)), Valid: true},
+		ID:             delivery.ID,
+	})
+}
+
+func (a App) ShowWebhooksPage(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hooks, err := a.db.GetWebhooksByUserId(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	deliveries, err := a.db.GetWebhookDeliveriesByUserId(a.ctx, userID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	webhooks := make([]*WebhookDTO, len(hooks))
+	for i, hook := range hooks {
+		webhooks[i] = MapWebhook(hook)
+	}
+	history := make([]*WebhookDeliveryDTO, len(deliveries))
+	for i, delivery := range deliveries {
+		history[i] = MapWebhookDelivery(delivery)
+	}
+
+	// a rejected new webhook is shown again in the create form
+	draft := &WebhookForm{}
+	if r.Method == http.MethodPost && p.ByName("id") == "" {
+		draft = webhookFromForm(r)
+	}
+
+	tmpl := ParseTemplateFiles(rw, "webhooks.html")
+	type WebhooksPageData struct {
+		Message    string
+		Draft      *WebhookForm
+		Events     []WebhookEventOption
+		Webhooks   []*WebhookDTO
+		Deliveries []*WebhookDeliveryDTO
+	}
+	data := WebhooksPageData{p.ByName("message"), draft, WebhookEventOptions(), webhooks, history}
+
+	err = tmpl.ExecuteTemplate(rw, "webhooks", data)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (a App) CreateWebhook(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	form := webhookFromForm(r)
+	if message := form.Validate(); message != "" {
+		p = append(p, httprouter.Param{Key: "message", Value: message})
+		a.ShowWebhooksPage(rw, r, p)
+		return
+	}
+
+	_, err = a.db.CreateWebhook(a.ctx, repository.CreateWebhookParams{
+		UserID: userID,
+		Url:    form.URL,
+		Events: form.Events,
+		Secret: form.Secret,
+	})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при создании вебхука!"})
+		a.ShowWebhooksPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/webhooks", http.StatusSeeOther)
+}
+
+func (a App) ownWebhook(userID int64, idParam string) (*repository.Webhook, error) {
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	hook, err := a.db.GetWebhookById(a.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hook.UserID != userID {
+		return nil, errNoteAccessDenied
+	}
+	return hook, nil
+}
+
+func (a App) ToggleWebhook(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hook, err := a.ownWebhook(userID, p.ByName("id"))
+	if err == nil {
+		_, err = a.db.SetWebhookActive(a.ctx, repository.SetWebhookActiveParams{Active: !hook.Active, ID: hook.ID, UserID: userID})
+	}
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при изменении вебхука!"})
+		a.ShowWebhooksPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/webhooks", http.StatusSeeOther)
+}
+
+func (a App) DeleteWebhook(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	if _, err = a.db.DeleteWebhook(a.ctx, repository.DeleteWebhookParams{ID: id, UserID: userID}); err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при удалении вебхука!"})
+		a.ShowWebhooksPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/webhooks", http.StatusSeeOther)
+}
+
+// TestWebhook sends a test event right away; it is logged like other deliveries
+// but is not retried.
+func (a App) TestWebhook(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hook, err := a.ownWebhook(userID, p.ByName("id"))
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Вебхук не найден!"})
+		a.ShowWebhooksPage(rw, r, p)
+		return
+	}
+
+	payload, _ := json.Marshal(WebhookPayload{Event: webhook.EventTest, CreatedAt: time.Now().Format(time.RFC3339)})
+	delivery, err := a.db.CreateWebhookDelivery(a.ctx, repository.CreateWebhookDeliveryParams{
+		WebhookID: hook.ID,
+		Event:     webhook.EventTest,
+		Payload:   string(payload),
+	})
+	if err != nil {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при отправке тестового события!"})
+		a.ShowWebhooksPage(rw, r, p)
+		return
+	}
+	result := a.sendWebhook(r.Context(), hook, delivery)
+	if err = a.recordWebhookResult(a.ctx, delivery, result, false); err != nil {
+		log.Printf("webhook: can't record delivery %d: %v", delivery.ID, err)
+	}
+
+	message := fmt.Sprintf("Тестовое событие доставлено, ответ %d", result.Status)
+	if !result.OK() {
+		message = "Не удалось доставить тестовое событие: " + result.Err.Error()
+	}
+	p = append(p, httprouter.Param{Key: "message", Value: message})
+	a.ShowWebhooksPage(rw, r, p)
+}
+
+func (a App) RetryWebhookDelivery(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	userID, err := paramUserID(p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(rw, "параметр 'id' невалидный", http.StatusBadRequest)
+		return
+	}
+
+	requeued, err := a.db.RequeueWebhookDelivery(a.ctx, repository.RequeueWebhookDeliveryParams{ID: id, UserID: userID})
+	if err != nil || requeued == 0 {
+		p = append(p, httprouter.Param{Key: "message", Value: "Возникла ошибка при повторной отправке!"})
+		a.ShowWebhooksPage(rw, r, p)
+		return
+	}
+
+	http.Redirect(rw, r, "/webhooks", http.StatusSeeOther)
+}
@@ -0,0 +1,105 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps attachment contents outside the database. Keys are slash-separated
+// paths generated by the application and never come from user input.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns the blob contents starting from offset up to the end.
+	Get(ctx context.Context, key string, offset int64) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromEnv builds the store selected by BLOB_STORE: "s3" for an S3-compatible
+// service configured by S3_* variables, local filesystem under BLOB_DIR otherwise.
+func NewFromEnv() (BlobStore, error) {
+	if os.Getenv("BLOB_STORE") == "s3" {
+		return NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	}
+	dir := os.Getenv("BLOB_DIR")
+	if dir == "" {
+		dir = "data/blobs"
+	}
+	return NewLocal(dir)
+}
+
+// ReadSeeker adapts a BlobStore blob of known size to io.ReadSeeker, so it can be
+// served with http.ServeContent: every seek reopens the blob from the new offset,
+// which S3 turns into a ranged GET.
+type ReadSeeker struct {
+	ctx    context.Context
+	store  BlobStore
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func NewReadSeeker(ctx context.Context, store BlobStore, key string, size int64) *ReadSeeker {
+	return &ReadSeeker{ctx: ctx, store: store, key: key, size: size}
+}
+
+func (rs *ReadSeeker) Read(p []byte) (int, error) {
+	if rs.offset >= rs.size {
+		return 0, io.EOF
+	}
+	if rs.body == nil {
+		body, err := rs.store.Get(rs.ctx, rs.key, rs.offset)
+		if err != nil {
+			return 0, err
+		}
+		rs.body = body
+	}
+	n, err := rs.body.Read(p)
+	rs.offset += int64(n)
+	return n, err
+}
+
+func (rs *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var next int64
+	switch whence {
+	case io.SeekStart:
+		next = offset
+	case io.SeekCurrent:
+		next = rs.offset + offset
+	case io.SeekEnd:
+		next = rs.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if next < 0 {
+		return 0, errors.New("negative position")
+	}
+	if next != rs.offset {
+		rs.closeBody()
+		rs.offset = next
+	}
+	return next, nil
+}
+
+func (rs *ReadSeeker) Close() error {
+	rs.closeBody()
+	return nil
+}
+
+func (rs *ReadSeeker) closeBody() {
+	if rs.body != nil {
+		_ = rs.body.Close()
+		rs.body = nil
+	}
+}
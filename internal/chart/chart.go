@@ -0,0 +1,133 @@
+// Package chart renders small SVG charts on the server, so that the pages
+// showing them need no JavaScript.
+package chart
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const (
+	width       = 640
+	barsHeight  = 240
+	axisWidth   = 36
+	legendSpace = 28
+	labelSpace  = 24
+	rowHeight   = 26
+	labelWidth  = 180
+	valueSpace  = 48
+	fontSize    = 12
+)
+
+type Series struct {
+	Name   string
+	Color  string
+	Values []int64
+}
+
+// niceCeil rounds the maximum up to 1, 2 or 5 times a power of ten, so that
+// the grid lines get round values.
+func niceCeil(value int64) int64 {
+	if value < 1 {
+		return 1
+	}
+	for scale := int64(1); ; scale *= 10 {
+		for _, step := range []int64{1, 2, 5} {
+			if step*scale >= value {
+				return step * scale
+			}
+		}
+	}
+}
+
+func begin(b *strings.Builder, height int, title string) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%" role="img" `+
+		`font-family="sans-serif" font-size="%d"><title>%s</title>`, width, height, fontSize, html.EscapeString(title))
+}
+
+// Bars renders a grouped bar chart: a group of bars per label and a bar per series.
+func Bars(title string, labels []string, series []Series) string {
+	var top int64
+	for _, s := range series {
+		for _, value := range s.Values {
+			top = max(top, value)
+		}
+	}
+	top = niceCeil(top)
+
+	var b strings.Builder
+	begin(&b, barsHeight, title)
+
+	plotTop, plotBottom := legendSpace, barsHeight-labelSpace
+	plotHeight := float64(plotBottom - plotTop)
+	y := func(value int64) float64 {
+		return float64(plotBottom) - plotHeight*float64(value)/float64(top)
+	}
+	for _, tick := range []int64{0, top / 2, top} {
+		if tick == top/2 && top%2 != 0 {
+			continue
+		}
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#dee2e6"/>`, axisWidth, y(tick), width, y(tick))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" fill="#6c757d">%d</text>`, axisWidth-6, y(tick)+4, tick)
+	}
+
+	x := axisWidth + 4
+	for _, s := range series {
+		fmt.Fprintf(&b, `<rect x="%d" y="8" width="12" height="12" fill="%s"/>`, x, html.EscapeString(s.Color))
+		fmt.Fprintf(&b, `<text x="%d" y="18">%s</text>`, x+16, html.EscapeString(s.Name))
+		x += 24 + len([]rune(s.Name))*7
+	}
+
+	if len(labels) > 0 && len(series) > 0 {
+		groupWidth := float64(width-axisWidth) / float64(len(labels))
+		barWidth := groupWidth * 0.8 / float64(len(series))
+		for i, label := range labels {
+			groupX := float64(axisWidth) + groupWidth*float64(i)
+			for j, s := range series {
+				var value int64
+				if i < len(s.Values) {
+					value = s.Values[i]
+				}
+				barX := groupX + groupWidth*0.1 + barWidth*float64(j)
+				fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s, %s: %d</title></rect>`,
+					barX, y(value), barWidth, float64(plotBottom)-y(value), html.EscapeString(s.Color),
+					html.EscapeString(s.Name), html.EscapeString(label), value)
+			}
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" fill="#6c757d">%s</text>`,
+				groupX+groupWidth/2, barsHeight-6, html.EscapeString(label))
+		}
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
+
+// HBars renders a horizontal bar per label with its value after the bar.
+func HBars(title string, labels []string, values []int64, color string) string {
+	var top int64
+	for _, value := range values {
+		top = max(top, value)
+	}
+	top = max(top, 1)
+
+	var b strings.Builder
+	height := max(len(labels), 1) * rowHeight
+	begin(&b, height, title)
+	barSpace := float64(width - labelWidth - valueSpace)
+	for i, label := range labels {
+		var value int64
+		if i < len(values) {
+			value = values[i]
+		}
+		rowY := i * rowHeight
+		barWidth := barSpace * float64(value) / float64(top)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`,
+			labelWidth-8, rowY+rowHeight/2+4, html.EscapeString(label))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%d" fill="%s"/>`,
+			labelWidth, rowY+4, barWidth, rowHeight-8, html.EscapeString(color))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" fill="#6c757d">%d</text>`,
+			float64(labelWidth)+barWidth+6, rowY+rowHeight/2+4, value)
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
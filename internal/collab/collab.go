@@ -0,0 +1,133 @@
+// Package collab merges concurrent edits of a text with operational transformation.
+// Every edit is a single replace operation; the server orders them, so only
+// transformation against the server history is needed (a Jupiter-style scheme).
+package collab
+
+import (
+	"github.com/pkg/errors"
+)
+
+const maxHistory = 1000
+
+var (
+	ErrInvalidOp   = errors.New("collab: operation is out of range")
+	ErrStaleClient = errors.New("collab: revision is no longer in history")
+	ErrTooLong     = errors.New("collab: text is too long")
+)
+
+// Op replaces Del runes starting at Pos with Ins. Positions count runes.
+type Op struct {
+	Pos int    `json:"pos"`
+	Del int    `json:"del"`
+	Ins string `json:"ins"`
+}
+
+func (o Op) end() int {
+	return o.Pos + o.Del
+}
+
+func (o Op) insLen() int {
+	return len([]rune(o.Ins))
+}
+
+func (o Op) IsNoop() bool {
+	return o.Del == 0 && o.Ins == ""
+}
+
+// Apply returns the text with the operation applied.
+func (o Op) Apply(text []rune) ([]rune, error) {
+	if o.Pos < 0 || o.Del < 0 || o.end() > len(text) {
+		return nil, ErrInvalidOp
+	}
+	result := make([]rune, 0, len(text)-o.Del+len(o.Ins))
+	result = append(result, text[:o.Pos]...)
+	result = append(result, []rune(o.Ins)...)
+	return append(result, text[o.end():]...), nil
+}
+
+// Diff returns one operation turning from into to: the text between their common
+// prefix and suffix is replaced.
+func Diff(from, to string) Op {
+	a, b := []rune(from), []rune(to)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return Op{Pos: prefix, Del: len(a) - prefix - suffix, Ins: string(b[prefix : len(b)-suffix])}
+}
+
+// Transform returns a rewritten so that it can be applied after b; both were made
+// against the same text. When the ranges overlap, the union is replaced by both
+// insertions, the one with priority first. Transform(a, b, p) and Transform(b, a, !p)
+// lead to the same text.
+func Transform(a, b Op, aFirst bool) Op {
+	shift := b.insLen() - b.Del
+	switch {
+	case a.Del == 0 && b.Del == 0 && a.Pos == b.Pos:
+		if aFirst {
+			return a
+		}
+		return Op{Pos: a.Pos + shift, Ins: a.Ins}
+	case a.end() <= b.Pos:
+		return a
+	case a.Pos >= b.end():
+		return Op{Pos: a.Pos + shift, Del: a.Del, Ins: a.Ins}
+	}
+
+	start, end := min(a.Pos, b.Pos), max(a.end(), b.end())
+	ins := b.Ins + a.Ins
+	if aFirst {
+		ins = a.Ins + b.Ins
+	}
+	return Op{Pos: start, Del: end - start + shift, Ins: ins}
+}
+
+// Document is the server copy of a text. It keeps the last operations so that
+// editors lagging behind by up to maxHistory revisions can still be merged.
+type Document struct {
+	Rev     int
+	Text    []rune
+	limit   int
+	base    int
+	history []Op
+}
+
+// NewDocument returns a document of at most limit runes; 0 means no limit.
+func NewDocument(text string, limit int) *Document {
+	return &Document{Text: []rune(text), limit: limit}
+}
+
+func (d *Document) String() string {
+	return string(d.Text)
+}
+
+// Apply transforms an operation made at revision rev against the operations applied
+// since then, applies it and returns the operation the other editors have to apply.
+func (d *Document) Apply(rev int, op Op) (Op, error) {
+	if rev < d.base || rev > d.Rev {
+		return Op{}, ErrStaleClient
+	}
+	for _, applied := range d.history[rev-d.base:] {
+		op = Transform(op, applied, false)
+	}
+	text, err := op.Apply(d.Text)
+	if err != nil {
+		return Op{}, err
+	}
+	if d.limit > 0 && len(text) > d.limit {
+		return Op{}, ErrTooLong
+	}
+	d.Text = text
+	d.Rev++
+	d.history = append(d.history, op)
+	if len(d.history) > maxHistory {
+		drop := len(d.history) - maxHistory
+		d.history = d.history[drop:]
+		d.base += drop
+	}
+	return op, nil
+}
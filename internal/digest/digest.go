@@ -0,0 +1,187 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"path/filepath"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/notjoji/web-notes/internal/notifier"
+	"github.com/notjoji/web-notes/internal/repository"
+)
+
+const layoutISO = "2006-01-02"
+
+type Note struct {
+	ID          int64
+	Name        string
+	Description string
+	Deadline    string
+}
+
+type Data struct {
+	Date      string
+	DaysAhead int32
+	Expired   []*Note
+	Upcoming  []*Note
+}
+
+type Job struct {
+	db       *repository.Queries
+	notifier notifier.Notifier
+	html     *htmltemplate.Template
+	text     *texttemplate.Template
+}
+
+func NewJob(db *repository.Queries, n notifier.Notifier, templatesDir string) (*Job, error) {
+	html, err := htmltemplate.ParseFiles(filepath.Join(templatesDir, "digest.html"))
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.ParseFiles(filepath.Join(templatesDir, "digest.txt"))
+	if err != nil {
+		return nil, err
+	}
+	return &Job{db: db, notifier: n, html: html, text: text}, nil
+}
+
+func (j *Job) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		j.Tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Job) Tick(ctx context.Context, now time.Time) {
+	settings, err := j.db.GetEnabledDigestSettings(ctx)
+	if err != nil {
+		log.Println("digest: can't load settings:", err)
+		return
+	}
+	for _, s := range settings {
+		today, ok := Due(s, now)
+		if !ok {
+			continue
+		}
+		if err = j.send(ctx, s, today); err != nil {
+			log.Printf("digest: can't send digest to user %d: %v", s.UserID, err)
+		}
+	}
+}
+
+// Due reports whether the digest for s must be sent at now and returns the user's local date.
+func Due(s *repository.DigestSetting, now time.Time) (time.Time, bool) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	if s.LastSentOn.Valid && s.LastSentOn.Time.Format(layoutISO) >= today.Format(layoutISO) {
+		return today, false
+	}
+	sendAt := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc).
+		Add(time.Duration(s.SendTime.Microseconds) * time.Microsecond)
+	return today, !local.Before(sendAt)
+}
+
+// send claims the digest of the day before sending it, so that of several
+// instances only one sends it. If sending fails, the claim is released and the
+// next tick tries again.
+func (j *Job) send(ctx context.Context, s *repository.DigestSetting, today time.Time) error {
+	claimed, err := j.db.ClaimDigest(ctx, repository.ClaimDigestParams{
+		Today:  pgtype.Date{Time: today, Valid: true},
+		UserID: s.UserID,
+	})
+	if err != nil || claimed == 0 {
+		return err
+	}
+	if err = j.compose(ctx, s, today); err != nil {
+		release := repository.ReleaseDigestParams{
+			LastSentOn: s.LastSentOn,
+			UserID:     s.UserID,
+			Today:      pgtype.Date{Time: today, Valid: true},
+		}
+		if releaseErr := j.db.ReleaseDigest(ctx, release); releaseErr != nil {
+			log.Printf("digest: can't release digest of user %d: %v", s.UserID, releaseErr)
+		}
+	}
+	return err
+}
+
+func (j *Job) compose(ctx context.Context, s *repository.DigestSetting, today time.Time) error {
+	expired, err := j.db.GetExpiredNotesByUserId(ctx, repository.GetExpiredNotesByUserIdParams{
+		UserID: s.UserID,
+		Today:  pgtype.Date{Time: today, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	upcoming, err := j.db.GetUpcomingNotesByUserId(ctx, repository.GetUpcomingNotesByUserIdParams{
+		UserID: s.UserID,
+		Today:  pgtype.Date{Time: today, Valid: true},
+		Until:  pgtype.Date{Time: today.AddDate(0, 0, int(s.DaysAhead)), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if len(expired) == 0 && len(upcoming) == 0 {
+		return nil
+	}
+
+	data := &Data{
+		Date:      today.Format(layoutISO),
+		DaysAhead: s.DaysAhead,
+		Expired:   mapNotes(expired),
+		Upcoming:  mapNotes(upcoming),
+	}
+	msg, err := j.Render(data)
+	if err != nil {
+		return err
+	}
+	msg.To = s.Email
+	return j.notifier.Send(ctx, msg)
+}
+
+func (j *Job) Render(data *Data) (*notifier.Message, error) {
+	var html, text bytes.Buffer
+	if err := j.html.ExecuteTemplate(&html, "digest", data); err != nil {
+		return nil, err
+	}
+	if err := j.text.ExecuteTemplate(&text, "digest", data); err != nil {
+		return nil, err
+	}
+	return &notifier.Message{
+		Subject: fmt.Sprintf("Заметки на %s: просрочено %d, скоро срок %d", data.Date, len(data.Expired), len(data.Upcoming)),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func mapNotes(notes []*repository.Note) []*Note {
+	dtos := make([]*Note, len(notes))
+	for i, note := range notes {
+		description := ""
+		if note.Description != nil {
+			description = *note.Description
+		}
+		dtos[i] = &Note{
+			ID:          note.ID,
+			Name:        note.Name,
+			Description: description,
+			Deadline:    note.DeadlineAt.Time.Format(layoutISO),
+		}
+	}
+	return dtos
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type Kind string
+
+const (
+	NoteCreated       Kind = "note.created"
+	NoteUpdated       Kind = "note.updated"
+	NoteDeleted       Kind = "note.deleted"
+	NoteStatusChanged Kind = "note.status"
+
+	subscriptionBuffer = 64
+)
+
+// Event is a change of a note. Users lists everyone who can see the note; the
+// event is delivered only to their subscriptions.
+type Event struct {
+	ID      uint64  `json:"id"`
+	Kind    Kind    `json:"kind"`
+	NoteID  int64   `json:"noteId"`
+	ActorID int64   `json:"actorId"`
+	Users   []int64 `json:"-"`
+	Note    any     `json:"note,omitempty"`
+}
+
+func (e *Event) visibleTo(userID int64) bool {
+	for _, id := range e.Users {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Publisher is implemented by the in-process Bus and by transports that fan the
+// events out to other instances.
+type Publisher interface {
+	Publish(e Event)
+}
+
+type Subscription struct {
+	UserID int64
+	C      <-chan Event
+	c      chan Event
+	bus    *Bus
+	once   sync.Once
+}
+
+// Close unsubscribes; it is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.c)
+	})
+}
+
+// Bus delivers events to the subscriptions of this process. A subscriber that
+// does not keep up loses events instead of blocking the publisher.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+	seq  atomic.Uint64
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+func (b *Bus) Subscribe(userID int64) *Subscription {
+	c := make(chan Event, subscriptionBuffer)
+	s := &Subscription{UserID: userID, C: c, c: c, bus: b}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+func (b *Bus) Publish(e Event) {
+	e.ID = b.seq.Add(1)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs {
+		if !e.visibleTo(s.UserID) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+		}
+	}
+}
+
+// WriteSSE writes the event in the text/event-stream format.
+func WriteSSE(w io.Writer, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Kind, data)
+	return err
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+)
+
+const (
+	Channel = "note_events"
+
+	// NOTIFY payloads are limited to 8000 bytes
+	maxPayload = 7900
+)
+
+// Notifier sends a message to every instance listening on the channel.
+type Notifier interface {
+	Notify(ctx context.Context, channel, payload string) error
+}
+
+type envelope struct {
+	Origin string  `json:"origin"`
+	Users  []int64 `json:"users"`
+	Event  Event   `json:"event"`
+}
+
+// Fanout delivers the events to the local bus right away and sends them to the
+// other instances; Receive is fed with the notifications of the channel and skips
+// the ones sent by this instance.
+type Fanout struct {
+	ctx      context.Context
+	bus      *Bus
+	notifier Notifier
+	channel  string
+	origin   string
+}
+
+func NewFanout(ctx context.Context, bus *Bus, notifier Notifier, channel string) *Fanout {
+	origin := make([]byte, 8)
+	_, _ = rand.Read(origin)
+	return &Fanout{ctx: ctx, bus: bus, notifier: notifier, channel: channel, origin: hex.EncodeToString(origin)}
+}
+
+func (f *Fanout) Publish(e Event) {
+	f.bus.Publish(e)
+
+	payload, err := f.encode(e)
+	if err != nil {
+		log.Printf("events: encode %s of note %d: %v", e.Kind, e.NoteID, err)
+		return
+	}
+	if err = f.notifier.Notify(f.ctx, f.channel, payload); err != nil {
+		log.Printf("events: notify %s of note %d: %v", e.Kind, e.NoteID, err)
+	}
+}
+
+// encode drops the note of an event that does not fit into a notification; the
+// clients reload such notes instead of updating them in place.
+func (f *Fanout) encode(e Event) (string, error) {
+	e.ID = 0
+	data, err := json.Marshal(envelope{Origin: f.origin, Users: e.Users, Event: e})
+	if err != nil || len(data) <= maxPayload {
+		return string(data), err
+	}
+	e.Note = nil
+	data, err = json.Marshal(envelope{Origin: f.origin, Users: e.Users, Event: e})
+	return string(data), err
+}
+
+func (f *Fanout) Receive(payload string) {
+	var env envelope
+	if err := json.Unmarshal([]byte(payload), &env); err != nil {
+		log.Printf("events: decode notification: %v", err)
+		return
+	}
+	if env.Origin == f.origin {
+		return
+	}
+	env.Event.Users = env.Users
+	f.bus.Publish(env.Event)
+}
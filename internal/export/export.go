@@ -0,0 +1,134 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const maxFileNameLength = 60
+
+// Archive writes a zip file and keeps entry names unique.
+type Archive struct {
+	zw    *zip.Writer
+	names map[string]bool
+	now   time.Time
+}
+
+func NewArchive(w io.Writer, now time.Time) *Archive {
+	return &Archive{zw: zip.NewWriter(w), names: make(map[string]bool), now: now}
+}
+
+// Name returns name or, if it is already taken, name with a numeric suffix.
+func (a *Archive) Name(name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	unique := name
+	for i := 2; a.names[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	a.names[unique] = true
+	return unique
+}
+
+func (a *Archive) WriteFile(name string, r io.Reader) error {
+	w, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.now})
+	if err != nil {
+		return errors.Wrapf(err, "create %s", name)
+	}
+	if _, err = io.Copy(w, r); err != nil {
+		return errors.Wrapf(err, "write %s", name)
+	}
+	return nil
+}
+
+func (a *Archive) WriteJSON(name string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "marshal %s", name)
+	}
+	return a.WriteFile(name, strings.NewReader(string(content)+"\n"))
+}
+
+func (a *Archive) Close() error {
+	return a.zw.Close()
+}
+
+// FileName turns a note or attachment name into a safe archive entry name,
+// keeping letters of any alphabet.
+func FileName(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	result := strings.Trim(b.String(), "-.")
+	if runes := []rune(result); len(runes) > maxFileNameLength {
+		result = strings.Trim(string(runes[:maxFileNameLength]), "-.")
+	}
+	if result == "" {
+		return "untitled"
+	}
+	return result
+}
+
+type MarkdownNote struct {
+	Name        string
+	Description string
+	CreatedAt   string
+	Deadline    string
+	Completed   bool
+	Priority    string
+	Tags        []string
+}
+
+// Markdown renders the note with the front matter read by importer.ParseMarkdown,
+// so an exported archive can be imported back.
+func Markdown(note MarkdownNote) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("name: " + quote(note.Name) + "\n")
+	if note.CreatedAt != "" {
+		b.WriteString("created: " + note.CreatedAt + "\n")
+	}
+	if note.Deadline != "" {
+		b.WriteString("deadline: " + note.Deadline + "\n")
+	}
+	b.WriteString("completed: " + strconv.FormatBool(note.Completed) + "\n")
+	if note.Priority != "" {
+		b.WriteString("priority: " + note.Priority + "\n")
+	}
+	if len(note.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, tag := range note.Tags {
+			b.WriteString("  - " + quote(tag) + "\n")
+		}
+	}
+	b.WriteString("---\n\n")
+	b.WriteString(note.Description)
+	b.WriteString("\n")
+	return b.String()
+}
+
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, ":#[]{},&*!|>'\"%@`\\") || strings.TrimSpace(s) != s ||
+		strings.HasPrefix(s, "-") {
+		return strconv.Quote(s)
+	}
+	return s
+}
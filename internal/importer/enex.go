@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const enexTimeLayout = "20060102T150405Z"
+
+type enexNote struct {
+	Title      string   `xml:"title"`
+	Content    string   `xml:"content"`
+	Created    string   `xml:"created"`
+	Tags       []string `xml:"tag"`
+	Attributes struct {
+		ReminderTime     string `xml:"reminder-time"`
+		ReminderDoneTime string `xml:"reminder-done-time"`
+	} `xml:"note-attributes"`
+}
+
+func enexDate(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	parsed, err := time.Parse(enexTimeLayout, strings.TrimSpace(value))
+	if err != nil {
+		return "", errors.Errorf("некорректная дата %q", value)
+	}
+	return parsed.Format(layoutISO), nil
+}
+
+// ParseENEX streams an Evernote export: the note body is converted from ENML to
+// Markdown, a reminder becomes the deadline and a done reminder completes the note.
+// Resources (attached files) are skipped.
+func ParseENEX(r io.Reader) ([]*Note, []RowError, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var notes []*Note
+	var rowErrors []RowError
+	for i := 1; ; {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "некорректный ENEX")
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+		if len(notes)+len(rowErrors) >= MaxRecords {
+			return nil, nil, errors.Errorf("не более %d записей за один импорт", MaxRecords)
+		}
+
+		var enex enexNote
+		if err = decoder.DecodeElement(&enex, &start); err != nil {
+			return nil, nil, errors.Wrap(err, "некорректный ENEX")
+		}
+		source := fmt.Sprintf("заметка %d", i)
+		if title := strings.TrimSpace(enex.Title); title != "" {
+			source = fmt.Sprintf("заметка %d «%s»", i, title)
+		}
+		i++
+
+		note, err := mapENEXNote(source, &enex)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{source, err.Error()})
+			continue
+		}
+		notes = append(notes, note)
+	}
+	if len(notes)+len(rowErrors) == 0 {
+		return nil, nil, errors.New("в файле нет заметок Evernote")
+	}
+	return notes, rowErrors, nil
+}
+
+func mapENEXNote(source string, enex *enexNote) (*Note, error) {
+	body, err := ENMLToMarkdown(enex.Content)
+	if err != nil {
+		return nil, err
+	}
+	created, err := enexDate(enex.Created)
+	if err != nil {
+		return nil, err
+	}
+	deadline, err := enexDate(enex.Attributes.ReminderTime)
+	if err != nil {
+		return nil, err
+	}
+	return &Note{
+		Source:      source,
+		Name:        NoteName(enex.Title, body),
+		Description: NoteBody(enex.Title, body),
+		Deadline:    deadline,
+		CreatedAt:   created,
+		Completed:   strings.TrimSpace(enex.Attributes.ReminderDoneTime) != "",
+		Tags:        NormalizeTags(enex.Tags),
+	}, nil
+}
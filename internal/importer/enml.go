@@ -0,0 +1,213 @@
+package importer
+
+import (
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	spaceRun    = regexp.MustCompile(`[ \t\r\n]+`)
+	blankLines  = regexp.MustCompile(`\n{3,}`)
+	trailingSpc = regexp.MustCompile(`[ \t]+\n`)
+)
+
+type enmlList struct {
+	ordered bool
+	n       int
+}
+
+type enmlConverter struct {
+	b     strings.Builder
+	lists []enmlList
+	links []string
+	pre   int
+}
+
+// ENMLToMarkdown converts the XHTML subset used by Evernote note bodies to Markdown.
+// Unknown elements keep their text; en-media (attached files) is replaced by a marker.
+func ENMLToMarkdown(enml string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(enml))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	c := &enmlConverter{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "parse ENML")
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			c.start(t)
+		case xml.EndElement:
+			c.end(t.Name.Local)
+		case xml.CharData:
+			c.text(string(t))
+		}
+	}
+
+	result := trailingSpc.ReplaceAllString(c.b.String(), "\n")
+	result = blankLines.ReplaceAllString(result, "\n\n")
+	return strings.TrimSpace(result), nil
+}
+
+func (c *enmlConverter) atLineStart() bool {
+	s := c.b.String()
+	return s == "" || strings.HasSuffix(s, "\n")
+}
+
+func (c *enmlConverter) newline() {
+	if !c.atLineStart() {
+		c.b.WriteByte('\n')
+	}
+}
+
+func (c *enmlConverter) paragraph() {
+	c.newline()
+	if s := c.b.String(); s != "" && !strings.HasSuffix(s, "\n\n") {
+		c.b.WriteByte('\n')
+	}
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (c *enmlConverter) start(e xml.StartElement) {
+	switch name := strings.ToLower(e.Name.Local); name {
+	case "div", "tr":
+		c.newline()
+	case "p", "blockquote", "table":
+		c.paragraph()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		c.paragraph()
+		level, _ := strconv.Atoi(name[1:])
+		c.b.WriteString(strings.Repeat("#", level) + " ")
+	case "br":
+		c.b.WriteByte('\n')
+	case "hr":
+		c.paragraph()
+		c.b.WriteString("---\n\n")
+	case "b", "strong":
+		c.b.WriteString("**")
+	case "i", "em":
+		c.b.WriteString("_")
+	case "s", "strike", "del":
+		c.b.WriteString("~~")
+	case "code":
+		if c.pre == 0 {
+			c.b.WriteString("`")
+		}
+	case "pre":
+		c.paragraph()
+		c.b.WriteString("```\n")
+		c.pre++
+	case "a":
+		href := attr(e, "href")
+		c.links = append(c.links, href)
+		if href != "" {
+			c.b.WriteString("[")
+		}
+	case "ul", "ol":
+		if len(c.lists) == 0 {
+			c.paragraph()
+		}
+		c.lists = append(c.lists, enmlList{ordered: name == "ol"})
+	case "li":
+		c.newline()
+		marker := "- "
+		if n := len(c.lists); n > 0 {
+			c.b.WriteString(strings.Repeat("  ", n-1))
+			if list := &c.lists[n-1]; list.ordered {
+				list.n++
+				marker = strconv.Itoa(list.n) + ". "
+			}
+		}
+		c.b.WriteString(marker)
+	case "en-todo":
+		box := "[ ] "
+		if strings.EqualFold(attr(e, "checked"), "true") {
+			box = "[x] "
+		}
+		if c.atLineStart() {
+			box = "- " + box
+		}
+		c.b.WriteString(box)
+	case "en-media":
+		c.b.WriteString("[вложение]")
+	case "img":
+		if alt := attr(e, "alt"); alt != "" {
+			c.b.WriteString(alt)
+		}
+	case "td", "th":
+		if !c.atLineStart() {
+			c.b.WriteString(" | ")
+		}
+	}
+}
+
+func (c *enmlConverter) end(name string) {
+	switch name = strings.ToLower(name); name {
+	case "div", "tr":
+		c.newline()
+	case "p", "blockquote", "table", "h1", "h2", "h3", "h4", "h5", "h6":
+		c.paragraph()
+	case "b", "strong":
+		c.b.WriteString("**")
+	case "i", "em":
+		c.b.WriteString("_")
+	case "s", "strike", "del":
+		c.b.WriteString("~~")
+	case "code":
+		if c.pre == 0 {
+			c.b.WriteString("`")
+		}
+	case "pre":
+		c.newline()
+		c.b.WriteString("```\n")
+		if c.pre > 0 {
+			c.pre--
+		}
+	case "a":
+		if n := len(c.links); n > 0 {
+			if href := c.links[n-1]; href != "" {
+				c.b.WriteString("](" + href + ")")
+			}
+			c.links = c.links[:n-1]
+		}
+	case "ul", "ol":
+		if n := len(c.lists); n > 0 {
+			c.lists = c.lists[:n-1]
+		}
+		c.newline()
+		if len(c.lists) == 0 {
+			c.paragraph()
+		}
+	}
+}
+
+func (c *enmlConverter) text(s string) {
+	if c.pre > 0 {
+		c.b.WriteString(s)
+		return
+	}
+	s = spaceRun.ReplaceAllString(s, " ")
+	if c.atLineStart() {
+		s = strings.TrimLeft(s, " ")
+	}
+	c.b.WriteString(s)
+}
@@ -0,0 +1,348 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	MaxRecords           = 5000
+	MaxFileSize          = 1 << 20
+	MaxArchiveSize       = 50 << 20
+	MaxDescriptionLength = 20000
+	layoutISO            = "2006-01-02"
+)
+
+var errArchiveTooLarge = errors.Errorf("распакованный архив больше %d байт", MaxArchiveSize)
+
+// Note is a format-independent imported note. Values are kept as strings where
+// the application validates them, so every format goes through the same rules.
+type Note struct {
+	Source      string
+	Name        string
+	Description string
+	Deadline    string
+	CreatedAt   string
+	Completed   bool
+	Trashed     bool
+	Priority    string
+	Pinned      bool
+	Tags        []string
+}
+
+type RowError struct {
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return e.Source + ": " + e.Message
+}
+
+// Mapping tells which CSV header columns hold note fields; empty means the field is not imported.
+type Mapping struct {
+	Name        string
+	Description string
+	Deadline    string
+	Completed   string
+	Tags        string
+}
+
+func DefaultMapping() Mapping {
+	return Mapping{
+		Name:        "name",
+		Description: "description",
+		Deadline:    "deadline",
+		Completed:   "completed",
+		Tags:        "tags",
+	}
+}
+
+func ParseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "0", "false", "no", "нет", "n":
+		return false, nil
+	case "1", "true", "yes", "да", "y", "x":
+		return true, nil
+	}
+	return false, errors.Errorf("некорректное логическое значение %q", s)
+}
+
+// SplitTags accepts comma or semicolon separated tags, drops leading '#' and duplicates.
+func SplitTags(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' })
+	return NormalizeTags(fields)
+}
+
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(tag), "#"))
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+func ParseCSV(r io.Reader, mapping Mapping) ([]*Note, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read CSV header")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))] = i
+	}
+	index := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := columns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return -1, errors.Errorf("в CSV нет колонки %q", name)
+		}
+		return i, nil
+	}
+
+	var idx [5]int
+	for i, name := range []string{mapping.Name, mapping.Description, mapping.Deadline, mapping.Completed, mapping.Tags} {
+		if idx[i], err = index(name); err != nil {
+			return nil, nil, err
+		}
+	}
+	if idx[0] < 0 {
+		return nil, nil, errors.New("не указана колонка с названием заметки")
+	}
+
+	var notes []*Note
+	var rowErrors []RowError
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		source := fmt.Sprintf("строка %d", line)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, source)
+		}
+		if len(notes)+len(rowErrors) >= MaxRecords {
+			return nil, nil, errors.Errorf("не более %d записей за один импорт", MaxRecords)
+		}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		completed, err := ParseBool(field(idx[3]))
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{source, err.Error()})
+			continue
+		}
+		notes = append(notes, &Note{
+			Source:      source,
+			Name:        field(idx[0]),
+			Description: field(idx[1]),
+			Deadline:    field(idx[2]),
+			Completed:   completed,
+			Tags:        SplitTags(field(idx[4])),
+		})
+	}
+	return notes, rowErrors, nil
+}
+
+// ParseMarkdownZip reads every .md file of the archive; the YAML front matter may
+// set name, deadline, created, completed, priority and tags, the rest of the file becomes the description.
+func ParseMarkdownZip(r io.ReaderAt, size int64) ([]*Note, []RowError, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read zip")
+	}
+
+	var notes []*Note
+	var rowErrors []RowError
+	remaining := int64(MaxArchiveSize)
+	for _, file := range archive.File {
+		name := file.Name
+		base := path.Base(name)
+		if file.FileInfo().IsDir() || strings.HasPrefix(base, ".") || strings.HasPrefix(name, "__MACOSX/") ||
+			!strings.EqualFold(path.Ext(base), ".md") {
+			continue
+		}
+		if len(notes)+len(rowErrors) >= MaxRecords {
+			return nil, nil, errors.Errorf("не более %d записей за один импорт", MaxRecords)
+		}
+
+		content, err := readZipFile(file, &remaining)
+		if errors.Is(err, errArchiveTooLarge) {
+			return nil, nil, err
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{name, err.Error()})
+			continue
+		}
+		note, err := ParseMarkdown(name, content)
+		if err == nil {
+			err = checkDescription(note)
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{name, err.Error()})
+			continue
+		}
+		notes = append(notes, note)
+	}
+	return notes, rowErrors, nil
+}
+
+// readZipFile reads at most MaxFileSize bytes regardless of the size declared in the archive
+// and takes them from the remaining budget of the whole archive.
+func readZipFile(file *zip.File, remaining *int64) (string, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(io.LimitReader(rc, min(MaxFileSize, *remaining)+1))
+	if err != nil {
+		return "", err
+	}
+	if len(content) > MaxFileSize {
+		return "", errors.Errorf("файл больше %d байт", MaxFileSize)
+	}
+	if *remaining -= int64(len(content)); *remaining < 0 {
+		return "", errArchiveTooLarge
+	}
+	return string(content), nil
+}
+
+// checkDescription rejects a note as soon as it is parsed, so an over-long
+// description is not kept until validation.
+func checkDescription(note *Note) error {
+	if utf8.RuneCountInString(note.Description) > MaxDescriptionLength {
+		return errors.Errorf("описание длиннее %d символов", MaxDescriptionLength)
+	}
+	return nil
+}
+
+func ParseMarkdown(source, content string) (*Note, error) {
+	meta, body, err := ParseFrontMatter(content)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := ParseBool(meta.Get("completed"))
+	if err != nil {
+		return nil, err
+	}
+
+	name := meta.Get("name")
+	if name == "" {
+		name = meta.Get("title")
+	}
+	if name == "" {
+		name = strings.TrimSuffix(path.Base(source), path.Ext(source))
+	}
+	return &Note{
+		Source:      source,
+		Name:        name,
+		Description: strings.TrimSpace(body),
+		Deadline:    meta.Get("deadline"),
+		CreatedAt:   meta.Get("created"),
+		Priority:    meta.Get("priority"),
+		Completed:   completed,
+		Tags:        NormalizeTags(meta["tags"]),
+	}, nil
+}
+
+// FrontMatter keeps every key as a list; scalar values are lists of one element.
+type FrontMatter map[string][]string
+
+func (f FrontMatter) Get(key string) string {
+	if values := f[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ParseFrontMatter supports the subset of YAML used in note front matter:
+// "key: value" pairs, inline lists "[a, b]" and block lists of "- item" lines.
+func ParseFrontMatter(content string) (FrontMatter, string, error) {
+	content = strings.TrimPrefix(strings.ReplaceAll(content, "\r\n", "\n"), "\uFEFF")
+	meta := FrontMatter{}
+	if !strings.HasPrefix(content, "---\n") {
+		return meta, content, nil
+	}
+	end := strings.Index(content[4:], "\n---")
+	if end < 0 {
+		return nil, "", errors.New("не закрыт блок front matter")
+	}
+	header := content[4 : 4+end]
+	body := strings.TrimPrefix(content[4+end+4:], "\n")
+
+	var listKey string
+	for i, line := range strings.Split(header, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return nil, "", errors.Errorf("front matter, строка %d: элемент списка без ключа", i+1)
+			}
+			meta[listKey] = append(meta[listKey], unquote(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, "", errors.Errorf("front matter, строка %d: ожидается \"ключ: значение\"", i+1)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		listKey = ""
+		switch {
+		case value == "":
+			listKey = key
+			meta[key] = nil
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			var items []string
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = unquote(strings.TrimSpace(item)); item != "" {
+					items = append(items, item)
+				}
+			}
+			meta[key] = items
+		default:
+			meta[key] = []string{unquote(value)}
+		}
+	}
+	return meta, body, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"') {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxNameLength = 50
+	untitled      = "Без названия"
+)
+
+type keepNote struct {
+	Title       string `json:"title"`
+	TextContent string `json:"textContent"`
+	ListContent []struct {
+		Text      string `json:"text"`
+		IsChecked bool   `json:"isChecked"`
+	} `json:"listContent"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	IsTrashed            bool  `json:"isTrashed"`
+	IsArchived           bool  `json:"isArchived"`
+	IsPinned             bool  `json:"isPinned"`
+	CreatedTimestampUsec int64 `json:"createdTimestampUsec"`
+}
+
+// NoteName returns the title cut to the note name length or, for untitled notes,
+// the first line of the body.
+func NoteName(title, body string) string {
+	name := strings.TrimSpace(title)
+	if name == "" {
+		for _, line := range strings.Split(body, "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#->*[]x "))
+			if line != "" {
+				name = line
+				break
+			}
+		}
+	}
+	if name == "" {
+		return untitled
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		name = strings.TrimSpace(string([]rune(name)[:maxNameLength-1])) + "…"
+	}
+	return name
+}
+
+// NoteBody returns the body or, for notes without text such as title-only or
+// image-only notes, the full title, because a note cannot be saved empty.
+func NoteBody(title, body string) string {
+	if body != "" {
+		return body
+	}
+	if title = strings.TrimSpace(title); title != "" {
+		return title
+	}
+	return untitled
+}
+
+// ParseKeepNote maps one note of Google Keep Takeout. Checklists become Markdown
+// task lists and archived notes are imported as completed. Keep does not export
+// reminders, so Keep notes have no deadline.
+func ParseKeepNote(source string, content []byte) (*Note, error) {
+	var keep keepNote
+	if err := json.Unmarshal(content, &keep); err != nil {
+		return nil, errors.Wrap(err, "некорректный JSON заметки Keep")
+	}
+
+	body := strings.TrimSpace(keep.TextContent)
+	if len(keep.ListContent) > 0 {
+		items := make([]string, 0, len(keep.ListContent))
+		for _, item := range keep.ListContent {
+			box := "- [ ] "
+			if item.IsChecked {
+				box = "- [x] "
+			}
+			items = append(items, box+strings.TrimSpace(item.Text))
+		}
+		body = strings.TrimSpace(body + "\n" + strings.Join(items, "\n"))
+	}
+
+	labels := make([]string, len(keep.Labels))
+	for i, label := range keep.Labels {
+		labels[i] = label.Name
+	}
+
+	note := &Note{
+		Source:      source,
+		Name:        NoteName(keep.Title, body),
+		Description: NoteBody(keep.Title, body),
+		Completed:   keep.IsArchived,
+		Trashed:     keep.IsTrashed,
+		Pinned:      keep.IsPinned,
+		Tags:        NormalizeTags(labels),
+	}
+	if keep.CreatedTimestampUsec > 0 {
+		note.CreatedAt = time.UnixMicro(keep.CreatedTimestampUsec).UTC().Format(layoutISO)
+	}
+	return note, nil
+}
+
+// ParseKeepZip reads the Keep/*.json files of a Takeout archive; other files
+// (images, HTML copies of the notes) are ignored.
+func ParseKeepZip(r io.ReaderAt, size int64) ([]*Note, []RowError, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read zip")
+	}
+
+	var notes []*Note
+	var rowErrors []RowError
+	remaining := int64(MaxArchiveSize)
+	for _, file := range archive.File {
+		name := file.Name
+		if file.FileInfo().IsDir() || !strings.EqualFold(path.Ext(name), ".json") ||
+			!strings.EqualFold(path.Base(path.Dir(name)), "keep") {
+			continue
+		}
+		if len(notes)+len(rowErrors) >= MaxRecords {
+			return nil, nil, errors.Errorf("не более %d записей за один импорт", MaxRecords)
+		}
+
+		content, err := readZipFile(file, &remaining)
+		if errors.Is(err, errArchiveTooLarge) {
+			return nil, nil, err
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{name, err.Error()})
+			continue
+		}
+		note, err := ParseKeepNote(name, []byte(content))
+		if err == nil {
+			err = checkDescription(note)
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{name, err.Error()})
+			continue
+		}
+		notes = append(notes, note)
+	}
+	if len(notes)+len(rowErrors) == 0 {
+		return nil, nil, errors.New("в архиве нет заметок Google Keep")
+	}
+	return notes, rowErrors, nil
+}
@@ -0,0 +1,39 @@
+package mentions
+
+import (
+	"regexp"
+	"strings"
+)
+
+// a mention starts a word, so e-mail addresses are not mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_.\-]+)`)
+
+// Parse returns the logins mentioned as @login in order of appearance, without repetitions.
+func Parse(text string) []string {
+	seen := make(map[string]bool)
+	logins := make([]string, 0)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		login := strings.TrimRight(match[1], ".-")
+		if login == "" || seen[login] {
+			continue
+		}
+		seen[login] = true
+		logins = append(logins, login)
+	}
+	return logins
+}
+
+// Added returns the logins mentioned in text but not in previous.
+func Added(previous, text string) []string {
+	before := make(map[string]bool)
+	for _, login := range Parse(previous) {
+		before[login] = true
+	}
+	added := make([]string, 0)
+	for _, login := range Parse(text) {
+		if !before[login] {
+			added = append(added, login)
+		}
+	}
+	return added
+}
@@ -0,0 +1,137 @@
+package notetemplate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Unit string
+
+const (
+	Day   Unit = "day"
+	Week  Unit = "week"
+	Month Unit = "month"
+
+	maxOffset = 365
+)
+
+var unitNames = map[string]Unit{
+	"d": Day, "day": Day, "days": Day, "д": Day, "дн": Day, "день": Day, "дня": Day, "дней": Day,
+	"w": Week, "week": Week, "weeks": Week, "н": Week, "нед": Week, "неделя": Week, "неделю": Week,
+	"недели": Week, "недель": Week,
+	"m": Month, "month": Month, "months": Month, "мес": Month, "месяц": Month, "месяца": Month,
+	"месяцев": Month,
+}
+
+var (
+	offsetPattern      = regexp.MustCompile(`^\+?\s*(\d+)\s*([^\s\d.]+)\.?$`)
+	placeholderPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+)
+
+// Offset is a deadline relative to the day a note is created, stored as e.g. "+3 days".
+type Offset struct {
+	N    int
+	Unit Unit
+}
+
+func ParseOffset(s string) (*Offset, error) {
+	match := offsetPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if match == nil {
+		return nil, errors.Errorf("invalid offset %q", s)
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n > maxOffset {
+		return nil, errors.Errorf("offset must be between 0 and %d, got %q", maxOffset, match[1])
+	}
+	unit, ok := unitNames[match[2]]
+	if !ok {
+		return nil, errors.Errorf("unknown offset unit %q", match[2])
+	}
+	return &Offset{N: n, Unit: unit}, nil
+}
+
+func (o *Offset) String() string {
+	if o.N == 1 {
+		return fmt.Sprintf("+1 %s", o.Unit)
+	}
+	return fmt.Sprintf("+%d %ss", o.N, o.Unit)
+}
+
+// Apply returns the deadline for a note created at now. Adding months keeps the day
+// of month where possible and falls back to the last day of a shorter month.
+func (o *Offset) Apply(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch o.Unit {
+	case Week:
+		return day.AddDate(0, 0, 7*o.N)
+	case Month:
+		first := time.Date(day.Year(), day.Month()+time.Month(o.N), 1, 0, 0, 0, 0, day.Location())
+		lastDay := first.AddDate(0, 1, -1).Day()
+		return first.AddDate(0, 0, min(day.Day(), lastDay)-1)
+	case Day:
+	}
+	return day.AddDate(0, 0, o.N)
+}
+
+func (o *Offset) Describe() string {
+	if o.N == 0 {
+		return "в день создания"
+	}
+	switch o.Unit {
+	case Week:
+		return fmt.Sprintf("через %d нед.", o.N)
+	case Month:
+		return fmt.Sprintf("через %d мес.", o.N)
+	case Day:
+	}
+	return fmt.Sprintf("через %d дн.", o.N)
+}
+
+var (
+	weekdayNames = []string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"}
+	monthNames   = []string{"январь", "февраль", "март", "апрель", "май", "июнь", "июль", "август", "сентябрь",
+		"октябрь", "ноябрь", "декабрь"}
+)
+
+type Placeholder struct {
+	Name        string
+	Description string
+}
+
+// Placeholders lists the supported placeholders with a description for the templates page.
+var Placeholders = []Placeholder{
+	{"{{date}}", "дата создания, 2024-12-21"},
+	{"{{time}}", "время создания, 15:04"},
+	{"{{weekday}}", "день недели"},
+	{"{{week}}", "номер недели по ISO"},
+	{"{{month}}", "месяц"},
+	{"{{year}}", "год"},
+}
+
+// Expand replaces the placeholders with values for now; unknown placeholders are kept.
+func Expand(text string, now time.Time) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		switch strings.ToLower(name) {
+		case "date":
+			return now.Format("2006-01-02")
+		case "time":
+			return now.Format("15:04")
+		case "weekday":
+			return weekdayNames[now.Weekday()]
+		case "week":
+			_, week := now.ISOWeek()
+			return strconv.Itoa(week)
+		case "month":
+			return monthNames[now.Month()-1]
+		case "year":
+			return strconv.Itoa(now.Year())
+		}
+		return placeholder
+	})
+}
@@ -0,0 +1,454 @@
+// Package qrcode encodes short texts such as otpauth:// URIs as QR codes (ISO/IEC
+// 18004) and renders them as SVG, so that secrets never leave the server. Only
+// byte mode, error correction level M and versions 1-10 (up to 213 bytes) are
+// supported.
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxVersion = 10
+	quietZone  = 4
+
+	modeByte = 0x4
+	// format bits of error correction level M
+	levelM = 0x0
+)
+
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// blocks describes the error correction blocks of a version at level M.
+type blocks struct {
+	ecPerBlock   int
+	short, long  int // number of blocks with shortDataLen and shortDataLen+1 data codewords
+	shortDataLen int
+}
+
+var versionBlocks = [maxVersion + 1]blocks{
+	1:  {10, 1, 0, 16},
+	2:  {16, 1, 0, 28},
+	3:  {26, 1, 0, 44},
+	4:  {18, 2, 0, 32},
+	5:  {24, 2, 0, 43},
+	6:  {16, 4, 0, 27},
+	7:  {18, 4, 0, 31},
+	8:  {22, 2, 2, 38},
+	9:  {22, 3, 2, 36},
+	10: {26, 4, 1, 43},
+}
+
+var alignmentPositions = [maxVersion + 1][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+func (b blocks) dataCodewords() int {
+	return b.short*b.shortDataLen + b.long*(b.shortDataLen+1)
+}
+
+// Code is an encoded symbol; modules are addressed by column x and row y.
+type Code struct {
+	Version  int
+	Size     int
+	Mask     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+func newCode(version int) *Code {
+	size := 17 + 4*version
+	c := &Code{Version: version, Size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+		c.function[y] = make([]bool, size)
+	}
+	return c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+// Encode returns the smallest symbol holding the data.
+func Encode(data []byte) (*Code, error) {
+	version := 1
+	for ; version <= maxVersion; version++ {
+		if capacityBits(version) >= dataBits(version, len(data)) {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, ErrTooLong
+	}
+
+	c := newCode(version)
+	c.drawFunctionPatterns()
+	c.drawCodewords(interleave(version, encodeData(version, data)))
+
+	best := -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if score := c.penalty(); best < 0 || score < best {
+			best, c.Mask = score, mask
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(c.Mask)
+	c.drawFormatBits(c.Mask)
+	return c, nil
+}
+
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+func capacityBits(version int) int {
+	return versionBlocks[version].dataCodewords() * 8
+}
+
+func dataBits(version, n int) int {
+	return 4 + countBits(version) + 8*n
+}
+
+type bitBuffer struct {
+	bytes []byte
+	n     int
+}
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.bytes = append(b.bytes, 0)
+		}
+		if value>>i&1 != 0 {
+			b.bytes[b.n/8] |= 0x80 >> (b.n % 8)
+		}
+		b.n++
+	}
+}
+
+// encodeData returns the data codewords: mode, length, data, terminator and padding.
+func encodeData(version int, data []byte) []byte {
+	capacity := capacityBits(version)
+	var b bitBuffer
+	b.append(modeByte, 4)
+	b.append(len(data), countBits(version))
+	for _, c := range data {
+		b.append(int(c), 8)
+	}
+	b.append(0, min(4, capacity-b.n))
+	b.append(0, (8-b.n%8)%8)
+	for pad := 0; b.n < capacity; pad++ {
+		b.append([]int{0xEC, 0x11}[pad%2], 8)
+	}
+	return b.bytes
+}
+
+// interleave splits the data into blocks, adds their error correction codewords
+// and interleaves them as the symbol stores them.
+func interleave(version int, data []byte) []byte {
+	spec := versionBlocks[version]
+	divisor := rsDivisor(spec.ecPerBlock)
+	var dataBlocks, ecBlocks [][]byte
+	for i, offset := 0, 0; i < spec.short+spec.long; i++ {
+		n := spec.shortDataLen
+		if i >= spec.short {
+			n++
+		}
+		block := data[offset : offset+n]
+		offset += n
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+	}
+
+	result := make([]byte, 0, len(data)+len(ecBlocks)*spec.ecPerBlock)
+	for i := 0; i <= spec.shortDataLen; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions[c.Version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// the corners are taken by the finder patterns
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// reserve the format areas; the bits are drawn once the mask is chosen
+	c.drawFormatBits(0)
+	c.drawVersionBits()
+}
+
+// drawFinder draws a finder pattern centered at x, y with its light separator.
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// FormatBits returns the 15 format bits of level M with the mask.
+func FormatBits(mask int) int {
+	data := levelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// VersionBits returns the 18 version bits stored by versions 7 and up.
+func VersionBits(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return version<<12 | rem
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	bits := FormatBits(mask)
+	bit := func(i int) bool {
+		return bits>>i&1 != 0
+	}
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+func (c *Code) drawVersionBits() {
+	if c.Version < 7 {
+		return
+	}
+	bits := VersionBits(c.Version)
+	for i := 0; i < 18; i++ {
+		dark := bits>>i&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the bits in two-module columns zigzagging from the bottom
+// right corner, skipping the function patterns and the vertical timing pattern.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = codewords[i/8]>>(7-i%8)&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	}
+	return ((x+y)%2+x*y%3)%2 == 0
+}
+
+// applyMask flips the data modules; applying it twice restores them.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.function[y][x] && maskBit(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol by the four rules of the standard; the mask with the
+// lowest score is the easiest to scan.
+func (c *Code) penalty() int {
+	score := 0
+	line := make([]bool, c.Size)
+	for _, vertical := range []bool{false, true} {
+		for i := 0; i < c.Size; i++ {
+			for j := range line {
+				if vertical {
+					line[j] = c.modules[j][i]
+				} else {
+					line[j] = c.modules[i][j]
+				}
+			}
+			score += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				v := c.modules[y][x]
+				if c.modules[y][x+1] == v && c.modules[y+1][x] == v && c.modules[y+1][x+1] == v {
+					score += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	// 10 points for every 5% the share of dark modules deviates from 50%
+	deviation := abs(dark*20 - total*10)
+	score += (deviation + total - 1) / total * 10
+	return score - 10
+}
+
+var finderLike = [2][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func linePenalty(line []bool) int {
+	score := 0
+	for start := 0; start < len(line); {
+		end := start
+		for end < len(line) && line[end] == line[start] {
+			end++
+		}
+		if run := end - start; run >= 5 {
+			score += run - 2
+		}
+		start = end
+	}
+	for i := 0; i+len(finderLike[0]) <= len(line); i++ {
+		for _, pattern := range finderLike {
+			if matches(line[i:], pattern) {
+				score += 40
+			}
+		}
+	}
+	return score
+}
+
+func matches(line, pattern []bool) bool {
+	for i, v := range pattern {
+		if line[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// SVG renders the symbol with its quiet zone as a square of the given size in
+// pixels; dark modules are joined into one path.
+func (c *Code) SVG(size int) string {
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+	side := c.Size + 2*quietZone
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" `+
+		`shape-rendering="crispEdges" role="img"><rect width="%d" height="%d" fill="#fff"/>`+
+		`<path d="%s" fill="#000"/></svg>`, side, side, size, size, side, side, path.String())
+}
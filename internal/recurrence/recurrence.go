@@ -0,0 +1,211 @@
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Freq string
+
+const (
+	Daily           Freq = "DAILY"
+	Weekly          Freq = "WEEKLY"
+	Monthly         Freq = "MONTHLY"
+	AfterCompletion Freq = "AFTER_COMPLETION"
+)
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Rule is a simplified RRULE, stored on a note as e.g. "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,WE".
+type Rule struct {
+	Freq     Freq
+	Interval int
+	Weekdays []time.Weekday
+	MonthDay int
+}
+
+func Parse(s string) (*Rule, error) {
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(strings.TrimSpace(s), ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, errors.Errorf("invalid rule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Freq(strings.ToUpper(value))
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.Errorf("invalid interval %q", value)
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			weekdays, err := ParseWeekdays(strings.Split(value, ","))
+			if err != nil {
+				return nil, err
+			}
+			rule.Weekdays = weekdays
+		case "BYMONTHDAY":
+			day, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.Errorf("invalid month day %q", value)
+			}
+			rule.MonthDay = day
+		default:
+			return nil, errors.Errorf("unknown rule part %q", key)
+		}
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func ParseWeekdays(codes []string) ([]time.Weekday, error) {
+	weekdays := make([]time.Weekday, 0, len(codes))
+	for _, code := range codes {
+		weekday, err := parseWeekday(code)
+		if err != nil {
+			return nil, err
+		}
+		weekdays = append(weekdays, weekday)
+	}
+	return weekdays, nil
+}
+
+func parseWeekday(code string) (time.Weekday, error) {
+	for i, c := range weekdayCodes {
+		if strings.EqualFold(c, strings.TrimSpace(code)) {
+			return time.Weekday(i), nil
+		}
+	}
+	return 0, errors.Errorf("invalid weekday %q", code)
+}
+
+func (r *Rule) Validate() error {
+	if r.Interval < 1 || r.Interval > 365 {
+		return errors.Errorf("interval must be between 1 and 365, got %d", r.Interval)
+	}
+	switch r.Freq {
+	case Daily, AfterCompletion:
+	case Weekly:
+		if len(r.Weekdays) == 0 {
+			return errors.New("weekly rule requires at least one weekday")
+		}
+	case Monthly:
+		if r.MonthDay < 1 || r.MonthDay > 31 {
+			return errors.Errorf("month day must be between 1 and 31, got %d", r.MonthDay)
+		}
+	default:
+		return errors.Errorf("unknown frequency %q", r.Freq)
+	}
+	return nil
+}
+
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq), "INTERVAL=" + strconv.Itoa(r.Interval)}
+	switch r.Freq {
+	case Weekly:
+		codes := make([]string, len(r.Weekdays))
+		for i, weekday := range r.Weekdays {
+			codes[i] = weekdayCodes[weekday]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	case Monthly:
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.MonthDay))
+	case Daily, AfterCompletion:
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the deadline of the occurrence following the one due at deadline.
+// AfterCompletion rules are counted from completedAt instead.
+func (r *Rule) Next(deadline, completedAt time.Time) time.Time {
+	deadline = truncateDay(deadline)
+	switch r.Freq {
+	case AfterCompletion:
+		return truncateDay(completedAt).AddDate(0, 0, r.Interval)
+	case Weekly:
+		return r.nextWeekly(deadline)
+	case Monthly:
+		return r.nextMonthly(deadline)
+	case Daily:
+	}
+	return deadline.AddDate(0, 0, r.Interval)
+}
+
+func (r *Rule) nextWeekly(deadline time.Time) time.Time {
+	weekStart := deadline.AddDate(0, 0, -((int(deadline.Weekday()) + 6) % 7))
+	for day := deadline.AddDate(0, 0, 1); ; day = day.AddDate(0, 0, 1) {
+		week := int(day.Sub(weekStart).Hours()/24) / 7
+		if week%r.Interval == 0 && r.hasWeekday(day.Weekday()) {
+			return day
+		}
+	}
+}
+
+func (r *Rule) hasWeekday(weekday time.Weekday) bool {
+	for _, w := range r.Weekdays {
+		if w == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) nextMonthly(deadline time.Time) time.Time {
+	for months := 0; ; months += r.Interval {
+		first := time.Date(deadline.Year(), deadline.Month()+time.Month(months), 1, 0, 0, 0, 0, deadline.Location())
+		lastDay := first.AddDate(0, 1, -1).Day()
+		day := min(r.MonthDay, lastDay)
+		candidate := first.AddDate(0, 0, day-1)
+		if candidate.After(deadline) {
+			return candidate
+		}
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+var weekdayNames = []string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+
+func (r *Rule) Describe() string {
+	switch r.Freq {
+	case Daily:
+		if r.Interval == 1 {
+			return "каждый день"
+		}
+		return fmt.Sprintf("каждые %d дн.", r.Interval)
+	case Weekly:
+		names := make([]string, len(r.Weekdays))
+		for i, weekday := range r.Weekdays {
+			names[i] = weekdayNames[weekday]
+		}
+		if r.Interval == 1 {
+			return "каждую неделю: " + strings.Join(names, ", ")
+		}
+		return fmt.Sprintf("каждые %d нед.: %s", r.Interval, strings.Join(names, ", "))
+	case Monthly:
+		if r.Interval == 1 {
+			return fmt.Sprintf("каждый месяц, %d числа", r.MonthDay)
+		}
+		return fmt.Sprintf("каждые %d мес., %d числа", r.Interval, r.MonthDay)
+	case AfterCompletion:
+		return fmt.Sprintf("через %d дн. после завершения", r.Interval)
+	}
+	return ""
+}
+
+func WeekdayCode(weekday time.Weekday) string {
+	return weekdayCodes[weekday]
+}
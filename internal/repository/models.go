@@ -8,18 +8,257 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Attachment struct {
+	ID          int64              `db:"id" json:"id"`
+	NoteID      int64              `db:"note_id" json:"note_id"`
+	UserID      int64              `db:"user_id" json:"user_id"`
+	BlobKey     string             `db:"blob_key" json:"blob_key"`
+	ThumbKey    *string            `db:"thumb_key" json:"thumb_key"`
+	FileName    string             `db:"file_name" json:"file_name"`
+	ContentType string             `db:"content_type" json:"content_type"`
+	Size        int64              `db:"size" json:"size"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type BulkActionNote struct {
+	BulkActionID int64              `db:"bulk_action_id" json:"bulk_action_id"`
+	NoteID       int64              `db:"note_id" json:"note_id"`
+	IsCompleted  bool               `db:"is_completed" json:"is_completed"`
+	DeadlineAt   pgtype.Date        `db:"deadline_at" json:"deadline_at"`
+	NotebookID   *int64             `db:"notebook_id" json:"notebook_id"`
+	TrashedAt    pgtype.Timestamptz `db:"trashed_at" json:"trashed_at"`
+	HadTag       bool               `db:"had_tag" json:"had_tag"`
+	Created      bool               `db:"created" json:"created"`
+	CompletedAt  pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
+}
+
+type BulkAction struct {
+	ID        int64              `db:"id" json:"id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Action    string             `db:"action" json:"action"`
+	TagID     *int64             `db:"tag_id" json:"tag_id"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UndoneAt  pgtype.Timestamptz `db:"undone_at" json:"undone_at"`
+}
+
+type DigestSetting struct {
+	UserID     int64       `db:"user_id" json:"user_id"`
+	Enabled    bool        `db:"enabled" json:"enabled"`
+	Email      string      `db:"email" json:"email"`
+	SendTime   pgtype.Time `db:"send_time" json:"send_time"`
+	Timezone   string      `db:"timezone" json:"timezone"`
+	DaysAhead  int32       `db:"days_ahead" json:"days_ahead"`
+	LastSentOn pgtype.Date `db:"last_sent_on" json:"last_sent_on"`
+}
+
+type Export struct {
+	ID         int64              `db:"id" json:"id"`
+	UserID     int64              `db:"user_id" json:"user_id"`
+	Status     string             `db:"status" json:"status"`
+	BlobKey    *string            `db:"blob_key" json:"blob_key"`
+	Size       int64              `db:"size" json:"size"`
+	Error      *string            `db:"error" json:"error"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	FinishedAt pgtype.Timestamptz `db:"finished_at" json:"finished_at"`
+	ClaimedAt  pgtype.Timestamptz `db:"claimed_at" json:"claimed_at"`
+}
+
+type LoginAttempt struct {
+	Key           string             `db:"key" json:"key"`
+	Failures      int32              `db:"failures" json:"failures"`
+	LastFailureAt pgtype.Timestamptz `db:"last_failure_at" json:"last_failure_at"`
+	LockedUntil   pgtype.Timestamptz `db:"locked_until" json:"locked_until"`
+}
+
+type LoginAudit struct {
+	ID        int64              `db:"id" json:"id"`
+	Login     string             `db:"login" json:"login"`
+	Ip        string             `db:"ip" json:"ip"`
+	Reason    string             `db:"reason" json:"reason"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type NoteActivity struct {
+	ID        int64              `db:"id" json:"id"`
+	NoteID    int64              `db:"note_id" json:"note_id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Kind      string             `db:"kind" json:"kind"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type NoteComment struct {
+	ID        int64              `db:"id" json:"id"`
+	NoteID    int64              `db:"note_id" json:"note_id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Body      string             `db:"body" json:"body"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type NoteReference struct {
+	NoteID     int64   `db:"note_id" json:"note_id"`
+	TargetID   *int64  `db:"target_id" json:"target_id"`
+	TargetName *string `db:"target_name" json:"target_name"`
+}
+
+type NoteTag struct {
+	NoteID int64 `db:"note_id" json:"note_id"`
+	TagID  int64 `db:"tag_id" json:"tag_id"`
+}
+
+type NoteTemplate struct {
+	ID             int64    `db:"id" json:"id"`
+	UserID         int64    `db:"user_id" json:"user_id"`
+	Name           string   `db:"name" json:"name"`
+	Title          string   `db:"title" json:"title"`
+	Description    string   `db:"description" json:"description"`
+	Tags           []string `db:"tags" json:"tags"`
+	DeadlineOffset *string  `db:"deadline_offset" json:"deadline_offset"`
+}
+
+type Notebook struct {
+	ID        int64       `db:"id" json:"id"`
+	UserID    int64       `db:"user_id" json:"user_id"`
+	ParentID  *int64      `db:"parent_id" json:"parent_id"`
+	Name      string      `db:"name" json:"name"`
+	CreatedAt pgtype.Date `db:"created_at" json:"created_at"`
+}
+
 type Note struct {
-	ID          int64       `db:"id" json:"id"`
-	UserID      int64       `db:"user_id" json:"user_id"`
-	Name        string      `db:"name" json:"name"`
-	Description *string     `db:"description" json:"description"`
-	IsCompleted bool        `db:"is_completed" json:"is_completed"`
-	CreatedAt   pgtype.Date `db:"created_at" json:"created_at"`
-	DeadlineAt  pgtype.Date `db:"deadline_at" json:"deadline_at"`
+	ID          int64              `db:"id" json:"id"`
+	UserID      int64              `db:"user_id" json:"user_id"`
+	Name        string             `db:"name" json:"name"`
+	Description *string            `db:"description" json:"description"`
+	IsCompleted bool               `db:"is_completed" json:"is_completed"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	DeadlineAt  pgtype.Date        `db:"deadline_at" json:"deadline_at"`
+	Recurrence  *string            `db:"recurrence" json:"recurrence"`
+	SeriesID    *int64             `db:"series_id" json:"series_id"`
+	NotebookID  *int64             `db:"notebook_id" json:"notebook_id"`
+	TrashedAt   pgtype.Timestamptz `db:"trashed_at" json:"trashed_at"`
+	Priority    int16              `db:"priority" json:"priority"`
+	Pinned      bool               `db:"pinned" json:"pinned"`
+	Version     int32              `db:"version" json:"version"`
+	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	CompletedAt pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
+}
+
+type Notification struct {
+	ID        int64              `db:"id" json:"id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	ActorID   int64              `db:"actor_id" json:"actor_id"`
+	NoteID    int64              `db:"note_id" json:"note_id"`
+	CommentID *int64             `db:"comment_id" json:"comment_id"`
+	Kind      string             `db:"kind" json:"kind"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	ReadAt    pgtype.Timestamptz `db:"read_at" json:"read_at"`
+}
+
+type PasskeyCeremony struct {
+	TokenHash string             `db:"token_hash" json:"token_hash"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Challenge []byte             `db:"challenge" json:"challenge"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+}
+
+type PendingLogin struct {
+	TokenHash string             `db:"token_hash" json:"token_hash"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+}
+
+type PublicLink struct {
+	ID        int64              `db:"id" json:"id"`
+	NoteID    int64              `db:"note_id" json:"note_id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Token     string             `db:"token" json:"token"`
+	Password  *string            `db:"password" json:"password"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	RevokedAt pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID        int64              `db:"id" json:"id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	CodeHash  string             `db:"code_hash" json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `db:"used_at" json:"used_at"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type Session struct {
+	TokenHash string             `db:"token_hash" json:"token_hash"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+}
+
+type Share struct {
+	ID         int64       `db:"id" json:"id"`
+	OwnerID    int64       `db:"owner_id" json:"owner_id"`
+	UserID     int64       `db:"user_id" json:"user_id"`
+	NoteID     *int64      `db:"note_id" json:"note_id"`
+	NotebookID *int64      `db:"notebook_id" json:"notebook_id"`
+	Permission string      `db:"permission" json:"permission"`
+	CreatedAt  pgtype.Date `db:"created_at" json:"created_at"`
+}
+
+type Tag struct {
+	ID     int64  `db:"id" json:"id"`
+	UserID int64  `db:"user_id" json:"user_id"`
+	Name   string `db:"name" json:"name"`
 }
 
 type User struct {
-	ID       int64  `db:"id" json:"id"`
-	Login    string `db:"login" json:"login"`
-	Password string `db:"password" json:"password"`
+	ID                    int64              `db:"id" json:"id"`
+	Login                 string             `db:"login" json:"login"`
+	Password              string             `db:"password" json:"password"`
+	Role                  string             `db:"role" json:"role"`
+	DisabledAt            pgtype.Timestamptz `db:"disabled_at" json:"disabled_at"`
+	PasswordResetRequired bool               `db:"password_reset_required" json:"password_reset_required"`
+	LastLoginAt           pgtype.Timestamptz `db:"last_login_at" json:"last_login_at"`
+	TotpSecret            *string            `db:"totp_secret" json:"totp_secret"`
+	TotpEnabledAt         pgtype.Timestamptz `db:"totp_enabled_at" json:"totp_enabled_at"`
+	TotpLastCounter       int64              `db:"totp_last_counter" json:"totp_last_counter"`
+}
+
+type WebauthnCredential struct {
+	ID           int64              `db:"id" json:"id"`
+	UserID       int64              `db:"user_id" json:"user_id"`
+	Name         string             `db:"name" json:"name"`
+	CredentialID []byte             `db:"credential_id" json:"credential_id"`
+	PublicKey    []byte             `db:"public_key" json:"public_key"`
+	SignCount    int64              `db:"sign_count" json:"sign_count"`
+	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	LastUsedAt   pgtype.Timestamptz `db:"last_used_at" json:"last_used_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64              `db:"id" json:"id"`
+	WebhookID      int64              `db:"webhook_id" json:"webhook_id"`
+	Event          string             `db:"event" json:"event"`
+	Payload        string             `db:"payload" json:"payload"`
+	Status         string             `db:"status" json:"status"`
+	Attempts       int32              `db:"attempts" json:"attempts"`
+	NextAttemptAt  pgtype.Timestamptz `db:"next_attempt_at" json:"next_attempt_at"`
+	ResponseStatus *int32             `db:"response_status" json:"response_status"`
+	Error          *string            `db:"error" json:"error"`
+	CreatedAt      pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	DeliveredAt    pgtype.Timestamptz `db:"delivered_at" json:"delivered_at"`
+	ClaimedAt      pgtype.Timestamptz `db:"claimed_at" json:"claimed_at"`
+}
+
+type WebhookOverdueNote struct {
+	NoteID     int64       `db:"note_id" json:"note_id"`
+	DeadlineAt pgtype.Date `db:"deadline_at" json:"deadline_at"`
+}
+
+type Webhook struct {
+	ID        int64              `db:"id" json:"id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Url       string             `db:"url" json:"url"`
+	Events    []string           `db:"events" json:"events"`
+	Secret    string             `db:"secret" json:"secret"`
+	Active    bool               `db:"active" json:"active"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
 }
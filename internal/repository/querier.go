@@ -6,18 +6,191 @@ package repository
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	AddBulkActionCreatedNote(ctx context.Context, arg AddBulkActionCreatedNoteParams) error
+	AddNoteReference(ctx context.Context, arg AddNoteReferenceParams) error
+	AddNoteTag(ctx context.Context, arg AddNoteTagParams) error
+	BulkAddTag(ctx context.Context, arg BulkAddTagParams) error
+	BulkRemoveTag(ctx context.Context, arg BulkRemoveTagParams) error
+	BulkSetCompleted(ctx context.Context, arg BulkSetCompletedParams) error
+	BulkSetDeadline(ctx context.Context, arg BulkSetDeadlineParams) error
+	BulkSetNotebook(ctx context.Context, arg BulkSetNotebookParams) error
+	BulkTrash(ctx context.Context, bulkActionID int64) error
 	ChangeNoteStatus(ctx context.Context, arg ChangeNoteStatusParams) (int64, error)
+	ClaimDigest(ctx context.Context, arg ClaimDigestParams) (int64, error)
+	ClaimExport(ctx context.Context) (*Export, error)
+	ClaimWebhookDelivery(ctx context.Context) (*WebhookDelivery, error)
+	ClearLoginAttempts(ctx context.Context, key string) error
+	CopySeriesReferences(ctx context.Context, arg CopySeriesReferencesParams) error
+	CountActiveExportsByUserId(ctx context.Context, userID int64) (int64, error)
+	CountBulkActionNotes(ctx context.Context, bulkActionID int64) (int64, error)
+	CountNotesByName(ctx context.Context, arg CountNotesByNameParams) (int64, error)
+	CountOpenSeriesNotes(ctx context.Context, arg CountOpenSeriesNotesParams) (int64, error)
+	CountUnreadNotifications(ctx context.Context, userID int64) (int64, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int64, error)
+	CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (int64, error)
+	CreateBulkAction(ctx context.Context, arg CreateBulkActionParams) (int64, error)
+	CreateComment(ctx context.Context, arg CreateCommentParams) (int64, error)
+	CreateExport(ctx context.Context, userID int64) (int64, error)
+	CreateLoginAudit(ctx context.Context, arg CreateLoginAuditParams) error
 	CreateNote(ctx context.Context, arg CreateNoteParams) (int64, error)
+	CreateNoteActivity(ctx context.Context, arg CreateNoteActivityParams) error
+	CreateNoteTemplate(ctx context.Context, arg CreateNoteTemplateParams) (int64, error)
+	CreateNotebook(ctx context.Context, arg CreateNotebookParams) (int64, error)
+	CreateNotification(ctx context.Context, arg CreateNotificationParams) error
+	CreatePasskeyCeremony(ctx context.Context, arg CreatePasskeyCeremonyParams) error
+	CreatePendingLogin(ctx context.Context, arg CreatePendingLoginParams) error
+	CreatePublicLink(ctx context.Context, arg CreatePublicLinkParams) (int64, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) error
 	CreateUser(ctx context.Context, arg CreateUserParams) (int64, error)
+	CreateWebauthnCredential(ctx context.Context, arg CreateWebauthnCredentialParams) (int64, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (int64, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (*WebhookDelivery, error)
+	DeleteAttachmentById(ctx context.Context, id int64) error
+	DeleteBulkActionCreatedNotes(ctx context.Context, bulkActionID int64) error
+	DeleteBulkActionsByUserId(ctx context.Context, userID int64) error
+	DeleteComment(ctx context.Context, arg DeleteCommentParams) (int64, error)
+	DeleteExpiredPasskeyCeremonies(ctx context.Context) error
+	DeleteExpiredPendingLogins(ctx context.Context) error
+	DeleteExpiredSessions(ctx context.Context) error
+	DeleteExportById(ctx context.Context, id int64) error
 	DeleteNoteById(ctx context.Context, id int64) (int64, error)
+	DeleteNoteReferences(ctx context.Context, noteID int64) error
+	DeleteNoteTemplate(ctx context.Context, arg DeleteNoteTemplateParams) (int64, error)
+	DeleteNotebookById(ctx context.Context, id int64) error
+	DeletePendingLogin(ctx context.Context, tokenHash string) error
+	DeleteRecoveryCodes(ctx context.Context, userID int64) error
+	DeleteSeriesReferences(ctx context.Context, arg DeleteSeriesReferencesParams) error
+	DeleteSession(ctx context.Context, tokenHash string) error
+	DeleteShare(ctx context.Context, arg DeleteShareParams) error
+	DeleteUserSessions(ctx context.Context, userID int64) (int64, error)
+	DeleteWebauthnCredential(ctx context.Context, arg DeleteWebauthnCredentialParams) (int64, error)
+	DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error)
+	EnableUserTotp(ctx context.Context, arg EnableUserTotpParams) (int64, error)
+	FailExport(ctx context.Context, arg FailExportParams) error
+	FailWebhookDelivery(ctx context.Context, arg FailWebhookDeliveryParams) error
+	FinishExport(ctx context.Context, arg FinishExportParams) error
+	FinishWebhookDelivery(ctx context.Context, arg FinishWebhookDeliveryParams) error
+	GetActivePublicLinksByUserId(ctx context.Context, userID int64) ([]*GetActivePublicLinksByUserIdRow, error)
+	GetAllNotesByUserId(ctx context.Context, userID int64) ([]*Note, error)
+	GetAttachmentById(ctx context.Context, id int64) (*Attachment, error)
+	GetAttachmentsByNoteId(ctx context.Context, noteID int64) ([]*Attachment, error)
+	GetAttachmentsByUserId(ctx context.Context, userID int64) ([]*Attachment, error)
+	GetBacklinks(ctx context.Context, arg GetBacklinksParams) ([]*Note, error)
+	GetBrokenReferences(ctx context.Context, userID int64) ([]*GetBrokenReferencesRow, error)
+	GetBulkActionNotes(ctx context.Context, bulkActionID int64) ([]*Note, error)
+	GetCommentById(ctx context.Context, id int64) (*NoteComment, error)
+	GetCommentsByNoteId(ctx context.Context, noteID int64) ([]*GetCommentsByNoteIdRow, error)
+	GetDigestSettingsByUserId(ctx context.Context, userID int64) (*DigestSetting, error)
+	GetEnabledDigestSettings(ctx context.Context) ([]*DigestSetting, error)
+	GetExpiredExports(ctx context.Context, before pgtype.Timestamptz) ([]*Export, error)
+	GetExpiredNotesByUserId(ctx context.Context, arg GetExpiredNotesByUserIdParams) ([]*Note, error)
+	GetExportById(ctx context.Context, id int64) (*Export, error)
+	GetExportsByUserId(ctx context.Context, userID int64) ([]*Export, error)
+	GetLastBulkAction(ctx context.Context, userID int64) (*BulkAction, error)
+	GetLinkTargets(ctx context.Context, arg GetLinkTargetsParams) ([]*Note, error)
+	GetLoginAttemptForUpdate(ctx context.Context, key string) (*GetLoginAttemptForUpdateRow, error)
+	GetNoteActivityByNoteId(ctx context.Context, noteID int64) ([]*GetNoteActivityByNoteIdRow, error)
+	GetNoteAudience(ctx context.Context, noteID int64) ([]int64, error)
 	GetNoteById(ctx context.Context, id int64) (*Note, error)
+	GetNoteCompletionStats(ctx context.Context, userID int64) (*GetNoteCompletionStatsRow, error)
+	GetNoteSharePermission(ctx context.Context, arg GetNoteSharePermissionParams) (*GetNoteSharePermissionRow, error)
+	GetNoteTemplateById(ctx context.Context, arg GetNoteTemplateByIdParams) (*NoteTemplate, error)
+	GetNoteTemplatesByUserId(ctx context.Context, userID int64) ([]*NoteTemplate, error)
+	GetNoteWebhooks(ctx context.Context, arg GetNoteWebhooksParams) ([]*Webhook, error)
+	GetNotebookById(ctx context.Context, id int64) (*Notebook, error)
+	GetNotebookNoteCounts(ctx context.Context, userID int64) ([]*GetNotebookNoteCountsRow, error)
+	GetNotebookNoteStats(ctx context.Context, userID int64) ([]*GetNotebookNoteStatsRow, error)
+	GetNotebookSubtreeIds(ctx context.Context, id int64) ([]int64, error)
+	GetNotebooksByUserId(ctx context.Context, userID int64) ([]*Notebook, error)
 	GetNotesByUserId(ctx context.Context, userID int64) ([]*Note, error)
+	GetNotesByUserIdAndNotebook(ctx context.Context, arg GetNotesByUserIdAndNotebookParams) ([]*Note, error)
 	GetNotesByUserIdAndSearch(ctx context.Context, arg GetNotesByUserIdAndSearchParams) ([]*Note, error)
+	GetNotesReferencingName(ctx context.Context, arg GetNotesReferencingNameParams) ([]*Note, error)
+	GetNotesSharedWithUser(ctx context.Context, userID int64) ([]*GetNotesSharedWithUserRow, error)
+	GetNotificationsByUserId(ctx context.Context, userID int64) ([]*GetNotificationsByUserIdRow, error)
+	GetOverdueNotesForWebhooks(ctx context.Context) ([]*Note, error)
+	GetPendingLoginUserId(ctx context.Context, tokenHash string) (int64, error)
+	GetPublicLinkByToken(ctx context.Context, token string) (*PublicLink, error)
+	GetRecentLoginAudit(ctx context.Context, limit int32) ([]*LoginAudit, error)
+	GetSessionUser(ctx context.Context, tokenHash string) (*User, error)
+	GetShareById(ctx context.Context, id int64) (*Share, error)
+	GetSharesByNoteId(ctx context.Context, noteID *int64) ([]*GetSharesByNoteIdRow, error)
+	GetSharesByNotebookId(ctx context.Context, notebookID *int64) ([]*GetSharesByNotebookIdRow, error)
+	GetSystemStats(ctx context.Context) (*GetSystemStatsRow, error)
+	GetTagByName(ctx context.Context, arg GetTagByNameParams) (*Tag, error)
+	GetTagNoteStats(ctx context.Context, userID int64) ([]*GetTagNoteStatsRow, error)
+	GetTagsByNoteIds(ctx context.Context, noteIds []int64) ([]*GetTagsByNoteIdsRow, error)
+	GetTrashedNotesByUserId(ctx context.Context, userID int64) ([]*Note, error)
+	GetUpcomingNotesByUserId(ctx context.Context, arg GetUpcomingNotesByUserIdParams) ([]*Note, error)
+	GetUserAttachmentsSize(ctx context.Context, userID int64) (int64, error)
+	GetUserById(ctx context.Context, id int64) (*User, error)
+	GetUserByLogin(ctx context.Context, login string) (*User, error)
 	GetUserByLoginAndPassword(ctx context.Context, arg GetUserByLoginAndPasswordParams) (*User, error)
-	UpdateNote(ctx context.Context, arg UpdateNoteParams) (int64, error)
+	GetUsersByLogins(ctx context.Context, logins []string) ([]*User, error)
+	GetUsersWithNoteCounts(ctx context.Context) ([]*GetUsersWithNoteCountsRow, error)
+	GetWebauthnCredentialByCredentialId(ctx context.Context, credentialID []byte) (*WebauthnCredential, error)
+	GetWebauthnCredentialsByUserId(ctx context.Context, userID int64) ([]*WebauthnCredential, error)
+	GetWebhookById(ctx context.Context, id int64) (*Webhook, error)
+	GetWebhookDeliveriesByUserId(ctx context.Context, userID int64) ([]*GetWebhookDeliveriesByUserIdRow, error)
+	GetWebhooksByUserId(ctx context.Context, userID int64) ([]*Webhook, error)
+	GetWeeklyNoteStats(ctx context.Context, arg GetWeeklyNoteStatsParams) ([]*GetWeeklyNoteStatsRow, error)
+	ImportNote(ctx context.Context, arg ImportNoteParams) (int64, error)
+	LockLoginAttempts(ctx context.Context, arg LockLoginAttemptsParams) error
+	LockUserForUpdate(ctx context.Context, id int64) error
+	MarkBulkActionUndone(ctx context.Context, id int64) (int64, error)
+	MarkNoteOverdueSent(ctx context.Context, arg MarkNoteOverdueSentParams) (int64, error)
+	MarkNotificationsRead(ctx context.Context, userID int64) error
+	MoveNotebook(ctx context.Context, arg MoveNotebookParams) error
+	MoveNotesBetweenNotebooks(ctx context.Context, arg MoveNotesBetweenNotebooksParams) (int64, error)
+	ReclaimExports(ctx context.Context, leaseSeconds float64) (int64, error)
+	ReclaimWebhookDeliveries(ctx context.Context, leaseSeconds float64) (int64, error)
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (int32, error)
+	ReleaseDigest(ctx context.Context, arg ReleaseDigestParams) error
+	ReleaseLoginAttempt(ctx context.Context, arg ReleaseLoginAttemptParams) error
+	RenameNotebook(ctx context.Context, arg RenameNotebookParams) error
+	RenewExportLease(ctx context.Context, id int64) error
+	RequeueWebhookDelivery(ctx context.Context, arg RequeueWebhookDeliveryParams) (int64, error)
+	RequireUserPasswordReset(ctx context.Context, id int64) (int64, error)
+	ResetUserTotp(ctx context.Context, id int64) (int64, error)
+	RestoreNote(ctx context.Context, id int64) error
+	RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) error
+	RevokePublicLink(ctx context.Context, arg RevokePublicLinkParams) (int64, error)
+	SetNoteNotebook(ctx context.Context, arg SetNoteNotebookParams) error
+	SetNotePinned(ctx context.Context, arg SetNotePinnedParams) error
+	SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (int64, error)
+	SetUserLastLogin(ctx context.Context, id int64) error
+	SetUserTotpSecret(ctx context.Context, arg SetUserTotpSecretParams) (int64, error)
+	SetWebhookActive(ctx context.Context, arg SetWebhookActiveParams) (int64, error)
+	ShareNote(ctx context.Context, arg ShareNoteParams) error
+	ShareNotebook(ctx context.Context, arg ShareNotebookParams) error
+	SnapshotBulkActionNotes(ctx context.Context, arg SnapshotBulkActionNotesParams) (int64, error)
+	StopNoteSeries(ctx context.Context, seriesID int64) (int64, error)
+	TakePasskeyCeremony(ctx context.Context, arg TakePasskeyCeremonyParams) ([]byte, error)
+	TrashNote(ctx context.Context, id int64) error
+	TrashNotesInNotebooks(ctx context.Context, notebookIds []int64) (int64, error)
+	UndoBulkAddTag(ctx context.Context, arg UndoBulkAddTagParams) error
+	UndoBulkCompleted(ctx context.Context, bulkActionID int64) error
+	UndoBulkDeadline(ctx context.Context, bulkActionID int64) error
+	UndoBulkNotebook(ctx context.Context, bulkActionID int64) error
+	UndoBulkRemoveTag(ctx context.Context, arg UndoBulkRemoveTagParams) error
+	UndoBulkTrash(ctx context.Context, bulkActionID int64) error
+	UpdateComment(ctx context.Context, arg UpdateCommentParams) (int64, error)
+	UpdateNote(ctx context.Context, arg UpdateNoteParams) (int32, error)
+	UpdateNoteDescription(ctx context.Context, arg UpdateNoteDescriptionParams) (int32, error)
+	UpdateNoteSeries(ctx context.Context, arg UpdateNoteSeriesParams) (int64, error)
+	UpdateNoteTemplate(ctx context.Context, arg UpdateNoteTemplateParams) (int64, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	UpdateWebauthnCredentialUsage(ctx context.Context, arg UpdateWebauthnCredentialUsageParams) (int64, error)
+	UpsertDigestSettings(ctx context.Context, arg UpsertDigestSettingsParams) error
+	UpsertTag(ctx context.Context, arg UpsertTagParams) (int64, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTotpCounter(ctx context.Context, arg UseTotpCounterParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -11,9 +11,160 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const AddBulkActionCreatedNote = `-- name: AddBulkActionCreatedNote :exec
+INSERT INTO bulk_action_notes (bulk_action_id, note_id, is_completed, created)
+VALUES ($1, $2, FALSE, TRUE)
+`
+
+type AddBulkActionCreatedNoteParams struct {
+	BulkActionID int64 `db:"bulk_action_id" json:"bulk_action_id"`
+	NoteID       int64 `db:"note_id" json:"note_id"`
+}
+
+func (q *Queries) AddBulkActionCreatedNote(ctx context.Context, arg AddBulkActionCreatedNoteParams) error {
+	_, err := q.db.Exec(ctx, AddBulkActionCreatedNote, arg.BulkActionID, arg.NoteID)
+	return err
+}
+
+const AddNoteReference = `-- name: AddNoteReference :exec
+INSERT INTO note_references (note_id, target_id, target_name)
+VALUES ($1, $2::BIGINT, $3::TEXT)
+`
+
+type AddNoteReferenceParams struct {
+	NoteID     int64   `db:"note_id" json:"note_id"`
+	TargetID   *int64  `db:"target_id" json:"target_id"`
+	TargetName *string `db:"target_name" json:"target_name"`
+}
+
+func (q *Queries) AddNoteReference(ctx context.Context, arg AddNoteReferenceParams) error {
+	_, err := q.db.Exec(ctx, AddNoteReference, arg.NoteID, arg.TargetID, arg.TargetName)
+	return err
+}
+
+const AddNoteTag = `-- name: AddNoteTag :exec
+INSERT INTO note_tags (note_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type AddNoteTagParams struct {
+	NoteID int64 `db:"note_id" json:"note_id"`
+	TagID  int64 `db:"tag_id" json:"tag_id"`
+}
+
+func (q *Queries) AddNoteTag(ctx context.Context, arg AddNoteTagParams) error {
+	_, err := q.db.Exec(ctx, AddNoteTag, arg.NoteID, arg.TagID)
+	return err
+}
+
+const BulkAddTag = `-- name: BulkAddTag :exec
+INSERT INTO note_tags (note_id, tag_id)
+SELECT b.note_id, $1::BIGINT
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = $2::BIGINT
+ON CONFLICT DO NOTHING
+`
+
+type BulkAddTagParams struct {
+	TagID        int64 `db:"tag_id" json:"tag_id"`
+	BulkActionID int64 `db:"bulk_action_id" json:"bulk_action_id"`
+}
+
+func (q *Queries) BulkAddTag(ctx context.Context, arg BulkAddTagParams) error {
+	_, err := q.db.Exec(ctx, BulkAddTag, arg.TagID, arg.BulkActionID)
+	return err
+}
+
+const BulkRemoveTag = `-- name: BulkRemoveTag :exec
+DELETE
+FROM note_tags
+WHERE tag_id = $1::BIGINT
+  AND note_id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = $2::BIGINT)
+`
+
+type BulkRemoveTagParams struct {
+	TagID        int64 `db:"tag_id" json:"tag_id"`
+	BulkActionID int64 `db:"bulk_action_id" json:"bulk_action_id"`
+}
+
+func (q *Queries) BulkRemoveTag(ctx context.Context, arg BulkRemoveTagParams) error {
+	_, err := q.db.Exec(ctx, BulkRemoveTag, arg.TagID, arg.BulkActionID)
+	return err
+}
+
+const BulkSetCompleted = `-- name: BulkSetCompleted :exec
+UPDATE notes
+SET is_completed = $1,
+    version      = version + 1,
+    updated_at   = NOW(),
+    completed_at = CASE WHEN $1 THEN COALESCE(completed_at, NOW()) END
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = $2::BIGINT)
+`
+
+type BulkSetCompletedParams struct {
+	IsCompleted  bool  `db:"is_completed" json:"is_completed"`
+	BulkActionID int64 `db:"bulk_action_id" json:"bulk_action_id"`
+}
+
+func (q *Queries) BulkSetCompleted(ctx context.Context, arg BulkSetCompletedParams) error {
+	_, err := q.db.Exec(ctx, BulkSetCompleted, arg.IsCompleted, arg.BulkActionID)
+	return err
+}
+
+const BulkSetDeadline = `-- name: BulkSetDeadline :exec
+UPDATE notes
+SET deadline_at = $1::DATE,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = $2::BIGINT)
+`
+
+type BulkSetDeadlineParams struct {
+	DeadlineAt   pgtype.Date `db:"deadline_at" json:"deadline_at"`
+	BulkActionID int64       `db:"bulk_action_id" json:"bulk_action_id"`
+}
+
+func (q *Queries) BulkSetDeadline(ctx context.Context, arg BulkSetDeadlineParams) error {
+	_, err := q.db.Exec(ctx, BulkSetDeadline, arg.DeadlineAt, arg.BulkActionID)
+	return err
+}
+
+const BulkSetNotebook = `-- name: BulkSetNotebook :exec
+UPDATE notes
+SET notebook_id = $1::BIGINT,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = $2::BIGINT)
+`
+
+type BulkSetNotebookParams struct {
+	NotebookID   *int64 `db:"notebook_id" json:"notebook_id"`
+	BulkActionID int64  `db:"bulk_action_id" json:"bulk_action_id"`
+}
+
+func (q *Queries) BulkSetNotebook(ctx context.Context, arg BulkSetNotebookParams) error {
+	_, err := q.db.Exec(ctx, BulkSetNotebook, arg.NotebookID, arg.BulkActionID)
+	return err
+}
+
+const BulkTrash = `-- name: BulkTrash :exec
+UPDATE notes
+SET trashed_at = NOW()
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = $1::BIGINT)
+`
+
+func (q *Queries) BulkTrash(ctx context.Context, bulkActionID int64) error {
+	_, err := q.db.Exec(ctx, BulkTrash, bulkActionID)
+	return err
+}
+
 const ChangeNoteStatus = `-- name: ChangeNoteStatus :one
 UPDATE notes
-SET is_completed = $1
+SET is_completed = $1,
+    version      = version + 1,
+    updated_at   = NOW(),
+    completed_at = CASE WHEN $1 THEN COALESCE(completed_at, NOW()) END
 WHERE id = $2
 RETURNING id
 `
@@ -30,176 +181,4164 @@ func (q *Queries) ChangeNoteStatus(ctx context.Context, arg ChangeNoteStatusPara
 	return id, err
 }
 
-const CreateNote = `-- name: CreateNote :one
-INSERT INTO notes (user_id, name, description, deadline_at)
+const ClaimDigest = `-- name: ClaimDigest :execrows
+UPDATE digest_settings
+SET last_sent_on = $1
+WHERE user_id = $2
+  AND (last_sent_on IS NULL OR last_sent_on < $1)
+`
+
+type ClaimDigestParams struct {
+	Today  pgtype.Date `db:"today" json:"today"`
+	UserID int64       `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) ClaimDigest(ctx context.Context, arg ClaimDigestParams) (int64, error) {
+	result, err := q.db.Exec(ctx, ClaimDigest, arg.Today, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const ClaimExport = `-- name: ClaimExport :one
+UPDATE exports
+SET status     = 'running',
+    claimed_at = NOW()
+WHERE id = (SELECT e.id
+            FROM exports e
+            WHERE e.status = 'pending'
+            ORDER BY e.id
+            LIMIT 1 FOR UPDATE SKIP LOCKED)
+RETURNING id, user_id, status, blob_key, size, error, created_at, finished_at, claimed_at
+`
+
+func (q *Queries) ClaimExport(ctx context.Context) (*Export, error) {
+	row := q.db.QueryRow(ctx, ClaimExport)
+	var i Export
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.BlobKey,
+		&i.Size,
+		&i.Error,
+		&i.CreatedAt,
+		&i.FinishedAt,
+		&i.ClaimedAt,
+	)
+	return &i, err
+}
+
+const ClaimWebhookDelivery = `-- name: ClaimWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status     = 'running',
+    claimed_at = NOW()
+WHERE id = (SELECT d.id
+            FROM webhook_deliveries d
+            WHERE d.status = 'pending'
+              AND d.next_attempt_at <= NOW()
+            ORDER BY d.next_attempt_at, d.id
+            LIMIT 1 FOR UPDATE SKIP LOCKED)
+RETURNING id, webhook_id, event, payload, status, attempts, next_attempt_at, response_status, error, created_at, delivered_at, claimed_at
+`
+
+func (q *Queries) ClaimWebhookDelivery(ctx context.Context) (*WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, ClaimWebhookDelivery)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.Event,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseStatus,
+		&i.Error,
+		&i.CreatedAt,
+		&i.DeliveredAt,
+		&i.ClaimedAt,
+	)
+	return &i, err
+}
+
+const ClearLoginAttempts = `-- name: ClearLoginAttempts :exec
+DELETE
+FROM login_attempts
+WHERE key = $1
+`
+
+func (q *Queries) ClearLoginAttempts(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, ClearLoginAttempts, key)
+	return err
+}
+
+const CopySeriesReferences = `-- name: CopySeriesReferences :exec
+INSERT INTO note_references (note_id, target_id, target_name)
+SELECT n.id, r.target_id, r.target_name
+FROM notes n
+         JOIN note_references r ON r.note_id = $1::BIGINT
+WHERE COALESCE(n.series_id, n.id) = $2::BIGINT
+  AND n.is_completed = FALSE
+  AND n.id <> $1::BIGINT
+`
+
+type CopySeriesReferencesParams struct {
+	NoteID   int64 `db:"note_id" json:"note_id"`
+	SeriesID int64 `db:"series_id" json:"series_id"`
+}
+
+func (q *Queries) CopySeriesReferences(ctx context.Context, arg CopySeriesReferencesParams) error {
+	_, err := q.db.Exec(ctx, CopySeriesReferences, arg.NoteID, arg.SeriesID)
+	return err
+}
+
+const CountActiveExportsByUserId = `-- name: CountActiveExportsByUserId :one
+SELECT COUNT(*)
+FROM exports e
+WHERE e.user_id = $1
+  AND e.status IN ('pending', 'running')
+`
+
+func (q *Queries) CountActiveExportsByUserId(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountActiveExportsByUserId, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountBulkActionNotes = `-- name: CountBulkActionNotes :one
+SELECT COUNT(*)
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = $1
+  AND b.created = FALSE
+`
+
+func (q *Queries) CountBulkActionNotes(ctx context.Context, bulkActionID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountBulkActionNotes, bulkActionID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountNotesByName = `-- name: CountNotesByName :one
+SELECT COUNT(*)
+FROM notes n
+WHERE n.user_id = $1
+  AND n.trashed_at IS NULL
+  AND n.id <> $2::BIGINT
+  AND LOWER(n.name) = LOWER($3::TEXT)
+`
+
+type CountNotesByNameParams struct {
+	UserID    int64  `db:"user_id" json:"user_id"`
+	ExcludeID int64  `db:"exclude_id" json:"exclude_id"`
+	Name      string `db:"name" json:"name"`
+}
+
+func (q *Queries) CountNotesByName(ctx context.Context, arg CountNotesByNameParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CountNotesByName, arg.UserID, arg.ExcludeID, arg.Name)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountOpenSeriesNotes = `-- name: CountOpenSeriesNotes :one
+SELECT COUNT(*)
+FROM notes n
+WHERE COALESCE(n.series_id, n.id) = $1::BIGINT
+  AND n.is_completed = FALSE
+  AND n.trashed_at IS NULL
+  AND n.id <> $2
+`
+
+type CountOpenSeriesNotesParams struct {
+	SeriesID int64 `db:"series_id" json:"series_id"`
+	ID       int64 `db:"id" json:"id"`
+}
+
+func (q *Queries) CountOpenSeriesNotes(ctx context.Context, arg CountOpenSeriesNotesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CountOpenSeriesNotes, arg.SeriesID, arg.ID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountUnreadNotifications = `-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications nf
+WHERE nf.user_id = $1
+  AND nf.read_at IS NULL
+`
+
+func (q *Queries) CountUnreadNotifications(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountUnreadNotifications, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountUnusedRecoveryCodes = `-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*)
+FROM recovery_codes
+WHERE user_id = $1
+  AND used_at IS NULL
+`
+
+func (q *Queries) CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountUnusedRecoveryCodes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreateAttachment = `-- name: CreateAttachment :one
+INSERT INTO attachments (note_id, user_id, blob_key, thumb_key, file_name, content_type, size)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id
+`
+
+type CreateAttachmentParams struct {
+	NoteID      int64   `db:"note_id" json:"note_id"`
+	UserID      int64   `db:"user_id" json:"user_id"`
+	BlobKey     string  `db:"blob_key" json:"blob_key"`
+	ThumbKey    *string `db:"thumb_key" json:"thumb_key"`
+	FileName    string  `db:"file_name" json:"file_name"`
+	ContentType string  `db:"content_type" json:"content_type"`
+	Size        int64   `db:"size" json:"size"`
+}
+
+func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateAttachment,
+		arg.NoteID,
+		arg.UserID,
+		arg.BlobKey,
+		arg.ThumbKey,
+		arg.FileName,
+		arg.ContentType,
+		arg.Size,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateBulkAction = `-- name: CreateBulkAction :one
+INSERT INTO bulk_actions (user_id, action, tag_id)
+VALUES ($1, $2, $3)
+RETURNING id
+`
+
+type CreateBulkActionParams struct {
+	UserID int64  `db:"user_id" json:"user_id"`
+	Action string `db:"action" json:"action"`
+	TagID  *int64 `db:"tag_id" json:"tag_id"`
+}
+
+func (q *Queries) CreateBulkAction(ctx context.Context, arg CreateBulkActionParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateBulkAction, arg.UserID, arg.Action, arg.TagID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateComment = `-- name: CreateComment :one
+INSERT INTO note_comments (note_id, user_id, body)
+VALUES ($1, $2, $3)
+RETURNING id
+`
+
+type CreateCommentParams struct {
+	NoteID int64  `db:"note_id" json:"note_id"`
+	UserID int64  `db:"user_id" json:"user_id"`
+	Body   string `db:"body" json:"body"`
+}
+
+func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateComment, arg.NoteID, arg.UserID, arg.Body)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateExport = `-- name: CreateExport :one
+INSERT INTO exports (user_id)
+VALUES ($1)
+RETURNING id
+`
+
+func (q *Queries) CreateExport(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateExport, userID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateLoginAudit = `-- name: CreateLoginAudit :exec
+INSERT INTO login_audit (login, ip, reason)
+VALUES ($1, $2, $3)
+`
+
+type CreateLoginAuditParams struct {
+	Login  string `db:"login" json:"login"`
+	Ip     string `db:"ip" json:"ip"`
+	Reason string `db:"reason" json:"reason"`
+}
+
+func (q *Queries) CreateLoginAudit(ctx context.Context, arg CreateLoginAuditParams) error {
+	_, err := q.db.Exec(ctx, CreateLoginAudit, arg.Login, arg.Ip, arg.Reason)
+	return err
+}
+
+const CreateNote = `-- name: CreateNote :one
+INSERT INTO notes (user_id, name, description, deadline_at, recurrence, series_id, notebook_id, priority, pinned)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id
+`
+
+type CreateNoteParams struct {
+	UserID      int64       `db:"user_id" json:"user_id"`
+	Name        string      `db:"name" json:"name"`
+	Description *string     `db:"description" json:"description"`
+	DeadlineAt  pgtype.Date `db:"deadline_at" json:"deadline_at"`
+	Recurrence  *string     `db:"recurrence" json:"recurrence"`
+	SeriesID    *int64      `db:"series_id" json:"series_id"`
+	NotebookID  *int64      `db:"notebook_id" json:"notebook_id"`
+	Priority    int16       `db:"priority" json:"priority"`
+	Pinned      bool        `db:"pinned" json:"pinned"`
+}
+
+func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateNote,
+		arg.UserID,
+		arg.Name,
+		arg.Description,
+		arg.DeadlineAt,
+		arg.Recurrence,
+		arg.SeriesID,
+		arg.NotebookID,
+		arg.Priority,
+		arg.Pinned,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateNoteActivity = `-- name: CreateNoteActivity :exec
+INSERT INTO note_activity (note_id, user_id, kind)
+VALUES ($1, $2, $3)
+`
+
+type CreateNoteActivityParams struct {
+	NoteID int64  `db:"note_id" json:"note_id"`
+	UserID int64  `db:"user_id" json:"user_id"`
+	Kind   string `db:"kind" json:"kind"`
+}
+
+func (q *Queries) CreateNoteActivity(ctx context.Context, arg CreateNoteActivityParams) error {
+	_, err := q.db.Exec(ctx, CreateNoteActivity, arg.NoteID, arg.UserID, arg.Kind)
+	return err
+}
+
+const CreateNoteTemplate = `-- name: CreateNoteTemplate :one
+INSERT INTO note_templates (user_id, name, title, description, tags, deadline_offset)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id
+`
+
+type CreateNoteTemplateParams struct {
+	UserID         int64    `db:"user_id" json:"user_id"`
+	Name           string   `db:"name" json:"name"`
+	Title          string   `db:"title" json:"title"`
+	Description    string   `db:"description" json:"description"`
+	Tags           []string `db:"tags" json:"tags"`
+	DeadlineOffset *string  `db:"deadline_offset" json:"deadline_offset"`
+}
+
+func (q *Queries) CreateNoteTemplate(ctx context.Context, arg CreateNoteTemplateParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateNoteTemplate,
+		arg.UserID,
+		arg.Name,
+		arg.Title,
+		arg.Description,
+		arg.Tags,
+		arg.DeadlineOffset,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateNotebook = `-- name: CreateNotebook :one
+INSERT INTO notebooks (user_id, parent_id, name)
+VALUES ($1, $2, $3)
+RETURNING id
+`
+
+type CreateNotebookParams struct {
+	UserID   int64  `db:"user_id" json:"user_id"`
+	ParentID *int64 `db:"parent_id" json:"parent_id"`
+	Name     string `db:"name" json:"name"`
+}
+
+func (q *Queries) CreateNotebook(ctx context.Context, arg CreateNotebookParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateNotebook, arg.UserID, arg.ParentID, arg.Name)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateNotification = `-- name: CreateNotification :exec
+INSERT INTO notifications (user_id, actor_id, note_id, comment_id, kind)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateNotificationParams struct {
+	UserID    int64  `db:"user_id" json:"user_id"`
+	ActorID   int64  `db:"actor_id" json:"actor_id"`
+	NoteID    int64  `db:"note_id" json:"note_id"`
+	CommentID *int64 `db:"comment_id" json:"comment_id"`
+	Kind      string `db:"kind" json:"kind"`
+}
+
+func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotificationParams) error {
+	_, err := q.db.Exec(ctx, CreateNotification,
+		arg.UserID,
+		arg.ActorID,
+		arg.NoteID,
+		arg.CommentID,
+		arg.Kind,
+	)
+	return err
+}
+
+const CreatePasskeyCeremony = `-- name: CreatePasskeyCeremony :exec
+INSERT INTO passkey_ceremonies (token_hash, user_id, challenge, expires_at)
+VALUES ($1, $2, $3, NOW() + MAKE_INTERVAL(secs => $4::FLOAT8))
+`
+
+type CreatePasskeyCeremonyParams struct {
+	TokenHash  string  `db:"token_hash" json:"token_hash"`
+	UserID     int64   `db:"user_id" json:"user_id"`
+	Challenge  []byte  `db:"challenge" json:"challenge"`
+	TtlSeconds float64 `db:"ttl_seconds" json:"ttl_seconds"`
+}
+
+func (q *Queries) CreatePasskeyCeremony(ctx context.Context, arg CreatePasskeyCeremonyParams) error {
+	_, err := q.db.Exec(ctx, CreatePasskeyCeremony,
+		arg.TokenHash,
+		arg.UserID,
+		arg.Challenge,
+		arg.TtlSeconds,
+	)
+	return err
+}
+
+const CreatePendingLogin = `-- name: CreatePendingLogin :exec
+INSERT INTO pending_logins (token_hash, user_id, expires_at)
+VALUES ($1, $2, NOW() + MAKE_INTERVAL(secs => $3::FLOAT8))
+`
+
+type CreatePendingLoginParams struct {
+	TokenHash  string  `db:"token_hash" json:"token_hash"`
+	UserID     int64   `db:"user_id" json:"user_id"`
+	TtlSeconds float64 `db:"ttl_seconds" json:"ttl_seconds"`
+}
+
+func (q *Queries) CreatePendingLogin(ctx context.Context, arg CreatePendingLoginParams) error {
+	_, err := q.db.Exec(ctx, CreatePendingLogin, arg.TokenHash, arg.UserID, arg.TtlSeconds)
+	return err
+}
+
+const CreatePublicLink = `-- name: CreatePublicLink :one
+INSERT INTO public_links (note_id, user_id, token, password, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id
+`
+
+type CreatePublicLinkParams struct {
+	NoteID    int64              `db:"note_id" json:"note_id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Token     string             `db:"token" json:"token"`
+	Password  *string            `db:"password" json:"password"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+}
+
+func (q *Queries) CreatePublicLink(ctx context.Context, arg CreatePublicLinkParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreatePublicLink,
+		arg.NoteID,
+		arg.UserID,
+		arg.Token,
+		arg.Password,
+		arg.ExpiresAt,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateRecoveryCode = `-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+VALUES ($1, $2)
+`
+
+type CreateRecoveryCodeParams struct {
+	UserID   int64  `db:"user_id" json:"user_id"`
+	CodeHash string `db:"code_hash" json:"code_hash"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error {
+	_, err := q.db.Exec(ctx, CreateRecoveryCode, arg.UserID, arg.CodeHash)
+	return err
+}
+
+const CreateSession = `-- name: CreateSession :exec
+INSERT INTO sessions (token_hash, user_id, expires_at)
+VALUES ($1, $2, NOW() + MAKE_INTERVAL(secs => $3::FLOAT8))
+`
+
+type CreateSessionParams struct {
+	TokenHash  string  `db:"token_hash" json:"token_hash"`
+	UserID     int64   `db:"user_id" json:"user_id"`
+	TtlSeconds float64 `db:"ttl_seconds" json:"ttl_seconds"`
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) error {
+	_, err := q.db.Exec(ctx, CreateSession, arg.TokenHash, arg.UserID, arg.TtlSeconds)
+	return err
+}
+
+const CreateUser = `-- name: CreateUser :one
+INSERT INTO users (login, password)
+VALUES ($1, $2)
+RETURNING id
+`
+
+type CreateUserParams struct {
+	Login    string `db:"login" json:"login"`
+	Password string `db:"password" json:"password"`
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateUser, arg.Login, arg.Password)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateWebauthnCredential = `-- name: CreateWebauthnCredential :one
+INSERT INTO webauthn_credentials (user_id, name, credential_id, public_key, sign_count)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id
+`
+
+type CreateWebauthnCredentialParams struct {
+	UserID       int64  `db:"user_id" json:"user_id"`
+	Name         string `db:"name" json:"name"`
+	CredentialID []byte `db:"credential_id" json:"credential_id"`
+	PublicKey    []byte `db:"public_key" json:"public_key"`
+	SignCount    int64  `db:"sign_count" json:"sign_count"`
+}
+
+func (q *Queries) CreateWebauthnCredential(ctx context.Context, arg CreateWebauthnCredentialParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateWebauthnCredential,
+		arg.UserID,
+		arg.Name,
+		arg.CredentialID,
+		arg.PublicKey,
+		arg.SignCount,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (user_id, url, events, secret)
+VALUES ($1, $2, $3, $4)
+RETURNING id
+`
+
+type CreateWebhookParams struct {
+	UserID int64    `db:"user_id" json:"user_id"`
+	Url    string   `db:"url" json:"url"`
+	Events []string `db:"events" json:"events"`
+	Secret string   `db:"secret" json:"secret"`
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CreateWebhook,
+		arg.UserID,
+		arg.Url,
+		arg.Events,
+		arg.Secret,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event, payload)
+VALUES ($1, $2, $3)
+RETURNING id, webhook_id, event, payload, status, attempts, next_attempt_at, response_status, error, created_at, delivered_at, claimed_at
+`
+
+type CreateWebhookDeliveryParams struct {
+	WebhookID int64  `db:"webhook_id" json:"webhook_id"`
+	Event     string `db:"event" json:"event"`
+	Payload   string `db:"payload" json:"payload"`
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (*WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, CreateWebhookDelivery, arg.WebhookID, arg.Event, arg.Payload)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.Event,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseStatus,
+		&i.Error,
+		&i.CreatedAt,
+		&i.DeliveredAt,
+		&i.ClaimedAt,
+	)
+	return &i, err
+}
+
+const DeleteAttachmentById = `-- name: DeleteAttachmentById :exec
+DELETE
+FROM attachments
+WHERE id = $1
+`
+
+func (q *Queries) DeleteAttachmentById(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, DeleteAttachmentById, id)
+	return err
+}
+
+const DeleteBulkActionCreatedNotes = `-- name: DeleteBulkActionCreatedNotes :exec
+DELETE
+FROM notes
+WHERE id IN (SELECT b.note_id FROM bulk_action_notes b WHERE b.bulk_action_id = $1::BIGINT AND b.created)
+`
+
+func (q *Queries) DeleteBulkActionCreatedNotes(ctx context.Context, bulkActionID int64) error {
+	_, err := q.db.Exec(ctx, DeleteBulkActionCreatedNotes, bulkActionID)
+	return err
+}
+
+const DeleteBulkActionsByUserId = `-- name: DeleteBulkActionsByUserId :exec
+DELETE
+FROM bulk_actions
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteBulkActionsByUserId(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, DeleteBulkActionsByUserId, userID)
+	return err
+}
+
+const DeleteComment = `-- name: DeleteComment :execrows
+DELETE
+FROM note_comments
+WHERE id = $1
+  AND user_id = $2
+`
+
+type DeleteCommentParams struct {
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) DeleteComment(ctx context.Context, arg DeleteCommentParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteComment, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const DeleteExpiredPasskeyCeremonies = `-- name: DeleteExpiredPasskeyCeremonies :exec
+DELETE
+FROM passkey_ceremonies
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredPasskeyCeremonies(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, DeleteExpiredPasskeyCeremonies)
+	return err
+}
+
+const DeleteExpiredPendingLogins = `-- name: DeleteExpiredPendingLogins :exec
+DELETE
+FROM pending_logins
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredPendingLogins(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, DeleteExpiredPendingLogins)
+	return err
+}
+
+const DeleteExpiredSessions = `-- name: DeleteExpiredSessions :exec
+DELETE
+FROM sessions
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, DeleteExpiredSessions)
+	return err
+}
+
+const DeleteExportById = `-- name: DeleteExportById :exec
+DELETE
+FROM exports
+WHERE id = $1
+`
+
+func (q *Queries) DeleteExportById(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, DeleteExportById, id)
+	return err
+}
+
+const DeleteNoteById = `-- name: DeleteNoteById :one
+DELETE
+FROM notes
+WHERE id = $1
+RETURNING id
+`
+
+func (q *Queries) DeleteNoteById(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRow(ctx, DeleteNoteById, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const DeleteNoteReferences = `-- name: DeleteNoteReferences :exec
+DELETE
+FROM note_references
+WHERE note_id = $1
+`
+
+func (q *Queries) DeleteNoteReferences(ctx context.Context, noteID int64) error {
+	_, err := q.db.Exec(ctx, DeleteNoteReferences, noteID)
+	return err
+}
+
+const DeleteNoteTemplate = `-- name: DeleteNoteTemplate :execrows
+DELETE
+FROM note_templates
+WHERE id = $1
+  AND user_id = $2
+`
+
+type DeleteNoteTemplateParams struct {
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) DeleteNoteTemplate(ctx context.Context, arg DeleteNoteTemplateParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteNoteTemplate, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const DeleteNotebookById = `-- name: DeleteNotebookById :exec
+DELETE
+FROM notebooks
+WHERE id = $1
+`
+
+func (q *Queries) DeleteNotebookById(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, DeleteNotebookById, id)
+	return err
+}
+
+const DeletePendingLogin = `-- name: DeletePendingLogin :exec
+DELETE
+FROM pending_logins
+WHERE token_hash = $1
+`
+
+func (q *Queries) DeletePendingLogin(ctx context.Context, tokenHash string) error {
+	_, err := q.db.Exec(ctx, DeletePendingLogin, tokenHash)
+	return err
+}
+
+const DeleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE
+FROM recovery_codes
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, DeleteRecoveryCodes, userID)
+	return err
+}
+
+const DeleteSeriesReferences = `-- name: DeleteSeriesReferences :exec
+DELETE
+FROM note_references r
+    USING notes n
+WHERE r.note_id = n.id
+  AND COALESCE(n.series_id, n.id) = $1::BIGINT
+  AND n.is_completed = FALSE
+  AND n.id <> $2::BIGINT
+`
+
+type DeleteSeriesReferencesParams struct {
+	SeriesID int64 `db:"series_id" json:"series_id"`
+	NoteID   int64 `db:"note_id" json:"note_id"`
+}
+
+func (q *Queries) DeleteSeriesReferences(ctx context.Context, arg DeleteSeriesReferencesParams) error {
+	_, err := q.db.Exec(ctx, DeleteSeriesReferences, arg.SeriesID, arg.NoteID)
+	return err
+}
+
+const DeleteSession = `-- name: DeleteSession :exec
+DELETE
+FROM sessions
+WHERE token_hash = $1
+`
+
+func (q *Queries) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := q.db.Exec(ctx, DeleteSession, tokenHash)
+	return err
+}
+
+const DeleteShare = `-- name: DeleteShare :exec
+DELETE
+FROM shares
+WHERE id = $1
+  AND owner_id = $2
+`
+
+type DeleteShareParams struct {
+	ID      int64 `db:"id" json:"id"`
+	OwnerID int64 `db:"owner_id" json:"owner_id"`
+}
+
+func (q *Queries) DeleteShare(ctx context.Context, arg DeleteShareParams) error {
+	_, err := q.db.Exec(ctx, DeleteShare, arg.ID, arg.OwnerID)
+	return err
+}
+
+const DeleteUserSessions = `-- name: DeleteUserSessions :execrows
+DELETE
+FROM sessions
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserSessions(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteUserSessions, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const DeleteWebauthnCredential = `-- name: DeleteWebauthnCredential :execrows
+DELETE
+FROM webauthn_credentials
+WHERE id = $1
+  AND user_id = $2
+`
+
+type DeleteWebauthnCredentialParams struct {
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) DeleteWebauthnCredential(ctx context.Context, arg DeleteWebauthnCredentialParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteWebauthnCredential, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const DeleteWebhook = `-- name: DeleteWebhook :execrows
+DELETE
+FROM webhooks
+WHERE id = $1
+  AND user_id = $2
+`
+
+type DeleteWebhookParams struct {
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteWebhook, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const EnableUserTotp = `-- name: EnableUserTotp :execrows
+UPDATE users
+SET totp_enabled_at   = NOW(),
+    totp_last_counter = $2
+WHERE id = $1
+  AND totp_secret IS NOT NULL
+  AND totp_enabled_at IS NULL
+`
+
+type EnableUserTotpParams struct {
+	ID              int64 `db:"id" json:"id"`
+	TotpLastCounter int64 `db:"totp_last_counter" json:"totp_last_counter"`
+}
+
+func (q *Queries) EnableUserTotp(ctx context.Context, arg EnableUserTotpParams) (int64, error) {
+	result, err := q.db.Exec(ctx, EnableUserTotp, arg.ID, arg.TotpLastCounter)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const FailExport = `-- name: FailExport :exec
+UPDATE exports
+SET status      = 'failed',
+    error       = $2,
+    finished_at = NOW()
+WHERE id = $1
+`
+
+type FailExportParams struct {
+	ID    int64   `db:"id" json:"id"`
+	Error *string `db:"error" json:"error"`
+}
+
+func (q *Queries) FailExport(ctx context.Context, arg FailExportParams) error {
+	_, err := q.db.Exec(ctx, FailExport, arg.ID, arg.Error)
+	return err
+}
+
+const FailWebhookDelivery = `-- name: FailWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status          = 'failed',
+    attempts        = attempts + 1,
+    response_status = $1,
+    error           = $2
+WHERE id = $3
+`
+
+type FailWebhookDeliveryParams struct {
+	ResponseStatus *int32  `db:"response_status" json:"response_status"`
+	Error          *string `db:"error" json:"error"`
+	ID             int64   `db:"id" json:"id"`
+}
+
+func (q *Queries) FailWebhookDelivery(ctx context.Context, arg FailWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, FailWebhookDelivery, arg.ResponseStatus, arg.Error, arg.ID)
+	return err
+}
+
+const FinishExport = `-- name: FinishExport :exec
+UPDATE exports
+SET status      = 'ready',
+    blob_key    = $2,
+    size        = $3,
+    finished_at = NOW()
+WHERE id = $1
+`
+
+type FinishExportParams struct {
+	ID      int64   `db:"id" json:"id"`
+	BlobKey *string `db:"blob_key" json:"blob_key"`
+	Size    int64   `db:"size" json:"size"`
+}
+
+func (q *Queries) FinishExport(ctx context.Context, arg FinishExportParams) error {
+	_, err := q.db.Exec(ctx, FinishExport, arg.ID, arg.BlobKey, arg.Size)
+	return err
+}
+
+const FinishWebhookDelivery = `-- name: FinishWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status          = 'delivered',
+    attempts        = attempts + 1,
+    response_status = $2,
+    error           = NULL,
+    delivered_at    = NOW()
+WHERE id = $1
+`
+
+type FinishWebhookDeliveryParams struct {
+	ID             int64  `db:"id" json:"id"`
+	ResponseStatus *int32 `db:"response_status" json:"response_status"`
+}
+
+func (q *Queries) FinishWebhookDelivery(ctx context.Context, arg FinishWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, FinishWebhookDelivery, arg.ID, arg.ResponseStatus)
+	return err
+}
+
+const GetActivePublicLinksByUserId = `-- name: GetActivePublicLinksByUserId :many
+SELECT pl.id, pl.note_id, pl.user_id, pl.token, pl.password, pl.expires_at, pl.revoked_at, pl.created_at, n.name AS note_name
+FROM public_links pl
+         JOIN notes n ON n.id = pl.note_id
+WHERE pl.user_id = $1
+  AND pl.revoked_at IS NULL
+  AND (pl.expires_at IS NULL OR pl.expires_at > NOW())
+ORDER BY pl.created_at DESC, pl.id DESC
+`
+
+type GetActivePublicLinksByUserIdRow struct {
+	ID        int64              `db:"id" json:"id"`
+	NoteID    int64              `db:"note_id" json:"note_id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Token     string             `db:"token" json:"token"`
+	Password  *string            `db:"password" json:"password"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	RevokedAt pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	NoteName  string             `db:"note_name" json:"note_name"`
+}
+
+func (q *Queries) GetActivePublicLinksByUserId(ctx context.Context, userID int64) ([]*GetActivePublicLinksByUserIdRow, error) {
+	rows, err := q.db.Query(ctx, GetActivePublicLinksByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetActivePublicLinksByUserIdRow{}
+	for rows.Next() {
+		var i GetActivePublicLinksByUserIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.UserID,
+			&i.Token,
+			&i.Password,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.CreatedAt,
+			&i.NoteName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetAllNotesByUserId = `-- name: GetAllNotesByUserId :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE n.user_id = $1
+ORDER BY n.created_at, n.id
+`
+
+func (q *Queries) GetAllNotesByUserId(ctx context.Context, userID int64) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetAllNotesByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetAttachmentById = `-- name: GetAttachmentById :one
+SELECT a.id, a.note_id, a.user_id, a.blob_key, a.thumb_key, a.file_name, a.content_type, a.size, a.created_at
+FROM attachments a
+WHERE a.id = $1
+`
+
+func (q *Queries) GetAttachmentById(ctx context.Context, id int64) (*Attachment, error) {
+	row := q.db.QueryRow(ctx, GetAttachmentById, id)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.UserID,
+		&i.BlobKey,
+		&i.ThumbKey,
+		&i.FileName,
+		&i.ContentType,
+		&i.Size,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
+const GetAttachmentsByNoteId = `-- name: GetAttachmentsByNoteId :many
+SELECT a.id, a.note_id, a.user_id, a.blob_key, a.thumb_key, a.file_name, a.content_type, a.size, a.created_at
+FROM attachments a
+WHERE a.note_id = $1
+ORDER BY a.created_at, a.id
+`
+
+func (q *Queries) GetAttachmentsByNoteId(ctx context.Context, noteID int64) ([]*Attachment, error) {
+	rows, err := q.db.Query(ctx, GetAttachmentsByNoteId, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Attachment{}
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.UserID,
+			&i.BlobKey,
+			&i.ThumbKey,
+			&i.FileName,
+			&i.ContentType,
+			&i.Size,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetAttachmentsByUserId = `-- name: GetAttachmentsByUserId :many
+SELECT a.id, a.note_id, a.user_id, a.blob_key, a.thumb_key, a.file_name, a.content_type, a.size, a.created_at
+FROM attachments a
+         JOIN notes n ON n.id = a.note_id
+WHERE n.user_id = $1
+ORDER BY a.note_id, a.id
+`
+
+func (q *Queries) GetAttachmentsByUserId(ctx context.Context, userID int64) ([]*Attachment, error) {
+	rows, err := q.db.Query(ctx, GetAttachmentsByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Attachment{}
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.UserID,
+			&i.BlobKey,
+			&i.ThumbKey,
+			&i.FileName,
+			&i.ContentType,
+			&i.Size,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetBacklinks = `-- name: GetBacklinks :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE n.trashed_at IS NULL
+  AND n.id <> $1::BIGINT
+  AND n.id IN (SELECT r.note_id
+               FROM note_references r
+               WHERE r.target_id = $1::BIGINT
+                  OR (r.target_id IS NULL AND LOWER(r.target_name) = LOWER($2::TEXT)))
+  AND (n.user_id = $3 OR EXISTS (SELECT 1
+                                       FROM note_references r
+                                       WHERE r.note_id = n.id
+                                         AND r.target_id = $1::BIGINT))
+ORDER BY n.name
+`
+
+type GetBacklinksParams struct {
+	TargetID int64  `db:"target_id" json:"target_id"`
+	Name     string `db:"name" json:"name"`
+	UserID   int64  `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) GetBacklinks(ctx context.Context, arg GetBacklinksParams) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetBacklinks, arg.TargetID, arg.Name, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetBrokenReferences = `-- name: GetBrokenReferences :many
+SELECT r.note_id, n.name AS note_name, r.target_id, r.target_name
+FROM note_references r
+         JOIN notes n ON n.id = r.note_id
+WHERE n.user_id = $1
+  AND n.trashed_at IS NULL
+  AND NOT EXISTS (SELECT 1
+                  FROM notes t
+                  WHERE t.trashed_at IS NULL
+                    AND ((r.target_id IS NOT NULL AND t.id = r.target_id)
+                      OR (r.target_id IS NULL AND t.user_id = n.user_id AND LOWER(t.name) = LOWER(r.target_name))))
+ORDER BY n.name, r.target_name, r.target_id
+`
+
+type GetBrokenReferencesRow struct {
+	NoteID     int64   `db:"note_id" json:"note_id"`
+	NoteName   string  `db:"note_name" json:"note_name"`
+	TargetID   *int64  `db:"target_id" json:"target_id"`
+	TargetName *string `db:"target_name" json:"target_name"`
+}
+
+func (q *Queries) GetBrokenReferences(ctx context.Context, userID int64) ([]*GetBrokenReferencesRow, error) {
+	rows, err := q.db.Query(ctx, GetBrokenReferences, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetBrokenReferencesRow{}
+	for rows.Next() {
+		var i GetBrokenReferencesRow
+		if err := rows.Scan(
+			&i.NoteID,
+			&i.NoteName,
+			&i.TargetID,
+			&i.TargetName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetBulkActionNotes = `-- name: GetBulkActionNotes :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+         JOIN bulk_action_notes b ON b.note_id = n.id
+WHERE b.bulk_action_id = $1
+  AND b.created = FALSE
+ORDER BY n.id
+`
+
+func (q *Queries) GetBulkActionNotes(ctx context.Context, bulkActionID int64) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetBulkActionNotes, bulkActionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetCommentById = `-- name: GetCommentById :one
+SELECT c.id, c.note_id, c.user_id, c.body, c.created_at, c.updated_at
+FROM note_comments c
+WHERE c.id = $1
+`
+
+func (q *Queries) GetCommentById(ctx context.Context, id int64) (*NoteComment, error) {
+	row := q.db.QueryRow(ctx, GetCommentById, id)
+	var i NoteComment
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.UserID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
+const GetCommentsByNoteId = `-- name: GetCommentsByNoteId :many
+SELECT c.id, c.note_id, c.user_id, c.body, c.created_at, c.updated_at, u.login
+FROM note_comments c
+         JOIN users u ON u.id = c.user_id
+WHERE c.note_id = $1
+ORDER BY c.created_at, c.id
+`
+
+type GetCommentsByNoteIdRow struct {
+	ID        int64              `db:"id" json:"id"`
+	NoteID    int64              `db:"note_id" json:"note_id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Body      string             `db:"body" json:"body"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Login     string             `db:"login" json:"login"`
+}
+
+func (q *Queries) GetCommentsByNoteId(ctx context.Context, noteID int64) ([]*GetCommentsByNoteIdRow, error) {
+	rows, err := q.db.Query(ctx, GetCommentsByNoteId, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetCommentsByNoteIdRow{}
+	for rows.Next() {
+		var i GetCommentsByNoteIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.UserID,
+			&i.Body,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Login,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetDigestSettingsByUserId = `-- name: GetDigestSettingsByUserId :one
+SELECT d.user_id, d.enabled, d.email, d.send_time, d.timezone, d.days_ahead, d.last_sent_on
+FROM digest_settings d
+WHERE d.user_id = $1
+`
+
+func (q *Queries) GetDigestSettingsByUserId(ctx context.Context, userID int64) (*DigestSetting, error) {
+	row := q.db.QueryRow(ctx, GetDigestSettingsByUserId, userID)
+	var i DigestSetting
+	err := row.Scan(
+		&i.UserID,
+		&i.Enabled,
+		&i.Email,
+		&i.SendTime,
+		&i.Timezone,
+		&i.DaysAhead,
+		&i.LastSentOn,
+	)
+	return &i, err
+}
+
+const GetEnabledDigestSettings = `-- name: GetEnabledDigestSettings :many
+SELECT d.user_id, d.enabled, d.email, d.send_time, d.timezone, d.days_ahead, d.last_sent_on
+FROM digest_settings d
+WHERE d.enabled = TRUE
+ORDER BY d.user_id
+`
+
+func (q *Queries) GetEnabledDigestSettings(ctx context.Context) ([]*DigestSetting, error) {
+	rows, err := q.db.Query(ctx, GetEnabledDigestSettings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*DigestSetting{}
+	for rows.Next() {
+		var i DigestSetting
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Enabled,
+			&i.Email,
+			&i.SendTime,
+			&i.Timezone,
+			&i.DaysAhead,
+			&i.LastSentOn,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetExpiredExports = `-- name: GetExpiredExports :many
+SELECT e.id, e.user_id, e.status, e.blob_key, e.size, e.error, e.created_at, e.finished_at, e.claimed_at
+FROM exports e
+WHERE e.finished_at < $1::TIMESTAMPTZ
+`
+
+func (q *Queries) GetExpiredExports(ctx context.Context, before pgtype.Timestamptz) ([]*Export, error) {
+	rows, err := q.db.Query(ctx, GetExpiredExports, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Export{}
+	for rows.Next() {
+		var i Export
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Status,
+			&i.BlobKey,
+			&i.Size,
+			&i.Error,
+			&i.CreatedAt,
+			&i.FinishedAt,
+			&i.ClaimedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetExpiredNotesByUserId = `-- name: GetExpiredNotesByUserId :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE n.user_id = $1
+  AND n.is_completed = FALSE
+  AND n.trashed_at IS NULL
+  AND n.deadline_at < $2::DATE
+ORDER BY n.deadline_at, n.id
+`
+
+type GetExpiredNotesByUserIdParams struct {
+	UserID int64       `db:"user_id" json:"user_id"`
+	Today  pgtype.Date `db:"today" json:"today"`
+}
+
+func (q *Queries) GetExpiredNotesByUserId(ctx context.Context, arg GetExpiredNotesByUserIdParams) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetExpiredNotesByUserId, arg.UserID, arg.Today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetExportById = `-- name: GetExportById :one
+SELECT e.id, e.user_id, e.status, e.blob_key, e.size, e.error, e.created_at, e.finished_at, e.claimed_at
+FROM exports e
+WHERE e.id = $1
+`
+
+func (q *Queries) GetExportById(ctx context.Context, id int64) (*Export, error) {
+	row := q.db.QueryRow(ctx, GetExportById, id)
+	var i Export
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.BlobKey,
+		&i.Size,
+		&i.Error,
+		&i.CreatedAt,
+		&i.FinishedAt,
+		&i.ClaimedAt,
+	)
+	return &i, err
+}
+
+const GetExportsByUserId = `-- name: GetExportsByUserId :many
+SELECT e.id, e.user_id, e.status, e.blob_key, e.size, e.error, e.created_at, e.finished_at, e.claimed_at
+FROM exports e
+WHERE e.user_id = $1
+ORDER BY e.created_at DESC, e.id DESC
+LIMIT 10
+`
+
+func (q *Queries) GetExportsByUserId(ctx context.Context, userID int64) ([]*Export, error) {
+	rows, err := q.db.Query(ctx, GetExportsByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Export{}
+	for rows.Next() {
+		var i Export
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Status,
+			&i.BlobKey,
+			&i.Size,
+			&i.Error,
+			&i.CreatedAt,
+			&i.FinishedAt,
+			&i.ClaimedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetLastBulkAction = `-- name: GetLastBulkAction :one
+SELECT ba.id, ba.user_id, ba.action, ba.tag_id, ba.created_at, ba.undone_at
+FROM bulk_actions ba
+WHERE ba.user_id = $1
+ORDER BY ba.id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastBulkAction(ctx context.Context, userID int64) (*BulkAction, error) {
+	row := q.db.QueryRow(ctx, GetLastBulkAction, userID)
+	var i BulkAction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Action,
+		&i.TagID,
+		&i.CreatedAt,
+		&i.UndoneAt,
+	)
+	return &i, err
+}
+
+const GetLinkTargets = `-- name: GetLinkTargets :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE n.trashed_at IS NULL
+  AND (n.id = ANY ($1::BIGINT[])
+    OR (n.user_id = $2 AND LOWER(n.name) = ANY ($3::TEXT[])))
+ORDER BY n.id
+`
+
+type GetLinkTargetsParams struct {
+	Ids    []int64  `db:"ids" json:"ids"`
+	UserID int64    `db:"user_id" json:"user_id"`
+	Names  []string `db:"names" json:"names"`
+}
+
+func (q *Queries) GetLinkTargets(ctx context.Context, arg GetLinkTargetsParams) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetLinkTargets, arg.Ids, arg.UserID, arg.Names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetLoginAttemptForUpdate = `-- name: GetLoginAttemptForUpdate :one
+INSERT INTO login_attempts (key, failures, last_failure_at)
+VALUES ($1, 0, NOW())
+ON CONFLICT (key) DO UPDATE
+    SET key = EXCLUDED.key
+RETURNING locked_until, NOW()::TIMESTAMPTZ AS now
+`
+
+type GetLoginAttemptForUpdateRow struct {
+	LockedUntil pgtype.Timestamptz `db:"locked_until" json:"locked_until"`
+	Now         pgtype.Timestamptz `db:"now" json:"now"`
+}
+
+func (q *Queries) GetLoginAttemptForUpdate(ctx context.Context, key string) (*GetLoginAttemptForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, GetLoginAttemptForUpdate, key)
+	var i GetLoginAttemptForUpdateRow
+	err := row.Scan(&i.LockedUntil, &i.Now)
+	return &i, err
+}
+
+const GetNoteActivityByNoteId = `-- name: GetNoteActivityByNoteId :many
+SELECT a.id, a.note_id, a.user_id, a.kind, a.created_at, u.login
+FROM note_activity a
+         JOIN users u ON u.id = a.user_id
+WHERE a.note_id = $1
+ORDER BY a.created_at, a.id
+`
+
+type GetNoteActivityByNoteIdRow struct {
+	ID        int64              `db:"id" json:"id"`
+	NoteID    int64              `db:"note_id" json:"note_id"`
+	UserID    int64              `db:"user_id" json:"user_id"`
+	Kind      string             `db:"kind" json:"kind"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	Login     string             `db:"login" json:"login"`
+}
+
+func (q *Queries) GetNoteActivityByNoteId(ctx context.Context, noteID int64) ([]*GetNoteActivityByNoteIdRow, error) {
+	rows, err := q.db.Query(ctx, GetNoteActivityByNoteId, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetNoteActivityByNoteIdRow{}
+	for rows.Next() {
+		var i GetNoteActivityByNoteIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.UserID,
+			&i.Kind,
+			&i.CreatedAt,
+			&i.Login,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNoteAudience = `-- name: GetNoteAudience :many
+WITH RECURSIVE ancestors AS (SELECT nb.id, nb.parent_id
+                             FROM notebooks nb
+                                      JOIN notes n ON n.notebook_id = nb.id
+                             WHERE n.id = $1
+                             UNION ALL
+                             SELECT parent.id, parent.parent_id
+                             FROM notebooks parent
+                                      JOIN ancestors ON parent.id = ancestors.parent_id)
+SELECT DISTINCT s.user_id
+FROM shares s
+WHERE s.note_id = $1
+   OR s.notebook_id IN (SELECT ancestors.id FROM ancestors)
+`
+
+func (q *Queries) GetNoteAudience(ctx context.Context, noteID int64) ([]int64, error) {
+	rows, err := q.db.Query(ctx, GetNoteAudience, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		items = append(items, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNoteById = `-- name: GetNoteById :one
+SELECT DISTINCT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE n.id = $1
+`
+
+func (q *Queries) GetNoteById(ctx context.Context, id int64) (*Note, error) {
+	row := q.db.QueryRow(ctx, GetNoteById, id)
+	var i Note
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Description,
+		&i.IsCompleted,
+		&i.CreatedAt,
+		&i.DeadlineAt,
+		&i.Recurrence,
+		&i.SeriesID,
+		&i.NotebookID,
+		&i.TrashedAt,
+		&i.Priority,
+		&i.Pinned,
+		&i.Version,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+	)
+	return &i, err
+}
+
+const GetNoteCompletionStats = `-- name: GetNoteCompletionStats :one
+SELECT COUNT(*) AS notes_count,
+       COUNT(*) FILTER (WHERE n.is_completed) AS completed_count,
+       COUNT(*) FILTER (WHERE n.is_completed AND n.deadline_at IS NOT NULL) AS completed_with_deadline_count,
+       COUNT(*) FILTER (WHERE n.is_completed AND n.completed_at < (n.deadline_at + 1)::TIMESTAMPTZ) AS completed_on_time_count,
+       COUNT(*) FILTER (WHERE NOT n.is_completed AND n.deadline_at < CURRENT_DATE) AS overdue_count,
+       COALESCE(AVG(EXTRACT(EPOCH FROM n.completed_at - n.created_at)) FILTER (WHERE n.is_completed),
+                0)::FLOAT8 AS avg_completion_seconds
+FROM notes n
+WHERE n.user_id = $1
+  AND n.trashed_at IS NULL
+`
+
+type GetNoteCompletionStatsRow struct {
+	NotesCount                 int64   `db:"notes_count" json:"notes_count"`
+	CompletedCount             int64   `db:"completed_count" json:"completed_count"`
+	CompletedWithDeadlineCount int64   `db:"completed_with_deadline_count" json:"completed_with_deadline_count"`
+	CompletedOnTimeCount       int64   `db:"completed_on_time_count" json:"completed_on_time_count"`
+	OverdueCount               int64   `db:"overdue_count" json:"overdue_count"`
+	AvgCompletionSeconds       float64 `db:"avg_completion_seconds" json:"avg_completion_seconds"`
+}
+
+func (q *Queries) GetNoteCompletionStats(ctx context.Context, userID int64) (*GetNoteCompletionStatsRow, error) {
+	row := q.db.QueryRow(ctx, GetNoteCompletionStats, userID)
+	var i GetNoteCompletionStatsRow
+	err := row.Scan(
+		&i.NotesCount,
+		&i.CompletedCount,
+		&i.CompletedWithDeadlineCount,
+		&i.CompletedOnTimeCount,
+		&i.OverdueCount,
+		&i.AvgCompletionSeconds,
+	)
+	return &i, err
+}
+
+const GetNoteSharePermission = `-- name: GetNoteSharePermission :one
+WITH RECURSIVE ancestors AS (SELECT nb.id, nb.parent_id
+                             FROM notebooks nb
+                                      JOIN notes n ON n.notebook_id = nb.id
+                             WHERE n.id = $1
+                             UNION ALL
+                             SELECT parent.id, parent.parent_id
+                             FROM notebooks parent
+                                      JOIN ancestors ON parent.id = ancestors.parent_id)
+SELECT COUNT(*)                                                  AS shares_count,
+       COALESCE(BOOL_OR(s.permission = 'edit'), FALSE)::BOOLEAN AS can_edit
+FROM shares s
+WHERE s.user_id = $2
+  AND (s.note_id = $1 OR s.notebook_id IN (SELECT ancestors.id FROM ancestors))
+`
+
+type GetNoteSharePermissionParams struct {
+	NoteID int64 `db:"note_id" json:"note_id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+}
+
+type GetNoteSharePermissionRow struct {
+	SharesCount int64 `db:"shares_count" json:"shares_count"`
+	CanEdit     bool  `db:"can_edit" json:"can_edit"`
+}
+
+func (q *Queries) GetNoteSharePermission(ctx context.Context, arg GetNoteSharePermissionParams) (*GetNoteSharePermissionRow, error) {
+	row := q.db.QueryRow(ctx, GetNoteSharePermission, arg.NoteID, arg.UserID)
+	var i GetNoteSharePermissionRow
+	err := row.Scan(&i.SharesCount, &i.CanEdit)
+	return &i, err
+}
+
+const GetNoteTemplateById = `-- name: GetNoteTemplateById :one
+SELECT t.id, t.user_id, t.name, t.title, t.description, t.tags, t.deadline_offset
+FROM note_templates t
+WHERE t.id = $1
+  AND t.user_id = $2
+`
+
+type GetNoteTemplateByIdParams struct {
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) GetNoteTemplateById(ctx context.Context, arg GetNoteTemplateByIdParams) (*NoteTemplate, error) {
+	row := q.db.QueryRow(ctx, GetNoteTemplateById, arg.ID, arg.UserID)
+	var i NoteTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Title,
+		&i.Description,
+		&i.Tags,
+		&i.DeadlineOffset,
+	)
+	return &i, err
+}
+
+const GetNoteTemplatesByUserId = `-- name: GetNoteTemplatesByUserId :many
+SELECT t.id, t.user_id, t.name, t.title, t.description, t.tags, t.deadline_offset
+FROM note_templates t
+WHERE t.user_id = $1
+ORDER BY t.name
+`
+
+func (q *Queries) GetNoteTemplatesByUserId(ctx context.Context, userID int64) ([]*NoteTemplate, error) {
+	rows, err := q.db.Query(ctx, GetNoteTemplatesByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*NoteTemplate{}
+	for rows.Next() {
+		var i NoteTemplate
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Title,
+			&i.Description,
+			&i.Tags,
+			&i.DeadlineOffset,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNoteWebhooks = `-- name: GetNoteWebhooks :many
+SELECT w.id, w.user_id, w.url, w.events, w.secret, w.active, w.created_at
+FROM webhooks w
+         JOIN notes n ON n.user_id = w.user_id
+WHERE n.id = $1
+  AND w.active
+  AND $2::TEXT = ANY (w.events)
+ORDER BY w.id
+`
+
+type GetNoteWebhooksParams struct {
+	NoteID int64  `db:"note_id" json:"note_id"`
+	Event  string `db:"event" json:"event"`
+}
+
+func (q *Queries) GetNoteWebhooks(ctx context.Context, arg GetNoteWebhooksParams) ([]*Webhook, error) {
+	rows, err := q.db.Query(ctx, GetNoteWebhooks, arg.NoteID, arg.Event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Webhook{}
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Url,
+			&i.Events,
+			&i.Secret,
+			&i.Active,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotebookById = `-- name: GetNotebookById :one
+SELECT nb.id, nb.user_id, nb.parent_id, nb.name, nb.created_at
+FROM notebooks nb
+WHERE nb.id = $1
+`
+
+func (q *Queries) GetNotebookById(ctx context.Context, id int64) (*Notebook, error) {
+	row := q.db.QueryRow(ctx, GetNotebookById, id)
+	var i Notebook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ParentID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
+const GetNotebookNoteCounts = `-- name: GetNotebookNoteCounts :many
+SELECT n.notebook_id::BIGINT AS notebook_id, COUNT(*) AS notes_count
+FROM notes n
+WHERE n.user_id = $1
+  AND n.notebook_id IS NOT NULL
+  AND n.trashed_at IS NULL
+GROUP BY n.notebook_id
+`
+
+type GetNotebookNoteCountsRow struct {
+	NotebookID int64 `db:"notebook_id" json:"notebook_id"`
+	NotesCount int64 `db:"notes_count" json:"notes_count"`
+}
+
+func (q *Queries) GetNotebookNoteCounts(ctx context.Context, userID int64) ([]*GetNotebookNoteCountsRow, error) {
+	rows, err := q.db.Query(ctx, GetNotebookNoteCounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetNotebookNoteCountsRow{}
+	for rows.Next() {
+		var i GetNotebookNoteCountsRow
+		if err := rows.Scan(&i.NotebookID, &i.NotesCount); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotebookNoteStats = `-- name: GetNotebookNoteStats :many
+SELECT nb.name, COUNT(*) AS notes_count, COUNT(*) FILTER (WHERE n.is_completed) AS completed_count
+FROM notes n
+         LEFT JOIN notebooks nb ON nb.id = n.notebook_id
+WHERE n.user_id = $1
+  AND n.trashed_at IS NULL
+GROUP BY nb.id, nb.name
+ORDER BY notes_count DESC, nb.name NULLS LAST
+`
+
+type GetNotebookNoteStatsRow struct {
+	Name           *string `db:"name" json:"name"`
+	NotesCount     int64   `db:"notes_count" json:"notes_count"`
+	CompletedCount int64   `db:"completed_count" json:"completed_count"`
+}
+
+func (q *Queries) GetNotebookNoteStats(ctx context.Context, userID int64) ([]*GetNotebookNoteStatsRow, error) {
+	rows, err := q.db.Query(ctx, GetNotebookNoteStats, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetNotebookNoteStatsRow{}
+	for rows.Next() {
+		var i GetNotebookNoteStatsRow
+		if err := rows.Scan(&i.Name, &i.NotesCount, &i.CompletedCount); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotebookSubtreeIds = `-- name: GetNotebookSubtreeIds :many
+WITH RECURSIVE subtree AS (SELECT nb.id
+                           FROM notebooks nb
+                           WHERE nb.id = $1
+                           UNION ALL
+                           SELECT child.id
+                           FROM notebooks child
+                                    JOIN subtree ON child.parent_id = subtree.id)
+SELECT subtree.id::BIGINT AS id
+FROM subtree
+`
+
+func (q *Queries) GetNotebookSubtreeIds(ctx context.Context, id int64) ([]int64, error) {
+	rows, err := q.db.Query(ctx, GetNotebookSubtreeIds, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotebooksByUserId = `-- name: GetNotebooksByUserId :many
+SELECT nb.id, nb.user_id, nb.parent_id, nb.name, nb.created_at
+FROM notebooks nb
+WHERE nb.user_id = $1
+ORDER BY nb.name, nb.id
+`
+
+func (q *Queries) GetNotebooksByUserId(ctx context.Context, userID int64) ([]*Notebook, error) {
+	rows, err := q.db.Query(ctx, GetNotebooksByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Notebook{}
+	for rows.Next() {
+		var i Notebook
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ParentID,
+			&i.Name,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotesByUserId = `-- name: GetNotesByUserId :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE user_id = $1
+  AND n.trashed_at IS NULL
+ORDER BY n.pinned DESC, n.created_at, n.id
+`
+
+func (q *Queries) GetNotesByUserId(ctx context.Context, userID int64) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetNotesByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotesByUserIdAndNotebook = `-- name: GetNotesByUserIdAndNotebook :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE n.user_id = $1
+  AND n.notebook_id = $2
+  AND n.trashed_at IS NULL
+ORDER BY n.pinned DESC, n.created_at, n.id
+`
+
+type GetNotesByUserIdAndNotebookParams struct {
+	UserID     int64  `db:"user_id" json:"user_id"`
+	NotebookID *int64 `db:"notebook_id" json:"notebook_id"`
+}
+
+func (q *Queries) GetNotesByUserIdAndNotebook(ctx context.Context, arg GetNotesByUserIdAndNotebookParams) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetNotesByUserIdAndNotebook, arg.UserID, arg.NotebookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotesByUserIdAndSearch = `-- name: GetNotesByUserIdAndSearch :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE user_id = $1
+  AND (name ILIKE '%' || $2 || '%')
+  AND n.trashed_at IS NULL
+ORDER BY n.pinned DESC, n.created_at, n.id
+`
+
+type GetNotesByUserIdAndSearchParams struct {
+	UserID  int64   `db:"user_id" json:"user_id"`
+	Column2 *string `db:"column_2" json:"column_2"`
+}
+
+func (q *Queries) GetNotesByUserIdAndSearch(ctx context.Context, arg GetNotesByUserIdAndSearchParams) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetNotesByUserIdAndSearch, arg.UserID, arg.Column2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotesReferencingName = `-- name: GetNotesReferencingName :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE n.user_id = $1
+  AND n.id IN (SELECT r.note_id
+               FROM note_references r
+               WHERE r.target_id IS NULL
+                 AND LOWER(r.target_name) = LOWER($2::TEXT))
+`
+
+type GetNotesReferencingNameParams struct {
+	UserID int64  `db:"user_id" json:"user_id"`
+	Name   string `db:"name" json:"name"`
+}
+
+func (q *Queries) GetNotesReferencingName(ctx context.Context, arg GetNotesReferencingNameParams) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetNotesReferencingName, arg.UserID, arg.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotesSharedWithUser = `-- name: GetNotesSharedWithUser :many
+WITH RECURSIVE shared_notebooks AS (SELECT s.notebook_id AS id, s.permission
+                                    FROM shares s
+                                    WHERE s.user_id = $1
+                                      AND s.notebook_id IS NOT NULL
+                                    UNION ALL
+                                    SELECT child.id, shared_notebooks.permission
+                                    FROM notebooks child
+                                             JOIN shared_notebooks ON child.parent_id = shared_notebooks.id),
+               shared_notes AS (SELECT s.note_id AS id, s.permission
+                                FROM shares s
+                                WHERE s.user_id = $1
+                                  AND s.note_id IS NOT NULL
+                                UNION ALL
+                                SELECT nn.id, shared_notebooks.permission
+                                FROM notes nn
+                                         JOIN shared_notebooks ON nn.notebook_id = shared_notebooks.id)
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at,
+       u.login                                          AS owner_login,
+       BOOL_OR(shared_notes.permission = 'edit')::BOOLEAN AS can_edit
+FROM notes n
+         JOIN shared_notes ON shared_notes.id = n.id
+         JOIN users u ON u.id = n.user_id
+WHERE n.user_id <> $1
+  AND n.trashed_at IS NULL
+GROUP BY n.id, u.login
+ORDER BY n.pinned DESC, n.created_at, n.id
+`
+
+type GetNotesSharedWithUserRow struct {
+	ID          int64              `db:"id" json:"id"`
+	UserID      int64              `db:"user_id" json:"user_id"`
+	Name        string             `db:"name" json:"name"`
+	Description *string            `db:"description" json:"description"`
+	IsCompleted bool               `db:"is_completed" json:"is_completed"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	DeadlineAt  pgtype.Date        `db:"deadline_at" json:"deadline_at"`
+	Recurrence  *string            `db:"recurrence" json:"recurrence"`
+	SeriesID    *int64             `db:"series_id" json:"series_id"`
+	NotebookID  *int64             `db:"notebook_id" json:"notebook_id"`
+	TrashedAt   pgtype.Timestamptz `db:"trashed_at" json:"trashed_at"`
+	Priority    int16              `db:"priority" json:"priority"`
+	Pinned      bool               `db:"pinned" json:"pinned"`
+	Version     int32              `db:"version" json:"version"`
+	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	CompletedAt pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
+	OwnerLogin  string             `db:"owner_login" json:"owner_login"`
+	CanEdit     bool               `db:"can_edit" json:"can_edit"`
+}
+
+func (q *Queries) GetNotesSharedWithUser(ctx context.Context, userID int64) ([]*GetNotesSharedWithUserRow, error) {
+	rows, err := q.db.Query(ctx, GetNotesSharedWithUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetNotesSharedWithUserRow{}
+	for rows.Next() {
+		var i GetNotesSharedWithUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+			&i.OwnerLogin,
+			&i.CanEdit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetNotificationsByUserId = `-- name: GetNotificationsByUserId :many
+SELECT nf.id, nf.user_id, nf.actor_id, nf.note_id, nf.comment_id, nf.kind, nf.created_at, nf.read_at, u.login AS actor_login, n.name AS note_name
+FROM notifications nf
+         JOIN users u ON u.id = nf.actor_id
+         JOIN notes n ON n.id = nf.note_id
+WHERE nf.user_id = $1
+ORDER BY nf.created_at DESC, nf.id DESC
+LIMIT 100
+`
+
+type GetNotificationsByUserIdRow struct {
+	ID         int64              `db:"id" json:"id"`
+	UserID     int64              `db:"user_id" json:"user_id"`
+	ActorID    int64              `db:"actor_id" json:"actor_id"`
+	NoteID     int64              `db:"note_id" json:"note_id"`
+	CommentID  *int64             `db:"comment_id" json:"comment_id"`
+	Kind       string             `db:"kind" json:"kind"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	ReadAt     pgtype.Timestamptz `db:"read_at" json:"read_at"`
+	ActorLogin string             `db:"actor_login" json:"actor_login"`
+	NoteName   string             `db:"note_name" json:"note_name"`
+}
+
+func (q *Queries) GetNotificationsByUserId(ctx context.Context, userID int64) ([]*GetNotificationsByUserIdRow, error) {
+	rows, err := q.db.Query(ctx, GetNotificationsByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetNotificationsByUserIdRow{}
+	for rows.Next() {
+		var i GetNotificationsByUserIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ActorID,
+			&i.NoteID,
+			&i.CommentID,
+			&i.Kind,
+			&i.CreatedAt,
+			&i.ReadAt,
+			&i.ActorLogin,
+			&i.NoteName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetOverdueNotesForWebhooks = `-- name: GetOverdueNotesForWebhooks :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+         LEFT JOIN webhook_overdue_notes o ON o.note_id = n.id AND o.deadline_at = n.deadline_at
+WHERE n.is_completed = FALSE
+  AND n.trashed_at IS NULL
+  AND n.deadline_at < CURRENT_DATE
+  AND o.note_id IS NULL
+  AND EXISTS (SELECT 1
+              FROM webhooks w
+              WHERE w.user_id = n.user_id
+                AND w.active
+                AND 'note.overdue' = ANY (w.events))
+ORDER BY n.id
+LIMIT 100
+`
+
+func (q *Queries) GetOverdueNotesForWebhooks(ctx context.Context) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetOverdueNotesForWebhooks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetPendingLoginUserId = `-- name: GetPendingLoginUserId :one
+SELECT user_id
+FROM pending_logins
+WHERE token_hash = $1
+  AND expires_at > NOW()
+`
+
+func (q *Queries) GetPendingLoginUserId(ctx context.Context, tokenHash string) (int64, error) {
+	row := q.db.QueryRow(ctx, GetPendingLoginUserId, tokenHash)
+	var userID int64
+	err := row.Scan(&userID)
+	return userID, err
+}
+
+const GetPublicLinkByToken = `-- name: GetPublicLinkByToken :one
+SELECT pl.id, pl.note_id, pl.user_id, pl.token, pl.password, pl.expires_at, pl.revoked_at, pl.created_at
+FROM public_links pl
+WHERE pl.token = $1
+`
+
+func (q *Queries) GetPublicLinkByToken(ctx context.Context, token string) (*PublicLink, error) {
+	row := q.db.QueryRow(ctx, GetPublicLinkByToken, token)
+	var i PublicLink
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.UserID,
+		&i.Token,
+		&i.Password,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
+const GetRecentLoginAudit = `-- name: GetRecentLoginAudit :many
+SELECT a.id, a.login, a.ip, a.reason, a.created_at
+FROM login_audit a
+ORDER BY a.created_at DESC, a.id DESC
+LIMIT $1
+`
+
+func (q *Queries) GetRecentLoginAudit(ctx context.Context, limit int32) ([]*LoginAudit, error) {
+	rows, err := q.db.Query(ctx, GetRecentLoginAudit, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*LoginAudit{}
+	for rows.Next() {
+		var i LoginAudit
+		if err := rows.Scan(
+			&i.ID,
+			&i.Login,
+			&i.Ip,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetSessionUser = `-- name: GetSessionUser :one
+SELECT u.id, u.login, u.password, u.role, u.disabled_at, u.password_reset_required, u.last_login_at, u.totp_secret, u.totp_enabled_at, u.totp_last_counter
+FROM sessions s
+         JOIN users u ON u.id = s.user_id
+WHERE s.token_hash = $1
+  AND s.expires_at > NOW()
+`
+
+func (q *Queries) GetSessionUser(ctx context.Context, tokenHash string) (*User, error) {
+	row := q.db.QueryRow(ctx, GetSessionUser, tokenHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Login,
+		&i.Password,
+		&i.Role,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.LastLoginAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+	)
+	return &i, err
+}
+
+const GetShareById = `-- name: GetShareById :one
+SELECT s.id, s.owner_id, s.user_id, s.note_id, s.notebook_id, s.permission, s.created_at
+FROM shares s
+WHERE s.id = $1
+`
+
+func (q *Queries) GetShareById(ctx context.Context, id int64) (*Share, error) {
+	row := q.db.QueryRow(ctx, GetShareById, id)
+	var i Share
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.UserID,
+		&i.NoteID,
+		&i.NotebookID,
+		&i.Permission,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
+const GetSharesByNoteId = `-- name: GetSharesByNoteId :many
+SELECT s.id, s.owner_id, s.user_id, s.note_id, s.notebook_id, s.permission, s.created_at, u.login
+FROM shares s
+         JOIN users u ON u.id = s.user_id
+WHERE s.note_id = $1
+ORDER BY u.login, s.id
+`
+
+type GetSharesByNoteIdRow struct {
+	ID         int64       `db:"id" json:"id"`
+	OwnerID    int64       `db:"owner_id" json:"owner_id"`
+	UserID     int64       `db:"user_id" json:"user_id"`
+	NoteID     *int64      `db:"note_id" json:"note_id"`
+	NotebookID *int64      `db:"notebook_id" json:"notebook_id"`
+	Permission string      `db:"permission" json:"permission"`
+	CreatedAt  pgtype.Date `db:"created_at" json:"created_at"`
+	Login      string      `db:"login" json:"login"`
+}
+
+func (q *Queries) GetSharesByNoteId(ctx context.Context, noteID *int64) ([]*GetSharesByNoteIdRow, error) {
+	rows, err := q.db.Query(ctx, GetSharesByNoteId, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetSharesByNoteIdRow{}
+	for rows.Next() {
+		var i GetSharesByNoteIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.UserID,
+			&i.NoteID,
+			&i.NotebookID,
+			&i.Permission,
+			&i.CreatedAt,
+			&i.Login,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetSharesByNotebookId = `-- name: GetSharesByNotebookId :many
+SELECT s.id, s.owner_id, s.user_id, s.note_id, s.notebook_id, s.permission, s.created_at, u.login
+FROM shares s
+         JOIN users u ON u.id = s.user_id
+WHERE s.notebook_id = $1
+ORDER BY u.login, s.id
+`
+
+type GetSharesByNotebookIdRow struct {
+	ID         int64       `db:"id" json:"id"`
+	OwnerID    int64       `db:"owner_id" json:"owner_id"`
+	UserID     int64       `db:"user_id" json:"user_id"`
+	NoteID     *int64      `db:"note_id" json:"note_id"`
+	NotebookID *int64      `db:"notebook_id" json:"notebook_id"`
+	Permission string      `db:"permission" json:"permission"`
+	CreatedAt  pgtype.Date `db:"created_at" json:"created_at"`
+	Login      string      `db:"login" json:"login"`
+}
+
+func (q *Queries) GetSharesByNotebookId(ctx context.Context, notebookID *int64) ([]*GetSharesByNotebookIdRow, error) {
+	rows, err := q.db.Query(ctx, GetSharesByNotebookId, notebookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetSharesByNotebookIdRow{}
+	for rows.Next() {
+		var i GetSharesByNotebookIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.UserID,
+			&i.NoteID,
+			&i.NotebookID,
+			&i.Permission,
+			&i.CreatedAt,
+			&i.Login,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetSystemStats = `-- name: GetSystemStats :one
+SELECT (SELECT COUNT(*) FROM users)::BIGINT                                  AS users_count,
+       (SELECT COUNT(*) FROM users WHERE role = 'admin')::BIGINT             AS admins_count,
+       (SELECT COUNT(*) FROM users WHERE disabled_at IS NOT NULL)::BIGINT    AS disabled_users_count,
+       (SELECT COUNT(*) FROM notes WHERE trashed_at IS NULL)::BIGINT         AS notes_count,
+       (SELECT COUNT(*) FROM notes WHERE trashed_at IS NOT NULL)::BIGINT     AS trashed_notes_count,
+       (SELECT COUNT(*) FROM attachments)::BIGINT                            AS attachments_count,
+       (SELECT COALESCE(SUM(size), 0) FROM attachments)::BIGINT              AS attachments_size,
+       (SELECT COUNT(*) FROM webhook_deliveries WHERE status = 'pending')::BIGINT AS pending_deliveries_count,
+       (SELECT COUNT(*) FROM sessions WHERE expires_at > NOW())::BIGINT      AS sessions_count
+`
+
+type GetSystemStatsRow struct {
+	UsersCount             int64 `db:"users_count" json:"users_count"`
+	AdminsCount            int64 `db:"admins_count" json:"admins_count"`
+	DisabledUsersCount     int64 `db:"disabled_users_count" json:"disabled_users_count"`
+	NotesCount             int64 `db:"notes_count" json:"notes_count"`
+	TrashedNotesCount      int64 `db:"trashed_notes_count" json:"trashed_notes_count"`
+	AttachmentsCount       int64 `db:"attachments_count" json:"attachments_count"`
+	AttachmentsSize        int64 `db:"attachments_size" json:"attachments_size"`
+	PendingDeliveriesCount int64 `db:"pending_deliveries_count" json:"pending_deliveries_count"`
+	SessionsCount          int64 `db:"sessions_count" json:"sessions_count"`
+}
+
+func (q *Queries) GetSystemStats(ctx context.Context) (*GetSystemStatsRow, error) {
+	row := q.db.QueryRow(ctx, GetSystemStats)
+	var i GetSystemStatsRow
+	err := row.Scan(
+		&i.UsersCount,
+		&i.AdminsCount,
+		&i.DisabledUsersCount,
+		&i.NotesCount,
+		&i.TrashedNotesCount,
+		&i.AttachmentsCount,
+		&i.AttachmentsSize,
+		&i.PendingDeliveriesCount,
+		&i.SessionsCount,
+	)
+	return &i, err
+}
+
+const GetTagByName = `-- name: GetTagByName :one
+SELECT t.id, t.user_id, t.name
+FROM tags t
+WHERE t.user_id = $1
+  AND t.name = $2
+`
+
+type GetTagByNameParams struct {
+	UserID int64  `db:"user_id" json:"user_id"`
+	Name   string `db:"name" json:"name"`
+}
+
+func (q *Queries) GetTagByName(ctx context.Context, arg GetTagByNameParams) (*Tag, error) {
+	row := q.db.QueryRow(ctx, GetTagByName, arg.UserID, arg.Name)
+	var i Tag
+	err := row.Scan(&i.ID, &i.UserID, &i.Name)
+	return &i, err
+}
+
+const GetTagNoteStats = `-- name: GetTagNoteStats :many
+SELECT t.name, COUNT(*) AS notes_count, COUNT(*) FILTER (WHERE n.is_completed) AS completed_count
+FROM note_tags nt
+         JOIN tags t ON t.id = nt.tag_id
+         JOIN notes n ON n.id = nt.note_id
+WHERE t.user_id = $1
+  AND n.trashed_at IS NULL
+GROUP BY t.id, t.name
+ORDER BY notes_count DESC, t.name
+`
+
+type GetTagNoteStatsRow struct {
+	Name           string `db:"name" json:"name"`
+	NotesCount     int64  `db:"notes_count" json:"notes_count"`
+	CompletedCount int64  `db:"completed_count" json:"completed_count"`
+}
+
+func (q *Queries) GetTagNoteStats(ctx context.Context, userID int64) ([]*GetTagNoteStatsRow, error) {
+	rows, err := q.db.Query(ctx, GetTagNoteStats, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetTagNoteStatsRow{}
+	for rows.Next() {
+		var i GetTagNoteStatsRow
+		if err := rows.Scan(&i.Name, &i.NotesCount, &i.CompletedCount); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetTagsByNoteIds = `-- name: GetTagsByNoteIds :many
+SELECT nt.note_id, t.name
+FROM note_tags nt
+         JOIN tags t ON t.id = nt.tag_id
+WHERE nt.note_id = ANY ($1::BIGINT[])
+ORDER BY t.name
+`
+
+type GetTagsByNoteIdsRow struct {
+	NoteID int64  `db:"note_id" json:"note_id"`
+	Name   string `db:"name" json:"name"`
+}
+
+func (q *Queries) GetTagsByNoteIds(ctx context.Context, noteIds []int64) ([]*GetTagsByNoteIdsRow, error) {
+	rows, err := q.db.Query(ctx, GetTagsByNoteIds, noteIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetTagsByNoteIdsRow{}
+	for rows.Next() {
+		var i GetTagsByNoteIdsRow
+		if err := rows.Scan(&i.NoteID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetTrashedNotesByUserId = `-- name: GetTrashedNotesByUserId :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE n.user_id = $1
+  AND n.trashed_at IS NOT NULL
+ORDER BY n.trashed_at DESC, n.id
+`
+
+func (q *Queries) GetTrashedNotesByUserId(ctx context.Context, userID int64) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetTrashedNotesByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetUpcomingNotesByUserId = `-- name: GetUpcomingNotesByUserId :many
+SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at, n.recurrence, n.series_id, n.notebook_id, n.trashed_at, n.priority, n.pinned, n.version, n.updated_at, n.completed_at
+FROM notes n
+WHERE n.user_id = $1
+  AND n.is_completed = FALSE
+  AND n.trashed_at IS NULL
+  AND n.deadline_at >= $2::DATE
+  AND n.deadline_at <= $3::DATE
+ORDER BY n.deadline_at, n.id
+`
+
+type GetUpcomingNotesByUserIdParams struct {
+	UserID int64       `db:"user_id" json:"user_id"`
+	Today  pgtype.Date `db:"today" json:"today"`
+	Until  pgtype.Date `db:"until" json:"until"`
+}
+
+func (q *Queries) GetUpcomingNotesByUserId(ctx context.Context, arg GetUpcomingNotesByUserIdParams) ([]*Note, error) {
+	rows, err := q.db.Query(ctx, GetUpcomingNotesByUserId, arg.UserID, arg.Today, arg.Until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Note{}
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.IsCompleted,
+			&i.CreatedAt,
+			&i.DeadlineAt,
+			&i.Recurrence,
+			&i.SeriesID,
+			&i.NotebookID,
+			&i.TrashedAt,
+			&i.Priority,
+			&i.Pinned,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetUserAttachmentsSize = `-- name: GetUserAttachmentsSize :one
+SELECT COALESCE(SUM(a.size), 0)::BIGINT AS total_size
+FROM attachments a
+WHERE a.user_id = $1
+`
+
+func (q *Queries) GetUserAttachmentsSize(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, GetUserAttachmentsSize, userID)
+	var totalSize int64
+	err := row.Scan(&totalSize)
+	return totalSize, err
+}
+
+const GetUserById = `-- name: GetUserById :one
+SELECT u.id, u.login, u.password, u.role, u.disabled_at, u.password_reset_required, u.last_login_at, u.totp_secret, u.totp_enabled_at, u.totp_last_counter
+FROM users u
+WHERE u.id = $1
+`
+
+func (q *Queries) GetUserById(ctx context.Context, id int64) (*User, error) {
+	row := q.db.QueryRow(ctx, GetUserById, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Login,
+		&i.Password,
+		&i.Role,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.LastLoginAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+	)
+	return &i, err
+}
+
+const GetUserByLogin = `-- name: GetUserByLogin :one
+SELECT DISTINCT u.id, u.login, u.password, u.role, u.disabled_at, u.password_reset_required, u.last_login_at, u.totp_secret, u.totp_enabled_at, u.totp_last_counter
+FROM users u
+WHERE u.login = $1
+`
+
+func (q *Queries) GetUserByLogin(ctx context.Context, login string) (*User, error) {
+	row := q.db.QueryRow(ctx, GetUserByLogin, login)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Login,
+		&i.Password,
+		&i.Role,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.LastLoginAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+	)
+	return &i, err
+}
+
+const GetUserByLoginAndPassword = `-- name: GetUserByLoginAndPassword :one
+SELECT DISTINCT u.id, u.login, u.password, u.role, u.disabled_at, u.password_reset_required, u.last_login_at, u.totp_secret, u.totp_enabled_at, u.totp_last_counter
+FROM users u
+WHERE u.login = $1
+  AND u.password = $2
+`
+
+type GetUserByLoginAndPasswordParams struct {
+	Login    string `db:"login" json:"login"`
+	Password string `db:"password" json:"password"`
+}
+
+func (q *Queries) GetUserByLoginAndPassword(ctx context.Context, arg GetUserByLoginAndPasswordParams) (*User, error) {
+	row := q.db.QueryRow(ctx, GetUserByLoginAndPassword, arg.Login, arg.Password)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Login,
+		&i.Password,
+		&i.Role,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+		&i.LastLoginAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+	)
+	return &i, err
+}
+
+const GetUsersByLogins = `-- name: GetUsersByLogins :many
+SELECT u.id, u.login, u.password, u.role, u.disabled_at, u.password_reset_required, u.last_login_at, u.totp_secret, u.totp_enabled_at, u.totp_last_counter
+FROM users u
+WHERE u.login = ANY ($1::TEXT[])
+`
+
+func (q *Queries) GetUsersByLogins(ctx context.Context, logins []string) ([]*User, error) {
+	rows, err := q.db.Query(ctx, GetUsersByLogins, logins)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Login,
+			&i.Password,
+			&i.Role,
+			&i.DisabledAt,
+			&i.PasswordResetRequired,
+			&i.LastLoginAt,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastCounter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetUsersWithNoteCounts = `-- name: GetUsersWithNoteCounts :many
+SELECT u.id,
+       u.login,
+       u.role,
+       u.disabled_at,
+       u.password_reset_required,
+       u.last_login_at,
+       u.totp_enabled_at,
+       COUNT(n.id) FILTER (WHERE n.trashed_at IS NULL) AS notes_count,
+       (SELECT COUNT(*)
+        FROM sessions s
+        WHERE s.user_id = u.id
+          AND s.expires_at > NOW())::BIGINT             AS sessions_count
+FROM users u
+         LEFT JOIN notes n ON n.user_id = u.id
+GROUP BY u.id
+ORDER BY u.login
+`
+
+type GetUsersWithNoteCountsRow struct {
+	ID                    int64              `db:"id" json:"id"`
+	Login                 string             `db:"login" json:"login"`
+	Role                  string             `db:"role" json:"role"`
+	DisabledAt            pgtype.Timestamptz `db:"disabled_at" json:"disabled_at"`
+	PasswordResetRequired bool               `db:"password_reset_required" json:"password_reset_required"`
+	LastLoginAt           pgtype.Timestamptz `db:"last_login_at" json:"last_login_at"`
+	TotpEnabledAt         pgtype.Timestamptz `db:"totp_enabled_at" json:"totp_enabled_at"`
+	NotesCount            int64              `db:"notes_count" json:"notes_count"`
+	SessionsCount         int64              `db:"sessions_count" json:"sessions_count"`
+}
+
+func (q *Queries) GetUsersWithNoteCounts(ctx context.Context) ([]*GetUsersWithNoteCountsRow, error) {
+	rows, err := q.db.Query(ctx, GetUsersWithNoteCounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetUsersWithNoteCountsRow{}
+	for rows.Next() {
+		var i GetUsersWithNoteCountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Login,
+			&i.Role,
+			&i.DisabledAt,
+			&i.PasswordResetRequired,
+			&i.LastLoginAt,
+			&i.TotpEnabledAt,
+			&i.NotesCount,
+			&i.SessionsCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetWebauthnCredentialByCredentialId = `-- name: GetWebauthnCredentialByCredentialId :one
+SELECT c.id, c.user_id, c.name, c.credential_id, c.public_key, c.sign_count, c.created_at, c.last_used_at
+FROM webauthn_credentials c
+WHERE c.credential_id = $1
+`
+
+func (q *Queries) GetWebauthnCredentialByCredentialId(ctx context.Context, credentialID []byte) (*WebauthnCredential, error) {
+	row := q.db.QueryRow(ctx, GetWebauthnCredentialByCredentialId, credentialID)
+	var i WebauthnCredential
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CredentialID,
+		&i.PublicKey,
+		&i.SignCount,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return &i, err
+}
+
+const GetWebauthnCredentialsByUserId = `-- name: GetWebauthnCredentialsByUserId :many
+SELECT c.id, c.user_id, c.name, c.credential_id, c.public_key, c.sign_count, c.created_at, c.last_used_at
+FROM webauthn_credentials c
+WHERE c.user_id = $1
+ORDER BY c.created_at, c.id
+`
+
+func (q *Queries) GetWebauthnCredentialsByUserId(ctx context.Context, userID int64) ([]*WebauthnCredential, error) {
+	rows, err := q.db.Query(ctx, GetWebauthnCredentialsByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*WebauthnCredential{}
+	for rows.Next() {
+		var i WebauthnCredential
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CredentialID,
+			&i.PublicKey,
+			&i.SignCount,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetWebhookById = `-- name: GetWebhookById :one
+SELECT w.id, w.user_id, w.url, w.events, w.secret, w.active, w.created_at
+FROM webhooks w
+WHERE w.id = $1
+`
+
+func (q *Queries) GetWebhookById(ctx context.Context, id int64) (*Webhook, error) {
+	row := q.db.QueryRow(ctx, GetWebhookById, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Events,
+		&i.Secret,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
+const GetWebhookDeliveriesByUserId = `-- name: GetWebhookDeliveriesByUserId :many
+SELECT d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.response_status, d.error, d.created_at, d.delivered_at, d.claimed_at, w.url
+FROM webhook_deliveries d
+         JOIN webhooks w ON w.id = d.webhook_id
+WHERE w.user_id = $1
+ORDER BY d.id DESC
+LIMIT 50
+`
+
+type GetWebhookDeliveriesByUserIdRow struct {
+	ID             int64              `db:"id" json:"id"`
+	WebhookID      int64              `db:"webhook_id" json:"webhook_id"`
+	Event          string             `db:"event" json:"event"`
+	Payload        string             `db:"payload" json:"payload"`
+	Status         string             `db:"status" json:"status"`
+	Attempts       int32              `db:"attempts" json:"attempts"`
+	NextAttemptAt  pgtype.Timestamptz `db:"next_attempt_at" json:"next_attempt_at"`
+	ResponseStatus *int32             `db:"response_status" json:"response_status"`
+	Error          *string            `db:"error" json:"error"`
+	CreatedAt      pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	DeliveredAt    pgtype.Timestamptz `db:"delivered_at" json:"delivered_at"`
+	ClaimedAt      pgtype.Timestamptz `db:"claimed_at" json:"claimed_at"`
+	Url            string             `db:"url" json:"url"`
+}
+
+func (q *Queries) GetWebhookDeliveriesByUserId(ctx context.Context, userID int64) ([]*GetWebhookDeliveriesByUserIdRow, error) {
+	rows, err := q.db.Query(ctx, GetWebhookDeliveriesByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetWebhookDeliveriesByUserIdRow{}
+	for rows.Next() {
+		var i GetWebhookDeliveriesByUserIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.ResponseStatus,
+			&i.Error,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+			&i.ClaimedAt,
+			&i.Url,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetWebhooksByUserId = `-- name: GetWebhooksByUserId :many
+SELECT w.id, w.user_id, w.url, w.events, w.secret, w.active, w.created_at
+FROM webhooks w
+WHERE w.user_id = $1
+ORDER BY w.id
+`
+
+func (q *Queries) GetWebhooksByUserId(ctx context.Context, userID int64) ([]*Webhook, error) {
+	rows, err := q.db.Query(ctx, GetWebhooksByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Webhook{}
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Url,
+			&i.Events,
+			&i.Secret,
+			&i.Active,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetWeeklyNoteStats = `-- name: GetWeeklyNoteStats :many
+WITH weeks AS (SELECT GENERATE_SERIES(DATE_TRUNC('week', $1::TIMESTAMPTZ), DATE_TRUNC('week', NOW()),
+                                      INTERVAL '1 week') AS week_start)
+SELECT w.week_start::TIMESTAMPTZ AS week_start,
+       (SELECT COUNT(*)
+        FROM notes n
+        WHERE n.user_id = $2::BIGINT
+          AND n.trashed_at IS NULL
+          AND n.created_at >= w.week_start
+          AND n.created_at < w.week_start + INTERVAL '1 week')::BIGINT AS created_count,
+       (SELECT COUNT(*)
+        FROM notes n
+        WHERE n.user_id = $2::BIGINT
+          AND n.trashed_at IS NULL
+          AND n.completed_at >= w.week_start
+          AND n.completed_at < w.week_start + INTERVAL '1 week')::BIGINT AS completed_count
+FROM weeks w
+ORDER BY w.week_start
+`
+
+type GetWeeklyNoteStatsParams struct {
+	Since  pgtype.Timestamptz `db:"since" json:"since"`
+	UserID int64              `db:"user_id" json:"user_id"`
+}
+
+type GetWeeklyNoteStatsRow struct {
+	WeekStart      pgtype.Timestamptz `db:"week_start" json:"week_start"`
+	CreatedCount   int64              `db:"created_count" json:"created_count"`
+	CompletedCount int64              `db:"completed_count" json:"completed_count"`
+}
+
+func (q *Queries) GetWeeklyNoteStats(ctx context.Context, arg GetWeeklyNoteStatsParams) ([]*GetWeeklyNoteStatsRow, error) {
+	rows, err := q.db.Query(ctx, GetWeeklyNoteStats, arg.Since, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetWeeklyNoteStatsRow{}
+	for rows.Next() {
+		var i GetWeeklyNoteStatsRow
+		if err := rows.Scan(&i.WeekStart, &i.CreatedCount, &i.CompletedCount); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ImportNote = `-- name: ImportNote :one
+INSERT INTO notes (user_id, name, description, is_completed, deadline_at, priority, pinned, created_at, updated_at,
+                   completed_at, trashed_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7,
+        COALESCE($8::TIMESTAMPTZ, NOW()), NOW(),
+        CASE WHEN $4 THEN NOW() END, $9::TIMESTAMPTZ)
+RETURNING id
+`
+
+type ImportNoteParams struct {
+	UserID      int64              `db:"user_id" json:"user_id"`
+	Name        string             `db:"name" json:"name"`
+	Description *string            `db:"description" json:"description"`
+	IsCompleted bool               `db:"is_completed" json:"is_completed"`
+	DeadlineAt  pgtype.Date        `db:"deadline_at" json:"deadline_at"`
+	Priority    int16              `db:"priority" json:"priority"`
+	Pinned      bool               `db:"pinned" json:"pinned"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	TrashedAt   pgtype.Timestamptz `db:"trashed_at" json:"trashed_at"`
+}
+
+func (q *Queries) ImportNote(ctx context.Context, arg ImportNoteParams) (int64, error) {
+	row := q.db.QueryRow(ctx, ImportNote,
+		arg.UserID,
+		arg.Name,
+		arg.Description,
+		arg.IsCompleted,
+		arg.DeadlineAt,
+		arg.Priority,
+		arg.Pinned,
+		arg.CreatedAt,
+		arg.TrashedAt,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const LockLoginAttempts = `-- name: LockLoginAttempts :exec
+UPDATE login_attempts
+SET locked_until = GREATEST(locked_until, NOW() + MAKE_INTERVAL(secs => $1::FLOAT8))
+WHERE key = $2
+`
+
+type LockLoginAttemptsParams struct {
+	Seconds float64 `db:"seconds" json:"seconds"`
+	Key     string  `db:"key" json:"key"`
+}
+
+func (q *Queries) LockLoginAttempts(ctx context.Context, arg LockLoginAttemptsParams) error {
+	_, err := q.db.Exec(ctx, LockLoginAttempts, arg.Seconds, arg.Key)
+	return err
+}
+
+const LockUserForUpdate = `-- name: LockUserForUpdate :exec
+SELECT u.id
+FROM users u
+WHERE u.id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockUserForUpdate(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, LockUserForUpdate, id)
+	return err
+}
+
+const MarkBulkActionUndone = `-- name: MarkBulkActionUndone :execrows
+UPDATE bulk_actions
+SET undone_at = NOW()
+WHERE id = $1
+  AND undone_at IS NULL
+`
+
+func (q *Queries) MarkBulkActionUndone(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, MarkBulkActionUndone, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const MarkNoteOverdueSent = `-- name: MarkNoteOverdueSent :execrows
+INSERT INTO webhook_overdue_notes (note_id, deadline_at)
+VALUES ($1, $2)
+ON CONFLICT (note_id) DO UPDATE SET deadline_at = EXCLUDED.deadline_at
+WHERE webhook_overdue_notes.deadline_at <> EXCLUDED.deadline_at
+`
+
+type MarkNoteOverdueSentParams struct {
+	NoteID     int64       `db:"note_id" json:"note_id"`
+	DeadlineAt pgtype.Date `db:"deadline_at" json:"deadline_at"`
+}
+
+func (q *Queries) MarkNoteOverdueSent(ctx context.Context, arg MarkNoteOverdueSentParams) (int64, error) {
+	result, err := q.db.Exec(ctx, MarkNoteOverdueSent, arg.NoteID, arg.DeadlineAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const MarkNotificationsRead = `-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+  AND read_at IS NULL
+`
+
+func (q *Queries) MarkNotificationsRead(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, MarkNotificationsRead, userID)
+	return err
+}
+
+const MoveNotebook = `-- name: MoveNotebook :exec
+UPDATE notebooks
+SET parent_id = $1
+WHERE id = $2
+`
+
+type MoveNotebookParams struct {
+	ParentID *int64 `db:"parent_id" json:"parent_id"`
+	ID       int64  `db:"id" json:"id"`
+}
+
+func (q *Queries) MoveNotebook(ctx context.Context, arg MoveNotebookParams) error {
+	_, err := q.db.Exec(ctx, MoveNotebook, arg.ParentID, arg.ID)
+	return err
+}
+
+const MoveNotesBetweenNotebooks = `-- name: MoveNotesBetweenNotebooks :execrows
+UPDATE notes
+SET notebook_id = $1::BIGINT,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE notebook_id = ANY ($2::BIGINT[])
+`
+
+type MoveNotesBetweenNotebooksParams struct {
+	TargetID    *int64  `db:"target_id" json:"target_id"`
+	NotebookIds []int64 `db:"notebook_ids" json:"notebook_ids"`
+}
+
+func (q *Queries) MoveNotesBetweenNotebooks(ctx context.Context, arg MoveNotesBetweenNotebooksParams) (int64, error) {
+	result, err := q.db.Exec(ctx, MoveNotesBetweenNotebooks, arg.TargetID, arg.NotebookIds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const ReclaimExports = `-- name: ReclaimExports :execrows
+UPDATE exports
+SET status = 'pending'
+WHERE status = 'running'
+  AND (claimed_at IS NULL OR claimed_at < NOW() - MAKE_INTERVAL(secs => $1::FLOAT8))
+`
+
+func (q *Queries) ReclaimExports(ctx context.Context, leaseSeconds float64) (int64, error) {
+	result, err := q.db.Exec(ctx, ReclaimExports, leaseSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const ReclaimWebhookDeliveries = `-- name: ReclaimWebhookDeliveries :execrows
+UPDATE webhook_deliveries
+SET status = 'pending'
+WHERE status = 'running'
+  AND (claimed_at IS NULL OR claimed_at < NOW() - MAKE_INTERVAL(secs => $1::FLOAT8))
+`
+
+func (q *Queries) ReclaimWebhookDeliveries(ctx context.Context, leaseSeconds float64) (int64, error) {
+	result, err := q.db.Exec(ctx, ReclaimWebhookDeliveries, leaseSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const RecordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_attempts (key, failures, last_failure_at)
+VALUES ($1, 1, NOW())
+ON CONFLICT (key) DO UPDATE
+    SET failures        = CASE
+                              WHEN login_attempts.last_failure_at < NOW() - MAKE_INTERVAL(secs => $2::FLOAT8)
+                                  THEN 1
+                              ELSE login_attempts.failures + 1 END,
+        last_failure_at = NOW()
+RETURNING failures
+`
+
+type RecordLoginFailureParams struct {
+	Key          string  `db:"key" json:"key"`
+	ResetSeconds float64 `db:"reset_seconds" json:"reset_seconds"`
+}
+
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (int32, error) {
+	row := q.db.QueryRow(ctx, RecordLoginFailure, arg.Key, arg.ResetSeconds)
+	var failures int32
+	err := row.Scan(&failures)
+	return failures, err
+}
+
+const ReleaseDigest = `-- name: ReleaseDigest :exec
+UPDATE digest_settings
+SET last_sent_on = $1
+WHERE user_id = $2
+  AND last_sent_on = $3
+`
+
+type ReleaseDigestParams struct {
+	LastSentOn pgtype.Date `db:"last_sent_on" json:"last_sent_on"`
+	UserID     int64       `db:"user_id" json:"user_id"`
+	Today      pgtype.Date `db:"today" json:"today"`
+}
+
+func (q *Queries) ReleaseDigest(ctx context.Context, arg ReleaseDigestParams) error {
+	_, err := q.db.Exec(ctx, ReleaseDigest, arg.LastSentOn, arg.UserID, arg.Today)
+	return err
+}
+
+const ReleaseLoginAttempt = `-- name: ReleaseLoginAttempt :exec
+UPDATE login_attempts
+SET failures     = GREATEST(failures - 1, 0),
+    locked_until = CASE WHEN failures - 1 < $1::INTEGER THEN NULL ELSE locked_until END
+WHERE key = $2
+`
+
+type ReleaseLoginAttemptParams struct {
+	FreeAttempts int32  `db:"free_attempts" json:"free_attempts"`
+	Key          string `db:"key" json:"key"`
+}
+
+func (q *Queries) ReleaseLoginAttempt(ctx context.Context, arg ReleaseLoginAttemptParams) error {
+	_, err := q.db.Exec(ctx, ReleaseLoginAttempt, arg.FreeAttempts, arg.Key)
+	return err
+}
+
+const RenameNotebook = `-- name: RenameNotebook :exec
+UPDATE notebooks
+SET name = $1
+WHERE id = $2
+`
+
+type RenameNotebookParams struct {
+	Name string `db:"name" json:"name"`
+	ID   int64  `db:"id" json:"id"`
+}
+
+func (q *Queries) RenameNotebook(ctx context.Context, arg RenameNotebookParams) error {
+	_, err := q.db.Exec(ctx, RenameNotebook, arg.Name, arg.ID)
+	return err
+}
+
+const RenewExportLease = `-- name: RenewExportLease :exec
+UPDATE exports
+SET claimed_at = NOW()
+WHERE id = $1
+  AND status = 'running'
+`
+
+func (q *Queries) RenewExportLease(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, RenewExportLease, id)
+	return err
+}
+
+const RequeueWebhookDelivery = `-- name: RequeueWebhookDelivery :execrows
+UPDATE webhook_deliveries d
+SET status          = 'pending',
+    attempts        = 0,
+    next_attempt_at = NOW()
+FROM webhooks w
+WHERE d.id = $1
+  AND d.status = 'failed'
+  AND w.id = d.webhook_id
+  AND w.user_id = $2
+`
+
+type RequeueWebhookDeliveryParams struct {
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) RequeueWebhookDelivery(ctx context.Context, arg RequeueWebhookDeliveryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, RequeueWebhookDelivery, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const RequireUserPasswordReset = `-- name: RequireUserPasswordReset :execrows
+UPDATE users
+SET password_reset_required = TRUE
+WHERE id = $1
+`
+
+func (q *Queries) RequireUserPasswordReset(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, RequireUserPasswordReset, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const ResetUserTotp = `-- name: ResetUserTotp :execrows
+UPDATE users
+SET totp_secret       = NULL,
+    totp_enabled_at   = NULL,
+    totp_last_counter = 0
+WHERE id = $1
+`
+
+func (q *Queries) ResetUserTotp(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, ResetUserTotp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const RestoreNote = `-- name: RestoreNote :exec
+UPDATE notes
+SET trashed_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) RestoreNote(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, RestoreNote, id)
+	return err
+}
+
+const RetryWebhookDelivery = `-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status          = 'pending',
+    attempts        = attempts + 1,
+    response_status = $1,
+    error           = $2,
+    next_attempt_at = $3
+WHERE id = $4
+`
+
+type RetryWebhookDeliveryParams struct {
+	ResponseStatus *int32             `db:"response_status" json:"response_status"`
+	Error          *string            `db:"error" json:"error"`
+	NextAttemptAt  pgtype.Timestamptz `db:"next_attempt_at" json:"next_attempt_at"`
+	ID             int64              `db:"id" json:"id"`
+}
+
+func (q *Queries) RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, RetryWebhookDelivery,
+		arg.ResponseStatus,
+		arg.Error,
+		arg.NextAttemptAt,
+		arg.ID,
+	)
+	return err
+}
+
+const RevokePublicLink = `-- name: RevokePublicLink :execrows
+UPDATE public_links
+SET revoked_at = NOW()
+WHERE id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL
+`
+
+type RevokePublicLinkParams struct {
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) RevokePublicLink(ctx context.Context, arg RevokePublicLinkParams) (int64, error) {
+	result, err := q.db.Exec(ctx, RevokePublicLink, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const SetNoteNotebook = `-- name: SetNoteNotebook :exec
+UPDATE notes
+SET notebook_id = $1,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE id = $2
+`
+
+type SetNoteNotebookParams struct {
+	NotebookID *int64 `db:"notebook_id" json:"notebook_id"`
+	ID         int64  `db:"id" json:"id"`
+}
+
+func (q *Queries) SetNoteNotebook(ctx context.Context, arg SetNoteNotebookParams) error {
+	_, err := q.db.Exec(ctx, SetNoteNotebook, arg.NotebookID, arg.ID)
+	return err
+}
+
+const SetNotePinned = `-- name: SetNotePinned :exec
+UPDATE notes
+SET pinned     = $1,
+    version    = version + 1,
+    updated_at = NOW()
+WHERE id = $2
+`
+
+type SetNotePinnedParams struct {
+	Pinned bool  `db:"pinned" json:"pinned"`
+	ID     int64 `db:"id" json:"id"`
+}
+
+func (q *Queries) SetNotePinned(ctx context.Context, arg SetNotePinnedParams) error {
+	_, err := q.db.Exec(ctx, SetNotePinned, arg.Pinned, arg.ID)
+	return err
+}
+
+const SetUserDisabled = `-- name: SetUserDisabled :execrows
+UPDATE users
+SET disabled_at = CASE WHEN $1::BOOLEAN THEN COALESCE(disabled_at, NOW()) END
+WHERE id = $2
+`
+
+type SetUserDisabledParams struct {
+	Disabled bool  `db:"disabled" json:"disabled"`
+	ID       int64 `db:"id" json:"id"`
+}
+
+func (q *Queries) SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (int64, error) {
+	result, err := q.db.Exec(ctx, SetUserDisabled, arg.Disabled, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const SetUserLastLogin = `-- name: SetUserLastLogin :exec
+UPDATE users
+SET last_login_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) SetUserLastLogin(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, SetUserLastLogin, id)
+	return err
+}
+
+const SetUserTotpSecret = `-- name: SetUserTotpSecret :execrows
+UPDATE users
+SET totp_secret       = $2,
+    totp_last_counter = 0
+WHERE id = $1
+  AND totp_enabled_at IS NULL
+`
+
+type SetUserTotpSecretParams struct {
+	ID         int64   `db:"id" json:"id"`
+	TotpSecret *string `db:"totp_secret" json:"totp_secret"`
+}
+
+func (q *Queries) SetUserTotpSecret(ctx context.Context, arg SetUserTotpSecretParams) (int64, error) {
+	result, err := q.db.Exec(ctx, SetUserTotpSecret, arg.ID, arg.TotpSecret)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const SetWebhookActive = `-- name: SetWebhookActive :execrows
+UPDATE webhooks
+SET active = $1
+WHERE id = $2
+  AND user_id = $3
+`
+
+type SetWebhookActiveParams struct {
+	Active bool  `db:"active" json:"active"`
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) SetWebhookActive(ctx context.Context, arg SetWebhookActiveParams) (int64, error) {
+	result, err := q.db.Exec(ctx, SetWebhookActive, arg.Active, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const ShareNote = `-- name: ShareNote :exec
+INSERT INTO shares (owner_id, user_id, note_id, permission)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, note_id) WHERE note_id IS NOT NULL DO UPDATE SET permission = EXCLUDED.permission
+`
+
+type ShareNoteParams struct {
+	OwnerID    int64  `db:"owner_id" json:"owner_id"`
+	UserID     int64  `db:"user_id" json:"user_id"`
+	NoteID     *int64 `db:"note_id" json:"note_id"`
+	Permission string `db:"permission" json:"permission"`
+}
+
+func (q *Queries) ShareNote(ctx context.Context, arg ShareNoteParams) error {
+	_, err := q.db.Exec(ctx, ShareNote,
+		arg.OwnerID,
+		arg.UserID,
+		arg.NoteID,
+		arg.Permission,
+	)
+	return err
+}
+
+const ShareNotebook = `-- name: ShareNotebook :exec
+INSERT INTO shares (owner_id, user_id, notebook_id, permission)
 VALUES ($1, $2, $3, $4)
-RETURNING id
+ON CONFLICT (user_id, notebook_id) WHERE notebook_id IS NOT NULL DO UPDATE SET permission = EXCLUDED.permission
 `
 
-type CreateNoteParams struct {
-	UserID      int64       `db:"user_id" json:"user_id"`
-	Name        string      `db:"name" json:"name"`
-	Description *string     `db:"description" json:"description"`
-	DeadlineAt  pgtype.Date `db:"deadline_at" json:"deadline_at"`
+type ShareNotebookParams struct {
+	OwnerID    int64  `db:"owner_id" json:"owner_id"`
+	UserID     int64  `db:"user_id" json:"user_id"`
+	NotebookID *int64 `db:"notebook_id" json:"notebook_id"`
+	Permission string `db:"permission" json:"permission"`
 }
 
-func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (int64, error) {
-	row := q.db.QueryRow(ctx, CreateNote,
+func (q *Queries) ShareNotebook(ctx context.Context, arg ShareNotebookParams) error {
+	_, err := q.db.Exec(ctx, ShareNotebook,
+		arg.OwnerID,
 		arg.UserID,
-		arg.Name,
-		arg.Description,
-		arg.DeadlineAt,
+		arg.NotebookID,
+		arg.Permission,
 	)
-	var id int64
-	err := row.Scan(&id)
-	return id, err
+	return err
 }
 
-const CreateUser = `-- name: CreateUser :one
-INSERT INTO users (login, password)
-VALUES ($1, $2)
-RETURNING id
+const SnapshotBulkActionNotes = `-- name: SnapshotBulkActionNotes :execrows
+INSERT INTO bulk_action_notes (bulk_action_id, note_id, is_completed, completed_at, deadline_at, notebook_id, trashed_at,
+                               had_tag)
+SELECT $1::BIGINT,
+       n.id,
+       n.is_completed,
+       n.completed_at,
+       n.deadline_at,
+       n.notebook_id,
+       n.trashed_at,
+       EXISTS (SELECT 1 FROM note_tags nt WHERE nt.note_id = n.id AND nt.tag_id = $2::BIGINT)
+FROM notes n
+WHERE n.user_id = $3::BIGINT
+  AND n.trashed_at IS NULL
+  AND n.id = ANY ($4::BIGINT[])
 `
 
-type CreateUserParams struct {
-	Login    string `db:"login" json:"login"`
-	Password string `db:"password" json:"password"`
+type SnapshotBulkActionNotesParams struct {
+	BulkActionID int64   `db:"bulk_action_id" json:"bulk_action_id"`
+	TagID        *int64  `db:"tag_id" json:"tag_id"`
+	UserID       int64   `db:"user_id" json:"user_id"`
+	NoteIds      []int64 `db:"note_ids" json:"note_ids"`
 }
 
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (int64, error) {
-	row := q.db.QueryRow(ctx, CreateUser, arg.Login, arg.Password)
-	var id int64
-	err := row.Scan(&id)
-	return id, err
+func (q *Queries) SnapshotBulkActionNotes(ctx context.Context, arg SnapshotBulkActionNotesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, SnapshotBulkActionNotes,
+		arg.BulkActionID,
+		arg.TagID,
+		arg.UserID,
+		arg.NoteIds,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const DeleteNoteById = `-- name: DeleteNoteById :one
+const StopNoteSeries = `-- name: StopNoteSeries :execrows
+UPDATE notes
+SET recurrence = NULL,
+    version    = version + 1,
+    updated_at = NOW()
+WHERE COALESCE(series_id, id) = $1::BIGINT
+`
+
+func (q *Queries) StopNoteSeries(ctx context.Context, seriesID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, StopNoteSeries, seriesID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const TakePasskeyCeremony = `-- name: TakePasskeyCeremony :one
 DELETE
-FROM notes
-WHERE id = $1
-RETURNING id
+FROM passkey_ceremonies
+WHERE token_hash = $1
+  AND user_id = $2
+  AND expires_at > NOW()
+RETURNING challenge
 `
 
-func (q *Queries) DeleteNoteById(ctx context.Context, id int64) (int64, error) {
-	row := q.db.QueryRow(ctx, DeleteNoteById, id)
-	err := row.Scan(&id)
-	return id, err
+type TakePasskeyCeremonyParams struct {
+	TokenHash string `db:"token_hash" json:"token_hash"`
+	UserID    int64  `db:"user_id" json:"user_id"`
 }
 
-const GetNoteById = `-- name: GetNoteById :one
-SELECT DISTINCT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at
-FROM notes n
-WHERE n.id = $1
+func (q *Queries) TakePasskeyCeremony(ctx context.Context, arg TakePasskeyCeremonyParams) ([]byte, error) {
+	row := q.db.QueryRow(ctx, TakePasskeyCeremony, arg.TokenHash, arg.UserID)
+	var challenge []byte
+	err := row.Scan(&challenge)
+	return challenge, err
+}
+
+const TrashNote = `-- name: TrashNote :exec
+UPDATE notes
+SET trashed_at = NOW()
+WHERE id = $1
 `
 
-func (q *Queries) GetNoteById(ctx context.Context, id int64) (*Note, error) {
-	row := q.db.QueryRow(ctx, GetNoteById, id)
-	var i Note
-	err := row.Scan(
-		&i.ID,
-		&i.UserID,
-		&i.Name,
-		&i.Description,
-		&i.IsCompleted,
-		&i.CreatedAt,
-		&i.DeadlineAt,
-	)
-	return &i, err
+func (q *Queries) TrashNote(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, TrashNote, id)
+	return err
 }
 
-const GetNotesByUserId = `-- name: GetNotesByUserId :many
-SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at
-FROM notes n
-WHERE user_id = $1
-ORDER BY n.created_at, n.id
+const TrashNotesInNotebooks = `-- name: TrashNotesInNotebooks :execrows
+UPDATE notes
+SET trashed_at  = NOW(),
+    notebook_id = NULL
+WHERE notebook_id = ANY ($1::BIGINT[])
 `
 
-func (q *Queries) GetNotesByUserId(ctx context.Context, userID int64) ([]*Note, error) {
-	rows, err := q.db.Query(ctx, GetNotesByUserId, userID)
+func (q *Queries) TrashNotesInNotebooks(ctx context.Context, notebookIds []int64) (int64, error) {
+	result, err := q.db.Exec(ctx, TrashNotesInNotebooks, notebookIds)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []*Note{}
-	for rows.Next() {
-		var i Note
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.Name,
-			&i.Description,
-			&i.IsCompleted,
-			&i.CreatedAt,
-			&i.DeadlineAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, &i)
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
-const GetNotesByUserIdAndSearch = `-- name: GetNotesByUserIdAndSearch :many
-SELECT n.id, n.user_id, n.name, n.description, n.is_completed, n.created_at, n.deadline_at
-FROM notes n
-WHERE user_id = $1
-  AND (name ILIKE '%' || $2 || '%')
-ORDER BY n.created_at, n.id
+const UndoBulkAddTag = `-- name: UndoBulkAddTag :exec
+DELETE
+FROM note_tags nt
+    USING bulk_action_notes b
+WHERE b.bulk_action_id = $1::BIGINT
+  AND b.had_tag = FALSE
+  AND nt.note_id = b.note_id
+  AND nt.tag_id = $2::BIGINT
 `
 
-type GetNotesByUserIdAndSearchParams struct {
-	UserID  int64   `db:"user_id" json:"user_id"`
-	Column2 *string `db:"column_2" json:"column_2"`
+type UndoBulkAddTagParams struct {
+	BulkActionID int64 `db:"bulk_action_id" json:"bulk_action_id"`
+	TagID        int64 `db:"tag_id" json:"tag_id"`
 }
 
-func (q *Queries) GetNotesByUserIdAndSearch(ctx context.Context, arg GetNotesByUserIdAndSearchParams) ([]*Note, error) {
-	rows, err := q.db.Query(ctx, GetNotesByUserIdAndSearch, arg.UserID, arg.Column2)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []*Note{}
-	for rows.Next() {
-		var i Note
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.Name,
-			&i.Description,
-			&i.IsCompleted,
-			&i.CreatedAt,
-			&i.DeadlineAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, &i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) UndoBulkAddTag(ctx context.Context, arg UndoBulkAddTagParams) error {
+	_, err := q.db.Exec(ctx, UndoBulkAddTag, arg.BulkActionID, arg.TagID)
+	return err
 }
 
-const GetUserByLoginAndPassword = `-- name: GetUserByLoginAndPassword :one
-SELECT DISTINCT u.id, u.login, u.password
-FROM users u
-WHERE u.login = $1
-  AND u.password = $2
+const UndoBulkCompleted = `-- name: UndoBulkCompleted :exec
+UPDATE notes n
+SET is_completed = b.is_completed,
+    completed_at = b.completed_at,
+    version      = n.version + 1,
+    updated_at   = NOW()
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = $1::BIGINT
+  AND b.created = FALSE
+  AND n.id = b.note_id
 `
 
-type GetUserByLoginAndPasswordParams struct {
-	Login    string `db:"login" json:"login"`
-	Password string `db:"password" json:"password"`
+func (q *Queries) UndoBulkCompleted(ctx context.Context, bulkActionID int64) error {
+	_, err := q.db.Exec(ctx, UndoBulkCompleted, bulkActionID)
+	return err
 }
 
-func (q *Queries) GetUserByLoginAndPassword(ctx context.Context, arg GetUserByLoginAndPasswordParams) (*User, error) {
-	row := q.db.QueryRow(ctx, GetUserByLoginAndPassword, arg.Login, arg.Password)
-	var i User
-	err := row.Scan(&i.ID, &i.Login, &i.Password)
-	return &i, err
+const UndoBulkDeadline = `-- name: UndoBulkDeadline :exec
+UPDATE notes n
+SET deadline_at = b.deadline_at,
+    version     = n.version + 1,
+    updated_at  = NOW()
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = $1::BIGINT
+  AND n.id = b.note_id
+`
+
+func (q *Queries) UndoBulkDeadline(ctx context.Context, bulkActionID int64) error {
+	_, err := q.db.Exec(ctx, UndoBulkDeadline, bulkActionID)
+	return err
+}
+
+const UndoBulkNotebook = `-- name: UndoBulkNotebook :exec
+UPDATE notes n
+SET notebook_id = (SELECT nb.id FROM notebooks nb WHERE nb.id = b.notebook_id),
+    version     = n.version + 1,
+    updated_at  = NOW()
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = $1::BIGINT
+  AND n.id = b.note_id
+`
+
+func (q *Queries) UndoBulkNotebook(ctx context.Context, bulkActionID int64) error {
+	_, err := q.db.Exec(ctx, UndoBulkNotebook, bulkActionID)
+	return err
+}
+
+const UndoBulkRemoveTag = `-- name: UndoBulkRemoveTag :exec
+INSERT INTO note_tags (note_id, tag_id)
+SELECT b.note_id, $1::BIGINT
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = $2::BIGINT
+  AND b.had_tag
+ON CONFLICT DO NOTHING
+`
+
+type UndoBulkRemoveTagParams struct {
+	TagID        int64 `db:"tag_id" json:"tag_id"`
+	BulkActionID int64 `db:"bulk_action_id" json:"bulk_action_id"`
+}
+
+func (q *Queries) UndoBulkRemoveTag(ctx context.Context, arg UndoBulkRemoveTagParams) error {
+	_, err := q.db.Exec(ctx, UndoBulkRemoveTag, arg.TagID, arg.BulkActionID)
+	return err
+}
+
+const UndoBulkTrash = `-- name: UndoBulkTrash :exec
+UPDATE notes n
+SET trashed_at = b.trashed_at
+FROM bulk_action_notes b
+WHERE b.bulk_action_id = $1::BIGINT
+  AND n.id = b.note_id
+`
+
+func (q *Queries) UndoBulkTrash(ctx context.Context, bulkActionID int64) error {
+	_, err := q.db.Exec(ctx, UndoBulkTrash, bulkActionID)
+	return err
+}
+
+const UpdateComment = `-- name: UpdateComment :execrows
+UPDATE note_comments
+SET body       = $1,
+    updated_at = NOW()
+WHERE id = $2
+  AND user_id = $3
+`
+
+type UpdateCommentParams struct {
+	Body   string `db:"body" json:"body"`
+	ID     int64  `db:"id" json:"id"`
+	UserID int64  `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) UpdateComment(ctx context.Context, arg UpdateCommentParams) (int64, error) {
+	result, err := q.db.Exec(ctx, UpdateComment, arg.Body, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const UpdateNote = `-- name: UpdateNote :one
@@ -207,9 +4346,17 @@ UPDATE notes
 SET name         = $1,
     description  = $2,
     is_completed = $3,
-    deadline_at  = $4
-WHERE id = $5
-RETURNING id
+    deadline_at  = $4,
+    recurrence   = $5,
+    notebook_id  = $6,
+    priority     = $7,
+    pinned       = $8,
+    version      = version + 1,
+    updated_at   = NOW(),
+    completed_at = CASE WHEN $3 THEN COALESCE(completed_at, NOW()) END
+WHERE id = $9
+  AND version = $10
+RETURNING version
 `
 
 type UpdateNoteParams struct {
@@ -217,18 +4364,250 @@ type UpdateNoteParams struct {
 	Description *string     `db:"description" json:"description"`
 	IsCompleted bool        `db:"is_completed" json:"is_completed"`
 	DeadlineAt  pgtype.Date `db:"deadline_at" json:"deadline_at"`
+	Recurrence  *string     `db:"recurrence" json:"recurrence"`
+	NotebookID  *int64      `db:"notebook_id" json:"notebook_id"`
+	Priority    int16       `db:"priority" json:"priority"`
+	Pinned      bool        `db:"pinned" json:"pinned"`
 	ID          int64       `db:"id" json:"id"`
+	Version     int32       `db:"version" json:"version"`
 }
 
-func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (int64, error) {
+func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (int32, error) {
 	row := q.db.QueryRow(ctx, UpdateNote,
 		arg.Name,
 		arg.Description,
 		arg.IsCompleted,
 		arg.DeadlineAt,
+		arg.Recurrence,
+		arg.NotebookID,
+		arg.Priority,
+		arg.Pinned,
+		arg.ID,
+		arg.Version,
+	)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const UpdateNoteDescription = `-- name: UpdateNoteDescription :one
+UPDATE notes
+SET description = $1,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE id = $2
+  AND version = $3
+RETURNING version
+`
+
+type UpdateNoteDescriptionParams struct {
+	Description *string `db:"description" json:"description"`
+	ID          int64   `db:"id" json:"id"`
+	Version     int32   `db:"version" json:"version"`
+}
+
+func (q *Queries) UpdateNoteDescription(ctx context.Context, arg UpdateNoteDescriptionParams) (int32, error) {
+	row := q.db.QueryRow(ctx, UpdateNoteDescription, arg.Description, arg.ID, arg.Version)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const UpdateNoteSeries = `-- name: UpdateNoteSeries :execrows
+UPDATE notes
+SET name        = $1,
+    description = $2,
+    recurrence  = $3,
+    version     = version + 1,
+    updated_at  = NOW()
+WHERE COALESCE(series_id, id) = $4::BIGINT
+  AND is_completed = FALSE
+`
+
+type UpdateNoteSeriesParams struct {
+	Name        string  `db:"name" json:"name"`
+	Description *string `db:"description" json:"description"`
+	Recurrence  *string `db:"recurrence" json:"recurrence"`
+	SeriesID    int64   `db:"series_id" json:"series_id"`
+}
+
+func (q *Queries) UpdateNoteSeries(ctx context.Context, arg UpdateNoteSeriesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, UpdateNoteSeries,
+		arg.Name,
+		arg.Description,
+		arg.Recurrence,
+		arg.SeriesID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const UpdateNoteTemplate = `-- name: UpdateNoteTemplate :execrows
+UPDATE note_templates
+SET name            = $1,
+    title           = $2,
+    description     = $3,
+    tags            = $4,
+    deadline_offset = $5
+WHERE id = $6
+  AND user_id = $7
+`
+
+type UpdateNoteTemplateParams struct {
+	Name           string   `db:"name" json:"name"`
+	Title          string   `db:"title" json:"title"`
+	Description    string   `db:"description" json:"description"`
+	Tags           []string `db:"tags" json:"tags"`
+	DeadlineOffset *string  `db:"deadline_offset" json:"deadline_offset"`
+	ID             int64    `db:"id" json:"id"`
+	UserID         int64    `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) UpdateNoteTemplate(ctx context.Context, arg UpdateNoteTemplateParams) (int64, error) {
+	result, err := q.db.Exec(ctx, UpdateNoteTemplate,
+		arg.Name,
+		arg.Title,
+		arg.Description,
+		arg.Tags,
+		arg.DeadlineOffset,
 		arg.ID,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const UpdateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password                = $2,
+    password_reset_required = FALSE
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID       int64  `db:"id" json:"id"`
+	Password string `db:"password" json:"password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, UpdateUserPassword, arg.ID, arg.Password)
+	return err
+}
+
+const UpdateWebauthnCredentialUsage = `-- name: UpdateWebauthnCredentialUsage :execrows
+UPDATE webauthn_credentials
+SET sign_count   = $1,
+    last_used_at = NOW()
+WHERE id = $2
+  AND sign_count = $3
+`
+
+type UpdateWebauthnCredentialUsageParams struct {
+	SignCount         int64 `db:"sign_count" json:"sign_count"`
+	ID                int64 `db:"id" json:"id"`
+	PreviousSignCount int64 `db:"previous_sign_count" json:"previous_sign_count"`
+}
+
+func (q *Queries) UpdateWebauthnCredentialUsage(ctx context.Context, arg UpdateWebauthnCredentialUsageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, UpdateWebauthnCredentialUsage, arg.SignCount, arg.ID, arg.PreviousSignCount)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const UpsertDigestSettings = `-- name: UpsertDigestSettings :exec
+INSERT INTO digest_settings (user_id, enabled, email, send_time, timezone, days_ahead)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id) DO UPDATE
+    SET enabled    = EXCLUDED.enabled,
+        email      = EXCLUDED.email,
+        send_time  = EXCLUDED.send_time,
+        timezone   = EXCLUDED.timezone,
+        days_ahead = EXCLUDED.days_ahead
+`
+
+type UpsertDigestSettingsParams struct {
+	UserID    int64       `db:"user_id" json:"user_id"`
+	Enabled   bool        `db:"enabled" json:"enabled"`
+	Email     string      `db:"email" json:"email"`
+	SendTime  pgtype.Time `db:"send_time" json:"send_time"`
+	Timezone  string      `db:"timezone" json:"timezone"`
+	DaysAhead int32       `db:"days_ahead" json:"days_ahead"`
+}
+
+func (q *Queries) UpsertDigestSettings(ctx context.Context, arg UpsertDigestSettingsParams) error {
+	_, err := q.db.Exec(ctx, UpsertDigestSettings,
+		arg.UserID,
+		arg.Enabled,
+		arg.Email,
+		arg.SendTime,
+		arg.Timezone,
+		arg.DaysAhead,
 	)
+	return err
+}
+
+const UpsertTag = `-- name: UpsertTag :one
+INSERT INTO tags (user_id, name)
+VALUES ($1, $2)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id
+`
+
+type UpsertTagParams struct {
+	UserID int64  `db:"user_id" json:"user_id"`
+	Name   string `db:"name" json:"name"`
+}
+
+func (q *Queries) UpsertTag(ctx context.Context, arg UpsertTagParams) (int64, error) {
+	row := q.db.QueryRow(ctx, UpsertTag, arg.UserID, arg.Name)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
+
+const UseRecoveryCode = `-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL
+`
+
+type UseRecoveryCodeParams struct {
+	UserID   int64  `db:"user_id" json:"user_id"`
+	CodeHash string `db:"code_hash" json:"code_hash"`
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, UseRecoveryCode, arg.UserID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const UseTotpCounter = `-- name: UseTotpCounter :execrows
+UPDATE users
+SET totp_last_counter = $2
+WHERE id = $1
+  AND totp_last_counter < $2
+`
+
+type UseTotpCounterParams struct {
+	ID              int64 `db:"id" json:"id"`
+	TotpLastCounter int64 `db:"totp_last_counter" json:"totp_last_counter"`
+}
+
+func (q *Queries) UseTotpCounter(ctx context.Context, arg UseTotpCounterParams) (int64, error) {
+	result, err := q.db.Exec(ctx, UseTotpCounter, arg.ID, arg.TotpLastCounter)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,87 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ContentType = "image/png"
+	// MaxPixels protects from decompression bombs: dimensions are checked before decoding.
+	MaxPixels = 40_000_000
+)
+
+// Make decodes a JPEG, PNG or GIF image and returns a PNG that fits into a
+// maxSide x maxSide square. Smaller images keep their size.
+func Make(r io.ReadSeeker, maxSide int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode image config")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, errors.Errorf("unsupported image size %dx%d", cfg.Width, cfg.Height)
+	}
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode image")
+	}
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, Resize(src, maxSide)); err != nil {
+		return nil, errors.Wrap(err, "encode thumbnail")
+	}
+	return buf.Bytes(), nil
+}
+
+// Resize scales the image down with box filtering, keeping the aspect ratio.
+func Resize(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+	dw, dh := maxSide, h*maxSide/w
+	if h > w {
+		dw, dh = w*maxSide/h, maxSide
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0 := bounds.Min.Y + y*h/dh
+		y1 := max(bounds.Min.Y+(y+1)*h/dh, y0+1)
+		for x := 0; x < dw; x++ {
+			x0 := bounds.Min.X + x*w/dw
+			x1 := max(bounds.Min.X+(x+1)*w/dw, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
@@ -1,14 +1,13 @@
 package utils
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"net/http"
 	"net/url"
-	"strconv"
-	"time"
 
-	"github.com/notjoji/web-notes/internal/repository"
 	"github.com/pkg/errors"
 )
 
@@ -24,15 +23,17 @@ func ReadCookie(name string, r *http.Request) (value string, err error) {
 	return url.QueryUnescape(str)
 }
 
-func GetAuthToken(user *repository.User) string {
-	time64 := time.Now().Unix()
-	timeInt := strconv.FormatInt(time64, 10)
-	token := user.Login + user.Password + timeInt
-	return GetHashedString(token)
-}
-
 func GetHashedString(str string) string {
 	h := sha256.New()
 	h.Write([]byte(str))
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// GenerateToken returns a URL-safe random token built from n bytes of crypto/rand.
+func GenerateToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generate token")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,261 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// The subset of CBOR (RFC 8949) used by authenticators: integers, byte and text
+// strings, arrays, maps, tags and simple values. Indefinite lengths are not
+// allowed by CTAP2 and are rejected.
+
+const (
+	cborUint = iota
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const maxCBORDepth = 16
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// DecodeCBOR decodes the first item of data and returns it with the number of
+// bytes it took. Integers become int64, strings string, byte strings []byte,
+// arrays []any and maps map[any]any with int64 or string keys.
+func DecodeCBOR(data []byte) (any, int, error) {
+	d := cborDecoder{data: data}
+	v, err := d.decode(0)
+	return v, d.pos, err
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errCBORTruncated
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func (d *cborDecoder) head() (byte, byte, uint64, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info > 27:
+		return 0, 0, 0, errors.Errorf("cbor: unsupported additional info %d", info)
+	}
+	arg, err := d.next(1 << (info - 24))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var value uint64
+	for _, c := range arg {
+		value = value<<8 | uint64(c)
+	}
+	return major, info, value, nil
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint, cborNegInt:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflows int64")
+		}
+		if major == cborNegInt {
+			return -1 - int64(arg), nil
+		}
+		return int64(arg), nil
+	case cborBytes, cborText:
+		b, err := d.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborText {
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	case cborArray:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errCBORTruncated
+		}
+		items := make([]any, arg)
+		for i := range items {
+			if items[i], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborMap:
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, errCBORTruncated
+		}
+		items := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, errors.Errorf("cbor: unsupported map key %T", key)
+			}
+			if _, ok := items[key]; ok {
+				return nil, errors.Errorf("cbor: duplicate map key %v", key)
+			}
+			if items[key], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborTag:
+		return d.decode(depth + 1)
+	}
+	return decodeSimple(info, arg)
+}
+
+func decodeSimple(info byte, arg uint64) (any, error) {
+	switch {
+	case info == 20:
+		return false, nil
+	case info == 21:
+		return true, nil
+	case info == 22 || info == 23:
+		return nil, nil
+	case info == 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case info == 27:
+		return math.Float64frombits(arg), nil
+	}
+	return nil, errors.Errorf("cbor: unsupported simple value %d", info)
+}
+
+// EncodeCBOR encodes integers, strings, byte strings, booleans, nil, []any and
+// maps with int or string keys. Map keys are sorted as CTAP2 canonical CBOR
+// requires: shorter encodings first, then bytewise.
+func EncodeCBOR(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeCBOR(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeHead(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(major<<5 | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.Write([]byte{major<<5 | 24, byte(arg)})
+	case arg <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(arg)))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(arg)))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, arg))
+	}
+}
+
+func encodeCBOR(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case int:
+		return encodeCBOR(buf, int64(v))
+	case int64:
+		if v < 0 {
+			writeHead(buf, cborNegInt, uint64(-1-v))
+		} else {
+			writeHead(buf, cborUint, uint64(v))
+		}
+	case uint64:
+		writeHead(buf, cborUint, v)
+	case []byte:
+		writeHead(buf, cborBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case bool:
+		if v {
+			buf.WriteByte(cborSimple<<5 | 21)
+		} else {
+			buf.WriteByte(cborSimple<<5 | 20)
+		}
+	case nil:
+		buf.WriteByte(cborSimple<<5 | 22)
+	case []any:
+		writeHead(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeCBOR(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		return encodeCBORMap(buf, v)
+	default:
+		return errors.Errorf("cbor: unsupported type %T", v)
+	}
+	return nil
+}
+
+func encodeCBORMap(buf *bytes.Buffer, m map[any]any) error {
+	type entry struct{ key, value []byte }
+	entries := make([]entry, 0, len(m))
+	for key, value := range m {
+		switch key.(type) {
+		case int, int64, string:
+		default:
+			return errors.Errorf("cbor: unsupported map key %T", key)
+		}
+		k, err := EncodeCBOR(key)
+		if err != nil {
+			return err
+		}
+		val, err := EncodeCBOR(value)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{k, val})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].key, entries[j].key
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return bytes.Compare(a, b) < 0
+	})
+	writeHead(buf, cborMap, uint64(len(entries)))
+	for _, e := range entries {
+		buf.Write(e.key)
+		buf.Write(e.value)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package webauthn
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// COSE_Key labels and values (RFC 9053) of an ES256 key: ECDSA with SHA-256 on P-256.
+const (
+	coseKeyType = 1
+	coseAlg     = 3
+	coseCurve   = -1
+	coseX       = -2
+	coseY       = -3
+
+	coseKeyTypeEC2 = 2
+	coseCurveP256  = 1
+
+	AlgES256 = -7
+)
+
+var errUnsupportedKey = errors.New("webauthn: only ES256 keys are supported")
+
+// ParsePublicKey reads a COSE_Key from credential data and checks that the
+// point lies on the curve.
+func ParsePublicKey(coseKey []byte) (*ecdsa.PublicKey, error) {
+	v, _, err := DecodeCBOR(coseKey)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := v.(map[any]any)
+	if !ok {
+		return nil, errors.New("webauthn: public key is not a map")
+	}
+	if key[int64(coseKeyType)] != int64(coseKeyTypeEC2) || key[int64(coseAlg)] != int64(AlgES256) ||
+		key[int64(coseCurve)] != int64(coseCurveP256) {
+		return nil, errUnsupportedKey
+	}
+	x, okX := key[int64(coseX)].([]byte)
+	y, okY := key[int64(coseY)].([]byte)
+	if !okX || !okY || len(x) != 32 || len(y) != 32 {
+		return nil, errors.New("webauthn: malformed ES256 public key")
+	}
+	point := append(append([]byte{4}, x...), y...)
+	if _, err = ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, errors.Wrap(err, "webauthn: invalid public key")
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
+
+// EncodePublicKey returns the COSE_Key of an ES256 public key.
+func EncodePublicKey(key *ecdsa.PublicKey) ([]byte, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, errUnsupportedKey
+	}
+	return EncodeCBOR(map[any]any{
+		coseKeyType: coseKeyTypeEC2,
+		coseAlg:     AlgES256,
+		coseCurve:   coseCurveP256,
+		coseX:       key.X.FillBytes(make([]byte, 32)),
+		coseY:       key.Y.FillBytes(make([]byte, 32)),
+	})
+}
@@ -0,0 +1,238 @@
+// Package webauthn verifies WebAuthn registrations and assertions (passkeys)
+// without third-party dependencies. It supports ES256 credentials and the "none"
+// attestation format, which is what browsers send unless attestation is requested.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FlagUserPresent  = 0x01
+	FlagUserVerified = 0x04
+	FlagAttestedData = 0x40
+	FlagExtensions   = 0x80
+
+	TypeCreate = "webauthn.create"
+	TypeGet    = "webauthn.get"
+
+	challengeSize = 32
+	authDataSize  = 37
+)
+
+var (
+	// ErrSignCount means the authenticator counter went backwards: the
+	// credential may have been cloned.
+	ErrSignCount = errors.New("webauthn: signature counter did not increase")
+	// ErrUserNotVerified means the authenticator did not verify the user although
+	// the relying party requires it.
+	ErrUserNotVerified = errors.New("webauthn: user was not verified")
+
+	errChallenge = errors.New("webauthn: challenge mismatch")
+)
+
+// RelyingParty is the site credentials are bound to. ID is its domain and Origin
+// the scheme, host and port the browser reports. With UserVerification set, the
+// authenticator must have checked a PIN or biometrics, not just a touch.
+type RelyingParty struct {
+	ID               string
+	Name             string
+	Origin           string
+	UserVerification bool
+}
+
+// Credential is what is stored after registration.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type AuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func NewChallenge() ([]byte, error) {
+	b := make([]byte, challengeSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(err, "webauthn: generate challenge")
+	}
+	return b, nil
+}
+
+// DecodeBase64 accepts the unpadded base64url used by WebAuthn JSON, with or without padding.
+func DecodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func EncodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < authDataSize {
+		return nil, errors.New("webauthn: authenticator data too short")
+	}
+	authData := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[authDataSize:]
+	if authData.Flags&FlagAttestedData != 0 {
+		// AAGUID, credential ID length and the credential ID
+		if len(rest) < 18 {
+			return nil, errors.New("webauthn: attested credential data too short")
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		if len(rest) < 18+idLen {
+			return nil, errors.New("webauthn: credential ID truncated")
+		}
+		authData.CredentialID = rest[18 : 18+idLen]
+		_, n, err := DecodeCBOR(rest[18+idLen:])
+		if err != nil {
+			return nil, errors.Wrap(err, "webauthn: credential public key")
+		}
+		authData.PublicKey = rest[18+idLen : 18+idLen+n]
+		rest = rest[18+idLen+n:]
+	}
+	if authData.Flags&FlagExtensions != 0 {
+		_, n, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, errors.Wrap(err, "webauthn: extensions")
+		}
+		rest = rest[n:]
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("webauthn: trailing bytes in authenticator data")
+	}
+	return authData, nil
+}
+
+func (rp RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge []byte) error {
+	var clientData ClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return errors.Wrap(err, "webauthn: client data")
+	}
+	if clientData.Type != ceremony {
+		return errors.Errorf("webauthn: unexpected client data type %q", clientData.Type)
+	}
+	got, err := DecodeBase64(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return errChallenge
+	}
+	if clientData.Origin != rp.Origin || clientData.CrossOrigin {
+		return errors.Errorf("webauthn: unexpected origin %q", clientData.Origin)
+	}
+	return nil
+}
+
+func (rp RelyingParty) verifyAuthenticatorData(authData *AuthenticatorData) error {
+	hash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.RPIDHash, hash[:]) {
+		return errors.New("webauthn: credential belongs to another relying party")
+	}
+	if authData.Flags&FlagUserPresent == 0 {
+		return errors.New("webauthn: user was not present")
+	}
+	if rp.UserVerification && authData.Flags&FlagUserVerified == 0 {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// VerifyRegistration checks the response to navigator.credentials.create and
+// returns the new credential.
+func (rp RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, TypeCreate, challenge); err != nil {
+		return nil, err
+	}
+	v, _, err := DecodeCBOR(attestationObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "webauthn: attestation object")
+	}
+	attestation, ok := v.(map[any]any)
+	if !ok {
+		return nil, errors.New("webauthn: attestation object is not a map")
+	}
+	if format := attestation["fmt"]; format != "none" {
+		return nil, errors.Errorf("webauthn: unsupported attestation format %v", format)
+	}
+	if statement, ok := attestation["attStmt"].(map[any]any); !ok || len(statement) != 0 {
+		return nil, errors.New("webauthn: attestation statement must be empty")
+	}
+	raw, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("webauthn: authenticator data missing")
+	}
+	authData, err := ParseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err = rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.Flags&FlagAttestedData == 0 || len(authData.CredentialID) == 0 {
+		return nil, errors.New("webauthn: credential data missing")
+	}
+	if _, err = ParsePublicKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+	return &Credential{
+		ID:        append([]byte(nil), authData.CredentialID...),
+		PublicKey: append([]byte(nil), authData.PublicKey...),
+		SignCount: authData.SignCount,
+	}, nil
+}
+
+// VerifyAssertion checks the response to navigator.credentials.get for a stored
+// credential and returns its new signature counter.
+func (rp RelyingParty) VerifyAssertion(
+	credential *Credential, challenge, clientDataJSON, authenticatorData, signature []byte,
+) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, TypeGet, challenge); err != nil {
+		return 0, err
+	}
+	authData, err := ParseAuthenticatorData(authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	if err = rp.verifyAuthenticatorData(authData); err != nil {
+		return 0, err
+	}
+	key, err := ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authenticatorData...), clientDataHash[:]...))
+	if !ecdsa.VerifyASN1(key, digest[:], signature) {
+		return 0, errors.New("webauthn: invalid signature")
+	}
+	// authenticators without a counter always report zero
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		return 0, ErrSignCount
+	}
+	return authData.SignCount, nil
+}
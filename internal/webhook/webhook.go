@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	EventNoteCreated   = "note.created"
+	EventNoteCompleted = "note.completed"
+	EventNoteOverdue   = "note.overdue"
+	EventTest          = "webhook.test"
+
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+
+	// MaxAttempts is the number of attempts before a delivery is given up.
+	MaxAttempts = 8
+
+	signaturePrefix = "sha256="
+	firstRetry      = 30 * time.Second
+	maxRetry        = 6 * time.Hour
+	requestTimeout  = 10 * time.Second
+)
+
+// ErrForbiddenAddress is returned for webhooks that point into the server's own
+// network: loopback, private, link-local (cloud metadata) and similar addresses.
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// Events are the note events a webhook can subscribe to.
+var Events = []string{EventNoteCreated, EventNoteCompleted, EventNoteOverdue}
+
+// Sign returns the HMAC-SHA256 signature of the body in the "sha256=<hex>" form.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature made by Sign; receivers can use it as a reference.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+func NewSecret() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Backoff returns the delay before the next attempt after the given number of
+// failed attempts: 30s, 1m, 2m... up to 6h.
+func Backoff(attempts int) time.Duration {
+	delay := firstRetry
+	for i := 1; i < attempts && delay < maxRetry; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetry)
+}
+
+// ValidateURL accepts absolute http and https URLs.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook URL must be an absolute http(s) URL")
+	}
+	return nil
+}
+
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID int64
+	Body       []byte
+}
+
+// Result is the outcome of one attempt; Status is 0 when no response was received.
+type Result struct {
+	Status int
+	Err    error
+}
+
+func (r *Result) OK() bool {
+	return r.Err == nil
+}
+
+// PublicIP reports whether webhooks may be sent to the address.
+func PublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsMulticast() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast()
+}
+
+// dialControl checks the address after DNS resolution, so a public host name that
+// resolves to an internal address is refused as well.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender uses the client as is; without one it makes a client that connects
+// to public addresses only and does not follow redirects.
+func NewSender(client *http.Client) *Sender {
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{Timeout: requestTimeout, Control: dialControl}).DialContext
+		client = &http.Client{
+			Transport: transport,
+			Timeout:   requestTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	return &Sender{client: client}
+}
+
+// Send posts the signed body; any status other than 2xx is an error.
+func (s *Sender) Send(ctx context.Context, req *Request) *Result {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return &Result{Err: err}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "web-notes-webhook")
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderDelivery, strconv.FormatInt(req.DeliveryID, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, req.Body))
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return &Result{Err: err}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &Result{Status: resp.StatusCode, Err: fmt.Errorf("unexpected status %s", resp.Status)}
+	}
+	return &Result{Status: resp.StatusCode}
+}
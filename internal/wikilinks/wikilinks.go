@@ -0,0 +1,111 @@
+package wikilinks
+
+import (
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var linkPattern = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+
+// Link is a [[Note name]] or [[#123]] reference found in a note description.
+type Link struct {
+	Start int
+	End   int
+	ID    int64
+	Name  string
+}
+
+func (l Link) String() string {
+	if l.ID != 0 {
+		return "[[#" + strconv.FormatInt(l.ID, 10) + "]]"
+	}
+	return "[[" + l.Name + "]]"
+}
+
+func parseTarget(target string) (int64, string, bool) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return 0, "", false
+	}
+	if digits, ok := strings.CutPrefix(target, "#"); ok {
+		if id, err := strconv.ParseInt(digits, 10, 64); err == nil && id > 0 {
+			return id, "", true
+		}
+	}
+	return 0, target, true
+}
+
+func Parse(text string) []Link {
+	var links []Link
+	for _, match := range linkPattern.FindAllStringSubmatchIndex(text, -1) {
+		id, name, ok := parseTarget(text[match[2]:match[3]])
+		if !ok {
+			continue
+		}
+		links = append(links, Link{Start: match[0], End: match[1], ID: id, Name: name})
+	}
+	return links
+}
+
+// Unique returns the links without repetitions; names are compared case-insensitively
+// the same way they are resolved.
+func Unique(links []Link) []Link {
+	seen := make(map[string]bool, len(links))
+	unique := make([]Link, 0, len(links))
+	for _, link := range links {
+		key := strings.ToLower(link.String())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, link)
+	}
+	return unique
+}
+
+// Rename rewrites the links to oldName so that they keep pointing to the renamed note.
+func Rename(text, oldName, newName string) string {
+	links := Parse(text)
+	var b strings.Builder
+	last := 0
+	for _, link := range links {
+		if link.ID != 0 || !strings.EqualFold(link.Name, oldName) {
+			continue
+		}
+		b.WriteString(text[last:link.Start])
+		b.WriteString("[[" + newName + "]]")
+		last = link.End
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Target is a resolved link; a link without a target is rendered as broken.
+type Target struct {
+	URL   string
+	Title string
+}
+
+// Render escapes the text and replaces the links with anchors to their targets.
+func Render(text string, resolve func(Link) *Target) template.HTML {
+	var b strings.Builder
+	last := 0
+	for _, link := range Parse(text) {
+		b.WriteString(template.HTMLEscapeString(text[last:link.Start]))
+		if target := resolve(link); target != nil {
+			b.WriteString(`<a href="` + template.HTMLEscapeString(target.URL) + `">` +
+				template.HTMLEscapeString(target.Title) + `</a>`)
+		} else {
+			b.WriteString(`<span class="text-decoration-line-through" title="Заметка не найдена">` +
+				template.HTMLEscapeString(link.String()) + `</span>`)
+		}
+		last = link.End
+	}
+	b.WriteString(template.HTMLEscapeString(text[last:]))
+	return template.HTML(b.String())
+}
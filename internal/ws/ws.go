@@ -0,0 +1,359 @@
+// Package ws implements the part of RFC 6455 the application needs: the server
+// handshake, a client for tests, and text, close and ping/pong frames.
+package ws
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+
+	CloseNormal       = 1000
+	CloseGoingAway    = 1001
+	CloseProtocol     = 1002
+	CloseTooBig       = 1009
+	acceptGUID        = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	maxControlPayload = 125
+	writeTimeout      = 10 * time.Second
+)
+
+var (
+	ErrClosed          = errors.New("websocket: connection closed")
+	ErrMessageTooLarge = errors.New("websocket: message too large")
+	errProtocol        = errors.New("websocket: protocol error")
+	errOrigin          = errors.New("websocket: cross-origin request")
+)
+
+// Conn is a websocket connection. ReadMessage must be called from one goroutine;
+// writes may be concurrent.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	client   bool
+	maxSize  int64
+	writeMu  sync.Mutex
+	closeMu  sync.Mutex
+	isClosed bool
+}
+
+// AcceptKey computes Sec-WebSocket-Accept for a Sec-WebSocket-Key.
+func AcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SameOrigin reports whether the page that opened the connection is served from
+// the requested host or one of the allowed origins. Browsers send cookies with
+// cross-site websocket handshakes, so other sites must be refused. Requests
+// without Origin don't come from browsers.
+func SameOrigin(r *http.Request, allowed ...string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, a := range allowed {
+		if a != "" && strings.EqualFold(origin, a) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// Upgrade performs the server handshake for pages of the same origin or one of
+// the allowed ones; on failure an HTTP error is already written.
+func Upgrade(rw http.ResponseWriter, r *http.Request, maxSize int64, origins ...string) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(rw, "ожидается WebSocket-соединение", http.StatusBadRequest)
+		return nil, errProtocol
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		rw.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(rw, "неподдерживаемая версия WebSocket", http.StatusUpgradeRequired)
+		return nil, errProtocol
+	}
+	if !SameOrigin(r, origins...) {
+		http.Error(rw, "запрос с другого сайта запрещён", http.StatusForbidden)
+		return nil, errOrigin
+	}
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		http.Error(rw, "WebSocket не поддерживается", http.StatusInternalServerError)
+		return nil, errProtocol
+	}
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// the server's read deadline may still be set for the request
+	_ = conn.SetDeadline(time.Time{})
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n\r\n"
+	if _, err = conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, br: brw.Reader, maxSize: maxSize}, nil
+}
+
+// Dial connects to a ws:// URL; it is meant for tests and tools.
+func Dial(rawURL string, header http.Header, maxSize int64) (*Conn, error) {
+	if !strings.HasPrefix(rawURL, "ws://") {
+		return nil, errors.New("websocket: only ws:// URLs are supported")
+	}
+	rest := strings.TrimPrefix(rawURL, "ws://")
+	host, path := rest, "/"
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		host, path = rest[:i], rest[i:]
+	}
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req, _ := http.NewRequest(http.MethodGet, "http://"+host+path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err = req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		conn.Close()
+		return nil, errors.Errorf("websocket: handshake failed: %s", resp.Status)
+	}
+	return &Conn{conn: conn, br: br, client: true, maxSize: maxSize}, nil
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered and
+// fragmented messages are joined; a close frame is answered and ends with ErrClosed.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var message []byte
+	opcode := -1
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case OpPing:
+			if err = c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			_ = c.CloseWithCode(code)
+			return 0, nil, ErrClosed
+		case OpContinuation:
+			if opcode < 0 {
+				return 0, nil, c.fail(CloseProtocol, errProtocol)
+			}
+		case OpText, OpBinary:
+			if opcode >= 0 {
+				return 0, nil, c.fail(CloseProtocol, errProtocol)
+			}
+			opcode = op
+		default:
+			return 0, nil, c.fail(CloseProtocol, errProtocol)
+		}
+
+		if int64(len(message)+len(payload)) > c.maxSize {
+			return 0, nil, c.fail(CloseTooBig, ErrMessageTooLarge)
+		}
+		message = append(message, payload...)
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := head[0]&0x80 != 0
+	op := int(head[0] & 0x0F)
+	masked := head[1]&0x80 != 0
+	if head[0]&0x70 != 0 || masked == c.client {
+		// no extensions are negotiated; clients must mask and servers must not
+		return false, 0, nil, c.fail(CloseProtocol, errProtocol)
+	}
+
+	length := int64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]) & (1<<63 - 1))
+	}
+	if op >= OpClose && (length > maxControlPayload || !fin) {
+		return false, 0, nil, c.fail(CloseProtocol, errProtocol)
+	}
+	if length > c.maxSize {
+		return false, 0, nil, c.fail(CloseTooBig, ErrMessageTooLarge)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+func (c *Conn) writeFrame(op int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|byte(op))
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	if c.client {
+		var mask [4]byte
+		_, _ = rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(OpText, data)
+}
+
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteText(data)
+}
+
+func (c *Conn) Ping() error {
+	return c.writeFrame(OpPing, nil)
+}
+
+func (c *Conn) fail(code int, err error) error {
+	_ = c.CloseWithCode(code)
+	return err
+}
+
+// CloseWithCode sends a close frame and closes the connection.
+func (c *Conn) CloseWithCode(code int) error {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+	if c.isClosed {
+		return nil
+	}
+	c.isClosed = true
+	_ = c.writeFrame(OpClose, binary.BigEndian.AppendUint16(nil, uint16(code)))
+	return c.conn.Close()
+}
+
+func (c *Conn) Close() error {
+	return c.CloseWithCode(CloseNormal)
+}
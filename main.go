@@ -6,11 +6,17 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/notjoji/web-notes/internal/app"
+	"github.com/notjoji/web-notes/internal/blobstore"
 	"github.com/notjoji/web-notes/internal/config"
+	"github.com/notjoji/web-notes/internal/digest"
+	"github.com/notjoji/web-notes/internal/events"
+	"github.com/notjoji/web-notes/internal/notifier"
 	"github.com/notjoji/web-notes/internal/repository"
 	"github.com/notjoji/web-notes/pgdb"
 )
@@ -31,15 +37,35 @@ func main() {
 	db := repository.New(conn.Pool())
 	defer conn.Close()
 
-	application := app.NewApp(ctx, db)
+	if smtpNotifier := notifier.NewSMTPFromEnv(); smtpNotifier != nil {
+		digestJob, err := digest.NewJob(db, smtpNotifier, filepath.Join("public", "email"))
+		if err != nil {
+			log.Fatal("Can't init digest job: ", err)
+			return
+		}
+		go digestJob.Run(ctx, time.Minute)
+	}
+
+	blobs, err := blobstore.NewFromEnv()
+	if err != nil {
+		log.Fatal("Can't init blob store: ", err)
+		return
+	}
+
+	application := app.NewApp(ctx, conn.Pool(), blobs)
+	fanout := application.FanOut(conn, events.Channel)
+	go conn.Listen(ctx, events.Channel, fanout.Receive)
+	go application.RunExports(ctx, 10*time.Second)
+	go application.RunWebhooks(ctx, 10*time.Second)
 	router := httprouter.New()
 	application.Routes(router)
 
 	port := os.Getenv("API_PORT")
 	server := &http.Server{
-		Addr:        fmt.Sprintf(":%s", port),
-		ReadTimeout: time.Second * 3,
-		Handler:     router,
+		Addr:              fmt.Sprintf(":%s", port),
+		ReadHeaderTimeout: time.Second * 3,
+		ReadTimeout:       time.Minute * 5,
+		Handler:           router,
 	}
 	if err := server.ListenAndServe(); err != nil {
 		fmt.Println(fmt.Errorf("http listen err: %w", err))
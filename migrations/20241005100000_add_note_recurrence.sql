@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS recurrence VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS series_id  BIGINT;
+
+CREATE INDEX IF NOT EXISTS notes_series_id_idx ON notes (series_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS notes_series_id_idx;
+
+ALTER TABLE notes
+    DROP COLUMN IF EXISTS series_id,
+    DROP COLUMN IF EXISTS recurrence;
+-- +goose StatementEnd
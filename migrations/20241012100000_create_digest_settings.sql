@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS digest_settings
+(
+    user_id      BIGINT       NOT NULL PRIMARY KEY,
+    enabled      BOOLEAN      NOT NULL DEFAULT 'FALSE',
+    email        VARCHAR(255) NOT NULL,
+    send_time    TIME         NOT NULL DEFAULT '08:00',
+    timezone     VARCHAR(64)  NOT NULL DEFAULT 'UTC',
+    days_ahead   INTEGER      NOT NULL DEFAULT 3,
+    last_sent_on DATE,
+    CONSTRAINT digest_settings_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS digest_settings CASCADE;
+-- +goose StatementEnd
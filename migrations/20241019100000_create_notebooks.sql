@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS notebooks
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    parent_id  BIGINT,
+    name       VARCHAR(50) NOT NULL,
+    created_at DATE        NOT NULL DEFAULT NOW()::DATE,
+    CONSTRAINT notebooks_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT notebooks_to_notebooks_id_fk FOREIGN KEY (parent_id)
+        REFERENCES notebooks (id)
+        ON DELETE CASCADE
+);
+
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS notebook_id BIGINT,
+    ADD COLUMN IF NOT EXISTS trashed_at  TIMESTAMPTZ,
+    ADD CONSTRAINT notes_to_notebooks_id_fk FOREIGN KEY (notebook_id)
+        REFERENCES notebooks (id)
+        ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS notes_notebook_id_idx ON notes (notebook_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS notes_notebook_id_idx;
+
+ALTER TABLE notes
+    DROP CONSTRAINT IF EXISTS notes_to_notebooks_id_fk,
+    DROP COLUMN IF EXISTS trashed_at,
+    DROP COLUMN IF EXISTS notebook_id;
+
+DROP TABLE IF EXISTS notebooks CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notes
+    ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS pinned   BOOLEAN  NOT NULL DEFAULT 'FALSE';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notes
+    DROP COLUMN IF EXISTS pinned,
+    DROP COLUMN IF EXISTS priority;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS shares
+(
+    id          BIGSERIAL   NOT NULL PRIMARY KEY,
+    owner_id    BIGINT      NOT NULL,
+    user_id     BIGINT      NOT NULL,
+    note_id     BIGINT,
+    notebook_id BIGINT,
+    permission  VARCHAR(10) NOT NULL DEFAULT 'view',
+    created_at  DATE        NOT NULL DEFAULT NOW()::DATE,
+    CONSTRAINT shares_to_owner_users_id_fk FOREIGN KEY (owner_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT shares_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT shares_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT shares_to_notebooks_id_fk FOREIGN KEY (notebook_id)
+        REFERENCES notebooks (id)
+        ON DELETE CASCADE,
+    CONSTRAINT shares_target_check CHECK ((note_id IS NULL) <> (notebook_id IS NULL)),
+    CONSTRAINT shares_permission_check CHECK (permission IN ('view', 'edit'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS shares_user_id_note_id_uidx ON shares (user_id, note_id) WHERE note_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS shares_user_id_notebook_id_uidx ON shares (user_id, notebook_id) WHERE notebook_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS shares_user_id_notebook_id_uidx;
+DROP INDEX IF EXISTS shares_user_id_note_id_uidx;
+
+DROP TABLE IF EXISTS shares CASCADE;
+-- +goose StatementEnd
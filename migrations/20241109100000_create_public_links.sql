@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public_links
+(
+    id         BIGSERIAL    NOT NULL PRIMARY KEY,
+    note_id    BIGINT       NOT NULL,
+    user_id    BIGINT       NOT NULL,
+    token      VARCHAR(64)  NOT NULL UNIQUE,
+    password   VARCHAR(128),
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CONSTRAINT public_links_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT public_links_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS public_links_user_id_idx ON public_links (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public_links_user_id_idx;
+
+DROP TABLE IF EXISTS public_links CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS attachments
+(
+    id           BIGSERIAL    NOT NULL PRIMARY KEY,
+    note_id      BIGINT       NOT NULL,
+    user_id      BIGINT       NOT NULL,
+    blob_key     VARCHAR(255) NOT NULL,
+    thumb_key    VARCHAR(255),
+    file_name    VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size         BIGINT       NOT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CONSTRAINT attachments_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT attachments_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS attachments_note_id_idx ON attachments (note_id);
+CREATE INDEX IF NOT EXISTS attachments_user_id_idx ON attachments (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS attachments_user_id_idx;
+DROP INDEX IF EXISTS attachments_note_id_idx;
+
+DROP TABLE IF EXISTS attachments CASCADE;
+-- +goose StatementEnd
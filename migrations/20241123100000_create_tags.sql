@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tags
+(
+    id      BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id BIGINT      NOT NULL,
+    name    VARCHAR(50) NOT NULL,
+    CONSTRAINT tags_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT tags_user_id_name_key UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS note_tags
+(
+    note_id BIGINT NOT NULL,
+    tag_id  BIGINT NOT NULL,
+    CONSTRAINT note_tags_pk PRIMARY KEY (note_id, tag_id),
+    CONSTRAINT note_tags_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT note_tags_to_tags_id_fk FOREIGN KEY (tag_id)
+        REFERENCES tags (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS note_tags_tag_id_idx ON note_tags (tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS note_tags_tag_id_idx;
+
+DROP TABLE IF EXISTS note_tags CASCADE;
+DROP TABLE IF EXISTS tags CASCADE;
+-- +goose StatementEnd
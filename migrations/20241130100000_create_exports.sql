@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS exports
+(
+    id          BIGSERIAL    NOT NULL PRIMARY KEY,
+    user_id     BIGINT       NOT NULL,
+    status      VARCHAR(10)  NOT NULL DEFAULT 'pending',
+    blob_key    VARCHAR(255),
+    size        BIGINT       NOT NULL DEFAULT 0,
+    error       VARCHAR(255),
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    CONSTRAINT exports_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT exports_status_check CHECK (status IN ('pending', 'running', 'ready', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS exports_user_id_idx ON exports (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS exports_user_id_idx;
+
+DROP TABLE IF EXISTS exports CASCADE;
+-- +goose StatementEnd
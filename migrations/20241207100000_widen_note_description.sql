@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notes
+    ALTER COLUMN description TYPE TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notes
+    ALTER COLUMN description TYPE VARCHAR(255) USING LEFT(description, 255);
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS bulk_actions
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    action     VARCHAR(20) NOT NULL,
+    tag_id     BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    undone_at  TIMESTAMPTZ,
+    CONSTRAINT bulk_actions_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT bulk_actions_to_tags_id_fk FOREIGN KEY (tag_id)
+        REFERENCES tags (id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS bulk_actions_user_id_idx ON bulk_actions (user_id);
+
+CREATE TABLE IF NOT EXISTS bulk_action_notes
+(
+    bulk_action_id BIGINT  NOT NULL,
+    note_id        BIGINT  NOT NULL,
+    is_completed   BOOLEAN NOT NULL,
+    deadline_at    DATE,
+    notebook_id    BIGINT,
+    trashed_at     TIMESTAMPTZ,
+    had_tag        BOOLEAN NOT NULL DEFAULT 'FALSE',
+    created        BOOLEAN NOT NULL DEFAULT 'FALSE',
+    CONSTRAINT bulk_action_notes_pk PRIMARY KEY (bulk_action_id, note_id),
+    CONSTRAINT bulk_action_notes_to_bulk_actions_id_fk FOREIGN KEY (bulk_action_id)
+        REFERENCES bulk_actions (id)
+        ON DELETE CASCADE,
+    CONSTRAINT bulk_action_notes_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS bulk_action_notes CASCADE;
+DROP INDEX IF EXISTS bulk_actions_user_id_idx;
+DROP TABLE IF EXISTS bulk_actions CASCADE;
+-- +goose StatementEnd
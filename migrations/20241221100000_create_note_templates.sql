@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS note_templates
+(
+    id              BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id         BIGINT      NOT NULL,
+    name            VARCHAR(50) NOT NULL,
+    title           VARCHAR(50) NOT NULL DEFAULT '',
+    description     TEXT        NOT NULL DEFAULT '',
+    tags            TEXT[]      NOT NULL DEFAULT '{}',
+    deadline_offset VARCHAR(20),
+    CONSTRAINT note_templates_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT note_templates_user_id_name_key UNIQUE (user_id, name)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS note_templates CASCADE;
+-- +goose StatementEnd
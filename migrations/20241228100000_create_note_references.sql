@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS note_references
+(
+    note_id     BIGINT NOT NULL,
+    target_id   BIGINT,
+    target_name TEXT,
+    CONSTRAINT note_references_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS note_references_note_id_idx ON note_references (note_id);
+CREATE INDEX IF NOT EXISTS note_references_target_id_idx ON note_references (target_id);
+CREATE INDEX IF NOT EXISTS note_references_target_name_idx ON note_references (LOWER(target_name));
+
+-- links of existing notes, parsed the same way as internal/wikilinks does
+INSERT INTO note_references (note_id, target_id, target_name)
+SELECT DISTINCT l.note_id,
+                CASE WHEN l.target ~ '^#[0-9]{1,18}$' THEN SUBSTRING(l.target FROM 2)::BIGINT END,
+                CASE WHEN l.target ~ '^#[0-9]{1,18}$' THEN NULL ELSE l.target END
+FROM (SELECT n.id AS note_id, TRIM(m[1]) AS target
+      FROM notes n,
+           REGEXP_MATCHES(n.description, '\[\[([^][\n]+)\]\]', 'g') AS m) l
+WHERE l.target <> '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS note_references_target_name_idx;
+DROP INDEX IF EXISTS note_references_target_id_idx;
+DROP INDEX IF EXISTS note_references_note_id_idx;
+
+DROP TABLE IF EXISTS note_references CASCADE;
+-- +goose StatementEnd
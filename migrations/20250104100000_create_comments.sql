@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS note_comments
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    note_id    BIGINT      NOT NULL,
+    user_id    BIGINT      NOT NULL,
+    body       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT note_comments_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT note_comments_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS note_comments_note_id_idx ON note_comments (note_id);
+
+CREATE TABLE IF NOT EXISTS note_activity
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    note_id    BIGINT      NOT NULL,
+    user_id    BIGINT      NOT NULL,
+    kind       VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT note_activity_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT note_activity_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS note_activity_note_id_idx ON note_activity (note_id);
+
+CREATE TABLE IF NOT EXISTS notifications
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    actor_id   BIGINT      NOT NULL,
+    note_id    BIGINT      NOT NULL,
+    comment_id BIGINT,
+    kind       VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    read_at    TIMESTAMPTZ,
+    CONSTRAINT notifications_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT notifications_actor_to_users_id_fk FOREIGN KEY (actor_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT notifications_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE,
+    CONSTRAINT notifications_to_note_comments_id_fk FOREIGN KEY (comment_id)
+        REFERENCES note_comments (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, read_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS notifications_user_id_idx;
+DROP INDEX IF EXISTS note_activity_note_id_idx;
+DROP INDEX IF EXISTS note_comments_note_id_idx;
+
+DROP TABLE IF EXISTS notifications CASCADE;
+DROP TABLE IF EXISTS note_activity CASCADE;
+DROP TABLE IF EXISTS note_comments CASCADE;
+-- +goose StatementEnd
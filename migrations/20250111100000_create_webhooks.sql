@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id         BIGSERIAL     NOT NULL PRIMARY KEY,
+    user_id    BIGINT        NOT NULL,
+    url        VARCHAR(2048) NOT NULL,
+    events     TEXT[]        NOT NULL,
+    secret     VARCHAR(100)  NOT NULL,
+    active     BOOLEAN       NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+    CONSTRAINT webhooks_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              BIGSERIAL    NOT NULL PRIMARY KEY,
+    webhook_id      BIGINT       NOT NULL,
+    event           VARCHAR(30)  NOT NULL,
+    payload         TEXT         NOT NULL,
+    status          VARCHAR(10)  NOT NULL DEFAULT 'pending',
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    response_status INTEGER,
+    error           VARCHAR(255),
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ,
+    CONSTRAINT webhook_deliveries_to_webhooks_id_fk FOREIGN KEY (webhook_id)
+        REFERENCES webhooks (id)
+        ON DELETE CASCADE,
+    CONSTRAINT webhook_deliveries_status_check CHECK (status IN ('pending', 'running', 'delivered', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_queue_idx ON webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_overdue_notes
+(
+    note_id     BIGINT NOT NULL PRIMARY KEY,
+    deadline_at DATE   NOT NULL,
+    CONSTRAINT webhook_overdue_notes_to_notes_id_fk FOREIGN KEY (note_id)
+        REFERENCES notes (id)
+        ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_overdue_notes CASCADE;
+
+DROP INDEX IF EXISTS webhook_deliveries_queue_idx;
+DROP INDEX IF EXISTS webhook_deliveries_webhook_id_idx;
+
+DROP TABLE IF EXISTS webhook_deliveries CASCADE;
+
+DROP INDEX IF EXISTS webhooks_user_id_idx;
+
+DROP TABLE IF EXISTS webhooks CASCADE;
+-- +goose StatementEnd
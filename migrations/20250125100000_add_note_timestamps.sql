@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notes
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::TIMESTAMPTZ,
+    ALTER COLUMN created_at SET DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS updated_at   TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+
+ALTER TABLE bulk_action_notes
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+
+-- completion times are known from the activity feed since comments were added,
+-- older completed notes get their creation time
+UPDATE notes n
+SET completed_at = COALESCE((SELECT MAX(a.created_at)
+                             FROM note_activity a
+                             WHERE a.note_id = n.id
+                               AND a.kind = 'completed'), n.created_at)
+WHERE n.is_completed = TRUE;
+
+UPDATE notes n
+SET updated_at = GREATEST(n.created_at, n.completed_at, (SELECT MAX(a.created_at)
+                                                         FROM note_activity a
+                                                         WHERE a.note_id = n.id));
+
+ALTER TABLE notes
+    ALTER COLUMN updated_at SET DEFAULT NOW(),
+    ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS notes_user_id_updated_at_idx ON notes (user_id, updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS notes_user_id_updated_at_idx;
+
+ALTER TABLE bulk_action_notes
+    DROP COLUMN IF EXISTS completed_at;
+
+ALTER TABLE notes
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS updated_at,
+    ALTER COLUMN created_at TYPE DATE USING created_at::DATE,
+    ALTER COLUMN created_at SET DEFAULT NOW()::DATE;
+-- +goose StatementEnd
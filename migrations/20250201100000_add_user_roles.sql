@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role                    VARCHAR(10) NOT NULL DEFAULT 'user',
+    ADD COLUMN IF NOT EXISTS disabled_at             TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN     NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS last_login_at           TIMESTAMPTZ;
+
+-- the first registered user administers an existing installation
+UPDATE users
+SET role = 'admin'
+WHERE id = (SELECT MIN(id) FROM users);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN IF EXISTS role,
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS password_reset_required,
+    DROP COLUMN IF EXISTS last_login_at;
+-- +goose StatementEnd
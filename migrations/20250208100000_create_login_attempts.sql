@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+    key             VARCHAR(100) NOT NULL PRIMARY KEY,
+    failures        INTEGER      NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    locked_until    TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS login_audit
+(
+    id         BIGSERIAL    NOT NULL PRIMARY KEY,
+    login      VARCHAR(64)  NOT NULL,
+    ip         VARCHAR(45)  NOT NULL,
+    reason     VARCHAR(10)  NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CONSTRAINT login_audit_reason_check CHECK (reason IN ('password', 'locked', 'disabled'))
+);
+
+CREATE INDEX IF NOT EXISTS login_audit_created_at_idx ON login_audit (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS login_audit_created_at_idx;
+
+DROP TABLE IF EXISTS login_audit CASCADE;
+
+DROP TABLE IF EXISTS login_attempts CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS totp_secret       VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS totp_enabled_at   TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS totp_last_counter BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    code_hash  VARCHAR(64) NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT recovery_codes_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
+
+ALTER TABLE login_audit
+    DROP CONSTRAINT IF EXISTS login_audit_reason_check,
+    ADD CONSTRAINT login_audit_reason_check CHECK (reason IN ('password', 'code', 'locked', 'disabled'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE
+FROM login_audit
+WHERE reason = 'code';
+
+ALTER TABLE login_audit
+    DROP CONSTRAINT IF EXISTS login_audit_reason_check,
+    ADD CONSTRAINT login_audit_reason_check CHECK (reason IN ('password', 'locked', 'disabled'));
+
+DROP INDEX IF EXISTS recovery_codes_user_id_idx;
+
+DROP TABLE IF EXISTS recovery_codes CASCADE;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS totp_secret,
+    DROP COLUMN IF EXISTS totp_enabled_at,
+    DROP COLUMN IF EXISTS totp_last_counter;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS webauthn_credentials
+(
+    id            BIGSERIAL   NOT NULL PRIMARY KEY,
+    user_id       BIGINT      NOT NULL,
+    name          VARCHAR(50) NOT NULL,
+    credential_id BYTEA       NOT NULL,
+    public_key    BYTEA       NOT NULL,
+    sign_count    BIGINT      NOT NULL DEFAULT 0,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at  TIMESTAMPTZ,
+    CONSTRAINT webauthn_credentials_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+    CONSTRAINT webauthn_credentials_credential_id_key UNIQUE (credential_id)
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS webauthn_credentials_user_id_idx;
+
+DROP TABLE IF EXISTS webauthn_credentials CASCADE;
+-- +goose StatementEnd
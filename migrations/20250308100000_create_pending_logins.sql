@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pending_logins
+(
+    token_hash VARCHAR(64) NOT NULL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT pending_logins_to_users_id_fk FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS pending_logins_expires_at_idx ON pending_logins (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS pending_logins_expires_at_idx;
+
+DROP TABLE IF EXISTS pending_logins CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries
+    ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries
+    DROP COLUMN IF EXISTS claimed_at;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- attachments uploaded by editors of shared notes count against the note owner
+UPDATE attachments a
+SET user_id = n.user_id
+FROM notes n
+WHERE n.id = a.note_id
+  AND a.user_id <> n.user_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- the uploaders are not recorded anywhere else, so there is nothing to restore
+SELECT 1;
+-- +goose StatementEnd
@@ -0,0 +1,68 @@
+package pgdb
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	listenMinBackoff = time.Second
+	listenMaxBackoff = 30 * time.Second
+)
+
+// Notify sends a NOTIFY on the channel to every connection listening on it.
+func (p *Connection) Notify(ctx context.Context, channel, payload string) error {
+	_, err := p.pool.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
+
+// Listen passes the payloads of the notifications on the channel to handle until
+// ctx is done. A dedicated connection is taken out of the pool; when it breaks,
+// Listen reconnects with a growing delay. Notifications sent while disconnected
+// are lost.
+func (p *Connection) Listen(ctx context.Context, channel string, handle func(payload string)) {
+	backoff := listenMinBackoff
+	for {
+		listening, err := p.listen(ctx, channel, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if listening {
+			backoff = listenMinBackoff
+		}
+		log.Printf("pgdb: listen %s: %v, reconnecting in %s", channel, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, listenMaxBackoff)
+	}
+}
+
+// listen runs one LISTEN session and reports whether it was established.
+func (p *Connection) listen(ctx context.Context, channel string, handle func(payload string)) (bool, error) {
+	pooled, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "acquire connection")
+	}
+	// the connection keeps listening, so it must not go back to the pool
+	conn := pooled.Hijack()
+	defer func() { _ = conn.Close(context.Background()) }()
+
+	if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return false, errors.Wrap(err, "listen")
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		handle(notification.Payload)
+	}
+}